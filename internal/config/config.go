@@ -6,6 +6,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,9 +15,12 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	Limits LimitsConfig `yaml:"limits"`
-	Tokens TokensConfig `yaml:"tokens"`
+	Server    ServerConfig    `yaml:"server"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Tokens    TokensConfig    `yaml:"tokens"`
+	Retention RetentionConfig `yaml:"retention"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Files     FilesConfig     `yaml:"files"`
 }
 
 type ServerConfig struct {
@@ -43,6 +47,37 @@ type LimitsConfig struct {
 	MaxTotalSizeMB       int `yaml:"max_total_size_mb"`
 	UploadConcurrency    int `yaml:"upload_concurrency"`
 	TranscodeConcurrency int `yaml:"transcode_concurrency"`
+	// MaxVersions 为每个文件保留的历史版本数（转码前的内容），计入 max_total_size_mb；
+	// 0（或不填）取 3，-1 表示不保留。
+	MaxVersions int `yaml:"max_versions"`
+	// EvictionPolicy 为超出 max_files / max_total_size_mb 时的淘汰策略：fifo、lru、lfu 或 largest。
+	EvictionPolicy string `yaml:"eviction_policy"`
+	// MaxPinnedSizeMB 为置顶文件（不参与自动淘汰）的总量上限，须小于 max_total_size_mb；
+	// 0（或不填）取 max_total_size_mb 的一半，-1 表示不允许置顶。
+	MaxPinnedSizeMB int `yaml:"max_pinned_size_mb"`
+}
+
+// MaxPinnedBytes 返回置顶总量上限（字节，-1 折算为 0）。
+func (l LimitsConfig) MaxPinnedBytes() int64 {
+	if l.MaxPinnedSizeMB < 0 {
+		return 0
+	}
+	return int64(l.MaxPinnedSizeMB) * 1024 * 1024
+}
+
+const (
+	EvictionFIFO    = "fifo"
+	EvictionLRU     = "lru"
+	EvictionLFU     = "lfu"
+	EvictionLargest = "largest"
+)
+
+// HistoryVersions 返回实际保留的历史版本数（-1 折算为 0）。
+func (l LimitsConfig) HistoryVersions() int {
+	if l.MaxVersions < 0 {
+		return 0
+	}
+	return l.MaxVersions
 }
 
 type TokensConfig struct {
@@ -50,6 +85,137 @@ type TokensConfig struct {
 	BridgeTTLSeconds   int `yaml:"bridge_ttl_seconds"`
 }
 
+// RetentionConfig 控制按时间过期：上传未指定有效期时使用 default_seconds（0 表示永久保留），
+// 后台每 sweep_interval_seconds 清理一次已过期文件。
+type RetentionConfig struct {
+	DefaultSeconds       int `yaml:"default_seconds"`
+	SweepIntervalSeconds int `yaml:"sweep_interval_seconds"`
+	// TrashSeconds 为删除的文件在回收站中保留的时长（0 取 7 天，-1 表示不启用回收站）；
+	// TrashMaxSizeMB 为回收站的总量上限，独立于 max_total_size_mb（0 取 max_total_size_mb 的一半）；
+	// TrashEvicted 为 true 时被自动淘汰的文件也放入回收站。
+	TrashSeconds   int  `yaml:"trash_seconds"`
+	TrashMaxSizeMB int  `yaml:"trash_max_size_mb"`
+	TrashEvicted   bool `yaml:"trash_evicted"`
+}
+
+func (r RetentionConfig) Default() time.Duration {
+	return time.Duration(r.DefaultSeconds) * time.Second
+}
+
+func (r RetentionConfig) SweepInterval() time.Duration {
+	return time.Duration(r.SweepIntervalSeconds) * time.Second
+}
+
+// TrashTTL 返回回收站保留时长（-1 折算为 0，即不启用）。
+func (r RetentionConfig) TrashTTL() time.Duration {
+	if r.TrashSeconds < 0 {
+		return 0
+	}
+	return time.Duration(r.TrashSeconds) * time.Second
+}
+
+// MaxTrashBytes 返回回收站总量上限（字节）；不启用回收站时为 0。
+func (r RetentionConfig) MaxTrashBytes() int64 {
+	if r.TrashSeconds < 0 {
+		return 0
+	}
+	return int64(r.TrashMaxSizeMB) * 1024 * 1024
+}
+
+// FilesConfig 控制上传、改名的命名行为。
+type FilesConfig struct {
+	// OnNameConflict 为目录中已有同名文件时的默认处理（请求可单独指定）：reject（拒绝）、
+	// suffix（改用“名称 (1).扩展名”）、overwrite（写入同名文件的新版本）、keep_both（已有文件改名加序号，新文件用原名）。
+	OnNameConflict string `yaml:"on_name_conflict"`
+	// Names 为上传、改名时的文件名规则，不符合的名称被拒绝。
+	Names NamesConfig `yaml:"names"`
+}
+
+// NamesConfig 为文件名规则；控制字符、'/'、首尾空白总是不允许。
+type NamesConfig struct {
+	// MaxLength 为文件名最大长度（UTF-8 字节；0 取 255，-1 表示不限制）。
+	MaxLength int `yaml:"max_length"`
+	// ForbiddenChars 为额外不允许出现的字符，例如 Windows 不支持的 \:*?"<>|。
+	ForbiddenChars string `yaml:"forbidden_chars"`
+	// NormalizeNFC 为 true 时把文件名转换为 Unicode NFC 形式后保存。
+	NormalizeNFC bool `yaml:"normalize_nfc"`
+	// RejectReserved 为 true 时拒绝 Windows 保留名（CON、aux.txt 等）与以点结尾的名称。
+	RejectReserved bool `yaml:"reject_reserved"`
+}
+
+// MaxNameLength 返回文件名最大长度（-1 折算为 0，即不限制）。
+func (n NamesConfig) MaxNameLength() int {
+	if n.MaxLength < 0 {
+		return 0
+	}
+	return n.MaxLength
+}
+
+const (
+	NameConflictReject    = "reject"
+	NameConflictSuffix    = "suffix"
+	NameConflictOverwrite = "overwrite"
+	NameConflictKeepBoth  = "keep_both"
+)
+
+const (
+	StorageBackendMemory = "memory"
+	StorageBackendDisk   = "disk"
+	StorageBackendS3     = "s3"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	Dir     string `yaml:"dir"`
+	// Compression 为内容的静态压缩方式：none 或 gzip（只在能明显省空间时压缩，总量按压缩后计算）。
+	Compression string         `yaml:"compression"`
+	Snapshot    SnapshotConfig `yaml:"snapshot"`
+	Journal     JournalConfig  `yaml:"journal"`
+	S3          S3Config       `yaml:"s3"`
+}
+
+// S3Config 用于 s3 后端：内容存放在 S3 兼容对象存储，元数据索引保存在 storage.dir 下。
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	Prefix    string `yaml:"prefix"`
+	PathStyle bool   `yaml:"path_style"`
+	// CleanOrphans 为 true 时启动时删除 prefix 下未被本地索引引用的内容对象（默认关闭）。
+	CleanOrphans bool `yaml:"clean_orphans"`
+}
+
+// SnapshotConfig 仅对 memory 后端生效：启动时加载快照，运行中周期保存，优雅退出时再保存一次。
+type SnapshotConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Path            string `yaml:"path"`
+	IntervalSeconds int    `yaml:"interval_seconds"`
+}
+
+func (s SnapshotConfig) Interval() time.Duration {
+	return time.Duration(s.IntervalSeconds) * time.Second
+}
+
+// JournalConfig 仅对 memory 后端生效：每次变更追加到操作日志，启动时重放；
+// 周期或日志超过 compact_size_mb 时压缩为快照。与 snapshot 二选一。
+type JournalConfig struct {
+	Enabled                bool   `yaml:"enabled"`
+	Dir                    string `yaml:"dir"`
+	CompactIntervalSeconds int    `yaml:"compact_interval_seconds"`
+	CompactSizeMB          int    `yaml:"compact_size_mb"`
+}
+
+func (j JournalConfig) CompactInterval() time.Duration {
+	return time.Duration(j.CompactIntervalSeconds) * time.Second
+}
+
 func Load(path string) (Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -100,6 +266,15 @@ func (c *Config) applyDefaults() {
 	if c.Limits.TranscodeConcurrency == 0 {
 		c.Limits.TranscodeConcurrency = 2
 	}
+	if c.Limits.MaxVersions == 0 {
+		c.Limits.MaxVersions = 3
+	}
+	if c.Limits.MaxPinnedSizeMB == 0 {
+		c.Limits.MaxPinnedSizeMB = c.Limits.MaxTotalSizeMB / 2
+	}
+	if c.Limits.EvictionPolicy == "" {
+		c.Limits.EvictionPolicy = EvictionFIFO
+	}
 
 	if c.Tokens.DownloadTTLSeconds == 0 {
 		c.Tokens.DownloadTTLSeconds = 60
@@ -107,6 +282,54 @@ func (c *Config) applyDefaults() {
 	if c.Tokens.BridgeTTLSeconds == 0 {
 		c.Tokens.BridgeTTLSeconds = 300
 	}
+
+	if c.Retention.SweepIntervalSeconds == 0 {
+		c.Retention.SweepIntervalSeconds = 60
+	}
+	if c.Retention.TrashSeconds == 0 {
+		c.Retention.TrashSeconds = 7 * 24 * 3600
+	}
+	if c.Retention.TrashMaxSizeMB == 0 {
+		c.Retention.TrashMaxSizeMB = max(c.Limits.MaxTotalSizeMB/2, 1)
+	}
+
+	if c.Files.OnNameConflict == "" {
+		c.Files.OnNameConflict = NameConflictReject
+	}
+	if c.Files.Names.MaxLength == 0 {
+		c.Files.Names.MaxLength = 255
+	}
+
+	if strings.TrimSpace(c.Storage.Backend) == "" {
+		c.Storage.Backend = StorageBackendMemory
+	}
+	if strings.TrimSpace(c.Storage.Compression) == "" {
+		c.Storage.Compression = CompressionNone
+	}
+	if strings.TrimSpace(c.Storage.Dir) == "" {
+		c.Storage.Dir = "./data"
+	}
+	if strings.TrimSpace(c.Storage.Snapshot.Path) == "" {
+		c.Storage.Snapshot.Path = filepath.Join(c.Storage.Dir, "snapshot.bin")
+	}
+	if c.Storage.Snapshot.IntervalSeconds == 0 {
+		c.Storage.Snapshot.IntervalSeconds = 300
+	}
+	if strings.TrimSpace(c.Storage.Journal.Dir) == "" {
+		c.Storage.Journal.Dir = filepath.Join(c.Storage.Dir, "journal")
+	}
+	if c.Storage.Journal.CompactIntervalSeconds == 0 {
+		c.Storage.Journal.CompactIntervalSeconds = 600
+	}
+	if c.Storage.Journal.CompactSizeMB == 0 {
+		c.Storage.Journal.CompactSizeMB = 512
+	}
+	if strings.TrimSpace(c.Storage.S3.Region) == "" {
+		c.Storage.S3.Region = "us-east-1"
+	}
+	if strings.TrimSpace(c.Storage.S3.Prefix) == "" {
+		c.Storage.S3.Prefix = "files/"
+	}
 }
 
 func (c Config) Validate() error {
@@ -155,6 +378,17 @@ func (c Config) Validate() error {
 	if c.Limits.TranscodeConcurrency <= 0 {
 		errs = append(errs, errors.New("limits.transcode_concurrency must be > 0"))
 	}
+	if c.Limits.MaxVersions < -1 {
+		errs = append(errs, errors.New("limits.max_versions must be >= -1"))
+	}
+	if c.Limits.MaxPinnedSizeMB < -1 || c.Limits.MaxPinnedSizeMB >= c.Limits.MaxTotalSizeMB {
+		errs = append(errs, errors.New("limits.max_pinned_size_mb must be >= -1 and < limits.max_total_size_mb"))
+	}
+	switch c.Limits.EvictionPolicy {
+	case EvictionFIFO, EvictionLRU, EvictionLFU, EvictionLargest:
+	default:
+		errs = append(errs, fmt.Errorf("limits.eviction_policy must be one of %q, %q, %q, %q", EvictionFIFO, EvictionLRU, EvictionLFU, EvictionLargest))
+	}
 
 	if c.Tokens.DownloadTTLSeconds <= 0 {
 		errs = append(errs, errors.New("tokens.download_ttl_seconds must be > 0"))
@@ -163,6 +397,75 @@ func (c Config) Validate() error {
 		errs = append(errs, errors.New("tokens.bridge_ttl_seconds must be > 0"))
 	}
 
+	if c.Retention.DefaultSeconds < 0 {
+		errs = append(errs, errors.New("retention.default_seconds must be >= 0"))
+	}
+	if c.Retention.SweepIntervalSeconds <= 0 {
+		errs = append(errs, errors.New("retention.sweep_interval_seconds must be > 0"))
+	}
+	if c.Retention.TrashSeconds < -1 {
+		errs = append(errs, errors.New("retention.trash_seconds must be >= -1"))
+	}
+	if c.Retention.TrashMaxSizeMB < 0 {
+		errs = append(errs, errors.New("retention.trash_max_size_mb must be >= 0"))
+	}
+
+	switch c.Files.OnNameConflict {
+	case NameConflictReject, NameConflictSuffix, NameConflictOverwrite, NameConflictKeepBoth:
+	default:
+		errs = append(errs, fmt.Errorf("files.on_name_conflict must be one of %q, %q, %q, %q", NameConflictReject, NameConflictSuffix, NameConflictOverwrite, NameConflictKeepBoth))
+	}
+	if c.Files.Names.MaxLength < -1 {
+		errs = append(errs, errors.New("files.names.max_length must be >= -1"))
+	}
+
+	switch c.Storage.Backend {
+	case StorageBackendMemory:
+	case StorageBackendDisk:
+		if strings.TrimSpace(c.Storage.Dir) == "" {
+			errs = append(errs, errors.New("storage.dir is required when storage.backend is disk"))
+		}
+	case StorageBackendS3:
+		if strings.TrimSpace(c.Storage.Dir) == "" {
+			errs = append(errs, errors.New("storage.dir is required when storage.backend is s3 (metadata index)"))
+		}
+		if strings.TrimSpace(c.Storage.S3.Endpoint) == "" {
+			errs = append(errs, errors.New("storage.s3.endpoint is required"))
+		}
+		if strings.TrimSpace(c.Storage.S3.Bucket) == "" {
+			errs = append(errs, errors.New("storage.s3.bucket is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend must be one of %q, %q, %q", StorageBackendMemory, StorageBackendDisk, StorageBackendS3))
+	}
+	switch c.Storage.Compression {
+	case CompressionNone, CompressionGzip:
+	default:
+		errs = append(errs, fmt.Errorf("storage.compression must be one of %q, %q", CompressionNone, CompressionGzip))
+	}
+	if c.Storage.Snapshot.Enabled {
+		if c.Storage.Backend != StorageBackendMemory {
+			errs = append(errs, errors.New("storage.snapshot is only supported with storage.backend memory"))
+		}
+		if c.Storage.Snapshot.IntervalSeconds <= 0 {
+			errs = append(errs, errors.New("storage.snapshot.interval_seconds must be > 0"))
+		}
+	}
+	if c.Storage.Journal.Enabled {
+		if c.Storage.Backend != StorageBackendMemory {
+			errs = append(errs, errors.New("storage.journal is only supported with storage.backend memory"))
+		}
+		if c.Storage.Snapshot.Enabled {
+			errs = append(errs, errors.New("storage.journal and storage.snapshot cannot both be enabled"))
+		}
+		if c.Storage.Journal.CompactIntervalSeconds <= 0 {
+			errs = append(errs, errors.New("storage.journal.compact_interval_seconds must be > 0"))
+		}
+		if c.Storage.Journal.CompactSizeMB <= 0 {
+			errs = append(errs, errors.New("storage.journal.compact_size_mb must be > 0"))
+		}
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+	"go-learn/internal/tokens"
+)
+
+type uploadCheckRequest struct {
+	Folder    string `json:"folder"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	// OnConflict 为上传时将使用的重名处理策略，空时使用配置的默认策略。
+	OnConflict string `json:"on_conflict"`
+}
+
+// uploadCheckResponse 中 Admitted 为 true 表示按当前状态上传会成功（可能需要淘汰 Evictions 中的文件）；
+// OnConflict 为实际采用的重名处理策略，不是 reject 时重名不妨碍上传。
+type uploadCheckResponse struct {
+	Admitted     bool           `json:"admitted"`
+	NameConflict bool           `json:"name_conflict"`
+	OnConflict   string         `json:"on_conflict"`
+	TooLarge     bool           `json:"too_large"`
+	Fits         bool           `json:"fits"`
+	MaxFileBytes int64          `json:"max_file_bytes"`
+	Evictions    []fileListItem `json:"evictions"`
+}
+
+// uploadCheckHandler 处理 POST /files/check：上传前预演，报告是否重名、是否超过单文件上限、
+// 会淘汰哪些文件，不改变存储。
+func uploadCheckHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checkUpload(w, r, d)
+	}
+}
+
+// bridgeUploadCheckHandler 处理 POST /bridge/{bridgeToken}/upload-check：手机上传前的预演，只校验不消费二维码。
+func bridgeUploadCheckHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Tokens == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "token store not initialized", "")
+			return
+		}
+
+		bridgeToken := chi.URLParam(r, "bridgeToken")
+		if bridgeToken == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少 bridgeToken", "")
+			return
+		}
+
+		it, err := d.Tokens.Peek(bridgeToken)
+		if err != nil {
+			if errors.Is(err, tokens.ErrNotFound) {
+				Error(w, http.StatusGone, "TOKEN_INVALID", "二维码已失效", "")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "INTERNAL", "校验二维码失败", err.Error())
+			return
+		}
+		if it.Kind != tokenKindBridgeUpload {
+			Error(w, http.StatusGone, "TOKEN_INVALID", "二维码已失效", "")
+			return
+		}
+
+		checkUpload(w, r, d)
+	}
+}
+
+func checkUpload(w http.ResponseWriter, r *http.Request, d RouterDeps) {
+	if d.Store == nil {
+		Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+		return
+	}
+	if d.MaxFileBytes <= 0 {
+		Error(w, http.StatusInternalServerError, "INTERNAL", "max file size not initialized", "")
+		return
+	}
+
+	var req uploadCheckRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少 name", "")
+		return
+	}
+	if req.SizeBytes < 0 {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "size_bytes 不合法", "")
+		return
+	}
+	onConflict, err := conflictPolicy(req.OnConflict, d)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "重名处理方式不合法", err.Error())
+		return
+	}
+
+	// 与上传相同：超过单文件上限的文件在读取时被拒绝，不再预演淘汰。
+	out := uploadCheckResponse{
+		OnConflict:   onConflict,
+		TooLarge:     req.SizeBytes > d.MaxFileBytes,
+		MaxFileBytes: d.MaxFileBytes,
+		Evictions:    []fileListItem{},
+	}
+	size := min(req.SizeBytes, d.MaxFileBytes)
+	adm, err := d.Store.CheckAdmission(req.Folder, req.Name, size)
+	if err != nil {
+		if writeNameError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, store.ErrFolderNotFound):
+			Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
+		case errors.Is(err, store.ErrInvalidInput):
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求不合法", err.Error())
+		default:
+			Error(w, http.StatusInternalServerError, "INTERNAL", "预演失败", err.Error())
+		}
+		return
+	}
+
+	out.NameConflict = adm.NameConflict
+	out.Fits = adm.Fits
+	// 覆盖同名文件是写入它的新版本，不淘汰其他文件。
+	overwrite := out.NameConflict && onConflict == store.ConflictOverwrite
+	if !out.TooLarge && !overwrite {
+		for _, meta := range adm.Evictions {
+			out.Evictions = append(out.Evictions, metaToFileListItem(meta))
+		}
+	}
+	blocked := out.NameConflict && onConflict == store.ConflictReject
+	out.Admitted = !blocked && !out.TooLarge && out.Fits
+	JSON(w, http.StatusOK, out)
+}
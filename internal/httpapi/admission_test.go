@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+	"go-learn/internal/tokens"
+)
+
+func TestUploadCheckReportsWithoutChangingStore(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 3, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := s.Add(store.AddParams{Name: "old.txt", Bytes: bytes.Repeat([]byte("o"), 30)})
+	s.Add(store.AddParams{Name: "keep.txt", Bytes: bytes.Repeat([]byte("k"), 30)})
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		BridgeTTL:      300 * time.Second,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   60,
+	})
+	check := func(url string, req uploadCheckRequest) (uploadCheckResponse, *httptest.ResponseRecorder) {
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body)))
+		var out uploadCheckResponse
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return out, rr
+	}
+
+	out, rr := check("/api/files/check", uploadCheckRequest{Name: "new.bin", SizeBytes: 50})
+	if rr.Code != http.StatusOK || !out.Admitted || len(out.Evictions) != 1 || out.Evictions[0].ID != old.ID {
+		t.Fatalf("unexpected check: %d %s", rr.Code, rr.Body.String())
+	}
+	if st := s.Stats(); st.Files != 2 {
+		t.Fatalf("check changed the store: %#v", st)
+	}
+	if out, _ := check("/api/files/check", uploadCheckRequest{Name: "keep.txt", SizeBytes: 1}); out.Admitted || !out.NameConflict {
+		t.Fatalf("expected name conflict: %#v", out)
+	}
+	if out, _ := check("/api/files/check", uploadCheckRequest{Name: "keep.txt", SizeBytes: 1, OnConflict: "suffix"}); !out.Admitted || !out.NameConflict {
+		t.Fatalf("conflict should not block with suffix policy: %#v", out)
+	}
+	if out, _ := check("/api/files/check", uploadCheckRequest{Name: "big.bin", SizeBytes: 61}); out.Admitted || !out.TooLarge || out.MaxFileBytes != 60 || len(out.Evictions) != 0 {
+		t.Fatalf("expected too large: %#v", out)
+	}
+	if _, rr := check("/api/files/check", uploadCheckRequest{Folder: "nope", Name: "a", SizeBytes: 1}); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+
+	// 手机端预演只校验二维码，不消费。
+	item, err := ts.Create(tokenKindBridgeUpload, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "/api/bridge/" + item.Token + "/upload-check"
+	for i := 0; i < 2; i++ {
+		if out, rr := check(url, uploadCheckRequest{Name: "new.bin", SizeBytes: 50}); rr.Code != http.StatusOK || !out.Admitted {
+			t.Fatalf("bridge check %d: %d %s", i, rr.Code, rr.Body.String())
+		}
+	}
+	if _, rr := check("/api/bridge/invalid/upload-check", uploadCheckRequest{Name: "a", SizeBytes: 1}); rr.Code != http.StatusGone {
+		t.Fatalf("expected 410, got %d", rr.Code)
+	}
+}
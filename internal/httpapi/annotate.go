@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+type setTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type setNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// setTagsHandler 处理 PUT /files/{id}/tags，整体替换标签（空数组表示清除）。
+func setTagsHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+		var req setTagsRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		meta, err := d.Store.SetTags(id, req.Tags)
+		if err != nil {
+			writeAnnotateError(w, err, "标签不合法", "修改标签失败")
+			return
+		}
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
+
+// setNoteHandler 处理 PUT /files/{id}/note，替换备注（空串表示清除）。
+func setNoteHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+		var req setNoteRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		meta, err := d.Store.SetNote(id, req.Note)
+		if err != nil {
+			writeAnnotateError(w, err, "备注不合法", "修改备注失败")
+			return
+		}
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
+
+func writeAnnotateError(w http.ResponseWriter, err error, invalidMsg, failMsg string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+	case errors.Is(err, store.ErrInvalidInput):
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", invalidMsg, err.Error())
+	default:
+		Error(w, http.StatusInternalServerError, "INTERNAL", failMsg, err.Error())
+	}
+}
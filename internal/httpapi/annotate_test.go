@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-learn/internal/store"
+	"go-learn/internal/text"
+)
+
+func TestTagsAndNoteSurviveTranscodeAndFilterList(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024, MaxVersions: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("hello"), Encoding: text.EncodingUTF8, IsText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(store.AddParams{Name: "b.txt", Bytes: []byte("world")}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		TranscodeSem:   NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	decodeItem := func(rr *httptest.ResponseRecorder) fileListItem {
+		t.Helper()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var item fileListItem
+		if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+			t.Fatal(err)
+		}
+		return item
+	}
+
+	item := decodeItem(do(http.MethodPut, "/api/files/"+a.ID+"/tags", `{"tags":["needs GBK","customer X","needs GBK"]}`))
+	if !reflect.DeepEqual(item.Tags, []string{"customer X", "needs GBK"}) {
+		t.Fatalf("unexpected tags: %#v", item.Tags)
+	}
+	decodeItem(do(http.MethodPut, "/api/files/"+a.ID+"/note", `{"note":"from customer X"}`))
+	if rr := do(http.MethodPut, "/api/files/"+a.ID+"/tags", `{"tags":["a,b"]}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("invalid tag: expected 400, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPut, "/api/files/missing/note", `{"note":"x"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing file: expected 404, got %d", rr.Code)
+	}
+
+	decodeItem(do(http.MethodPatch, "/api/files/"+a.ID, `{"name":"renamed.txt"}`))
+	item = decodeItem(do(http.MethodPost, "/api/files/"+a.ID+"/transcode", `{"sourceEncoding":"auto","targetEncoding":"GB18030"}`))
+	if item.Name != "renamed.txt" || item.Note != "from customer X" || !reflect.DeepEqual(item.Tags, []string{"customer X", "needs GBK"}) {
+		t.Fatalf("annotations lost after rename/transcode: %#v", item)
+	}
+
+	var items []fileListItem
+	rr := do(http.MethodGet, "/api/files?tag=needs+GBK&tag=customer+X", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != a.ID {
+		t.Fatalf("unexpected filtered list: %#v", items)
+	}
+	rr = do(http.MethodGet, "/api/files?tag=other", "")
+	if strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Fatalf("expected empty list, got %s", rr.Body.String())
+	}
+	rr = do(http.MethodGet, "/api/files", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Tags == nil {
+		t.Fatalf("expected untagged file with empty tag list: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-learn/internal/store"
+)
+
+func TestNameConflictPolicies(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+		OnNameConflict: store.ConflictSuffix,
+	})
+	upload := func(fields map[string]string, content string) (fileListItem, *httptest.ResponseRecorder) {
+		body, contentType := newMultipartBodyWithFields(t, fields, "report.csv", []byte(content))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		var out fileListItem
+		_ = json.Unmarshal(rr.Body.Bytes(), &out)
+		return out, rr
+	}
+
+	first, rr := upload(nil, "v1")
+	if rr.Code != http.StatusCreated || first.Name != "report.csv" {
+		t.Fatalf("first upload: %d %s", rr.Code, rr.Body.String())
+	}
+	// 未指定时使用配置的默认策略。
+	if out, rr := upload(nil, "v2"); rr.Code != http.StatusCreated || out.Name != "report (1).csv" {
+		t.Fatalf("default policy: %d %s", rr.Code, rr.Body.String())
+	}
+	if _, rr := upload(map[string]string{"on_conflict": "reject"}, "v3"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if out, rr := upload(map[string]string{"on_conflict": "overwrite"}, "v3"); rr.Code != http.StatusCreated || out.ID != first.ID || out.Version != 2 {
+		t.Fatalf("overwrite: %d %s", rr.Code, rr.Body.String())
+	}
+	if _, rr := upload(map[string]string{"on_conflict": "bogus"}, "v4"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+
+	other, _ := s.Add(store.AddParams{Name: "other.csv", Bytes: []byte("o")})
+	rename := func(req renameFileRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/api/files/"+other.ID, bytes.NewReader(body)))
+		return rr
+	}
+	if rr := rename(renameFileRequest{Name: "report.csv", OnConflict: "reject"}); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	rr = rename(renameFileRequest{Name: "report.csv", OnConflict: "keep_both"})
+	var out fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); rr.Code != http.StatusOK || err != nil || out.Name != "report.csv" {
+		t.Fatalf("keep both: %d %s", rr.Code, rr.Body.String())
+	}
+	if meta, _ := s.GetMeta(first.ID); meta.Name != "report (2).csv" {
+		t.Fatalf("existing file after keep both: %#v", meta)
+	}
+}
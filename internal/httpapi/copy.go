@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+type copyFileRequest struct {
+	Name string `json:"name"`
+}
+
+// copyFileHandler 处理 POST /files/{id}/copy，在同一目录以新文件名复制文件，返回新文件信息。
+func copyFileHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+
+		var req copyFileRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if req.Name == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少 name", "")
+			return
+		}
+
+		meta, err := d.Store.Copy(id, req.Name)
+		if err != nil {
+			writeNewFileError(w, err, "复制失败")
+			return
+		}
+
+		setETag(w, meta)
+		JSON(w, http.StatusCreated, metaToFileListItem(meta))
+	}
+}
+
+// writeNewFileError 把新建文件（复制、转码为新文件）时的 store 错误映射为响应。
+func writeNewFileError(w http.ResponseWriter, err error, msg string) {
+	if writeNameError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+	case errors.Is(err, store.ErrFolderNotFound):
+		Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
+	case errors.Is(err, store.ErrNameConflict):
+		Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+	case errors.Is(err, store.ErrInsufficientSpace), errors.Is(err, store.ErrTooLarge):
+		Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法保存新文件", "")
+	case errors.Is(err, store.ErrInvalidInput):
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求不合法", err.Error())
+	default:
+		Error(w, http.StatusInternalServerError, "INTERNAL", msg, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-learn/internal/store"
+	"go-learn/internal/text"
+)
+
+func TestCopyAndTranscodeAsNewFile(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := []byte("中文测试")
+	src, _ := s.Add(store.AddParams{Name: "a.txt", Bytes: origin, Encoding: text.EncodingUTF8, IsText: true})
+
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		TranscodeSem:   NewSemaphore(1),
+	})
+	post := func(url string, v any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(v)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body)))
+		return rr
+	}
+
+	rr := post("/api/files/"+src.ID+"/copy", copyFileRequest{Name: "b.txt"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("copy: %d body=%s", rr.Code, rr.Body.String())
+	}
+	var cp fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &cp); err != nil || cp.Name != "b.txt" || cp.ID == src.ID || cp.SHA256 != src.SHA256 {
+		t.Fatalf("unexpected copy: %s", rr.Body.String())
+	}
+	if rr := post("/api/files/"+src.ID+"/copy", copyFileRequest{Name: "b.txt"}); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+	if rr := post("/api/files/nope/copy", copyFileRequest{Name: "c.txt"}); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+
+	rr = post("/api/files/"+src.ID+"/transcode", transcodeFileRequest{TargetEncoding: text.EncodingGBK, NewName: "a-gbk.txt"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("transcode as new file: %d body=%s", rr.Code, rr.Body.String())
+	}
+	var out fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil || out.Name != "a-gbk.txt" || out.Encoding != text.EncodingGBK {
+		t.Fatalf("unexpected transcode result: %s", rr.Body.String())
+	}
+	// 原文件保持不变。
+	if f, err := s.Get(src.ID); err != nil || !bytes.Equal(f.Bytes, origin) || f.Meta.Version != 1 {
+		t.Fatalf("original changed: %#v err=%v", f.Meta, err)
+	}
+	if rr := post("/api/files/"+src.ID+"/transcode", transcodeFileRequest{TargetEncoding: text.EncodingGBK, NewName: "b.txt"}); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d body=%s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestTranscodeAsNewFileUsesCleanedName(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{
+		MaxFiles:      10,
+		MaxTotalBytes: 1 << 20,
+		NamePolicy:    store.NamePolicy{NormalizeNFC: true, RejectReserved: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := s.Add(store.AddParams{Name: "caf\u00e9.txt", Bytes: []byte("hello"), Encoding: text.EncodingUTF8, IsText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewRouter(RouterDeps{Store: s, TranscodeSem: NewSemaphore(1)})
+	transcode := func(name string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(transcodeFileRequest{TargetEncoding: text.EncodingGBK, NewName: name})
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/files/"+src.ID+"/transcode", bytes.NewReader(body)))
+		return rr
+	}
+
+	// 分解形式的同名文件在规范化后才冲突。
+	if rr := transcode("cafe\u0301.txt"); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := transcode("CON.txt"); rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "NAME_RESERVED") {
+		t.Fatalf("expected NAME_RESERVED, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr := transcode("ne\u0301.txt")
+	var out fileListItem
+	if rr.Code != http.StatusCreated || json.Unmarshal(rr.Body.Bytes(), &out) != nil || out.Name != "n\u00e9.txt" {
+		t.Fatalf("expected NFC name, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if len(s.List()) != 2 {
+		t.Fatalf("unexpected files: %#v", s.List())
+	}
+}
@@ -21,12 +21,22 @@ func deleteFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
-		_, err := d.Store.Delete(id)
+		cond, ok := parseIfMatch(r)
+		if !ok {
+			writePreconditionFailed(w)
+			return
+		}
+
+		_, err := d.Store.DeleteIf(id, cond)
 		if err != nil {
 			if errors.Is(err, store.ErrNotFound) {
 				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
 				return
 			}
+			if errors.Is(err, store.ErrPreconditionFailed) {
+				writePreconditionFailed(w)
+				return
+			}
 			Error(w, http.StatusInternalServerError, "INTERNAL", "删除失败", err.Error())
 			return
 		}
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -94,17 +95,34 @@ func downloadByTokenHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
-		meta, reader, err := d.Store.Open(it.FileID)
+		// 历史版本以 token 中记录的版本号为准（链接由版本下载接口生成）；?version 与之不一致时拒绝。
+		if v := r.URL.Query().Get("version"); v != "" {
+			if version, perr := parseVersion(v); perr != nil || version != it.Version {
+				Error(w, http.StatusBadRequest, "BAD_REQUEST", "版本号不合法", "")
+				return
+			}
+		}
+		var (
+			meta   store.FileMeta
+			reader io.ReadSeekCloser
+		)
+		if it.Version > 0 {
+			meta, reader, err = d.Store.OpenVersion(it.FileID, it.Version)
+		} else {
+			meta, reader, err = d.Store.Open(it.FileID)
+		}
 		if err != nil {
-			if errors.Is(err, store.ErrNotFound) {
+			if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrVersionNotFound) {
 				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
 				return
 			}
 			Error(w, http.StatusInternalServerError, "INTERNAL", "读取文件失败", err.Error())
 			return
 		}
+		defer reader.Close()
 
-		modTime := meta.CreatedAt
+		// 按内容的修改时间（历史版本为该版本的生成时间），替换后缓存与条件请求才能识别变化。
+		modTime := meta.UpdatedAt
 		if modTime.IsZero() {
 			modTime = time.Now()
 		}
@@ -113,6 +131,7 @@ func downloadByTokenHandler(d RouterDeps) http.HandlerFunc {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("Cache-Control", "no-store")
 		w.Header().Set("Content-Disposition", contentDispositionAttachment(meta.Name))
+		setETag(w, meta)
 
 		http.ServeContent(w, r, meta.Name, modTime, reader)
 	}
@@ -99,3 +99,86 @@ func TestDownloadTokenOneTime(t *testing.T) {
 		t.Fatalf("expected 410, got %d body=%s", rr3.Code, rr3.Body.String())
 	}
 }
+
+func TestDownloadRangeFromCompressedStore(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, Compression: store.CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Repeat("0123456789abcdef\n", 200)
+	meta, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte(content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.PhysicalBytes >= int64(len(content)) {
+		t.Fatalf("expected content to be stored compressed: %#v", st)
+	}
+
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		DownloadTTL:    60 * time.Second,
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/files/"+meta.ID+"/download-token", nil))
+	var tok downloadTokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &tok); err != nil {
+		t.Fatalf("unmarshal: %v body=%s", err, rr.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, tok.URL, nil)
+	req.Header.Set("Range", "bytes=1000-1099")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Body.String(); got != content[1000:1100] {
+		t.Fatalf("unexpected range body: %q", got)
+	}
+}
+
+func TestDownloadLastModifiedFollowsContentUpdates(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, MaxVersions: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	meta, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("v1"), Now: created})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(store.ReplaceParams{ID: meta.ID, Bytes: []byte("v2"), Now: updated}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{Store: s, Tokens: ts, DownloadTTL: 60 * time.Second})
+	lastModified := func(tokenURL string) string {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tokenURL, nil))
+		var tok downloadTokenResponse
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &tok) != nil {
+			t.Fatalf("token: code=%d body=%s", rr.Code, rr.Body.String())
+		}
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tok.URL, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("download: code=%d body=%s", rr.Code, rr.Body.String())
+		}
+		return rr.Header().Get("Last-Modified")
+	}
+
+	if got, want := lastModified("/api/files/"+meta.ID+"/download-token"), updated.Format(http.TimeFormat); got != want {
+		t.Fatalf("current Last-Modified = %q, want %q", got, want)
+	}
+	if got, want := lastModified("/api/files/"+meta.ID+"/versions/1/download-token"), created.Format(http.TimeFormat); got != want {
+		t.Fatalf("version Last-Modified = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"go-learn/internal/store"
+)
+
+// 文件的实体标签为当前内容 SHA-256 的强 ETag（"<hex>"），内容不变时标签不变；旧数据未记录摘要时不提供 ETag。
+
+func etagOf(meta store.FileMeta) string {
+	if meta.SHA256 == "" {
+		return ""
+	}
+	return `"` + meta.SHA256 + `"`
+}
+
+func setETag(w http.ResponseWriter, meta store.FileMeta) {
+	if tag := etagOf(meta); tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// parseIfMatch 解析 If-Match 请求头（按强比较，弱标签 W/"..." 不匹配任何内容）。
+// 未携带或为 "*" 时返回空条件；ok 为 false 表示条件不可能成立（只有弱标签或格式不合法的值）。
+func parseIfMatch(r *http.Request) (cond store.IfMatch, ok bool) {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return nil, true
+	}
+	for _, v := range values {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.TrimSpace(tag)
+			switch {
+			case tag == "*":
+				return nil, true
+			case len(tag) >= 2 && tag[0] == '"' && tag[len(tag)-1] == '"':
+				cond = append(cond, tag[1:len(tag)-1])
+			}
+		}
+	}
+	return cond, len(cond) > 0
+}
+
+func writePreconditionFailed(w http.ResponseWriter) {
+	Error(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED", "文件已被修改，请刷新后重试", "")
+}
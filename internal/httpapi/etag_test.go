@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+	"go-learn/internal/text"
+	"go-learn/internal/tokens"
+)
+
+func TestETagAndIfMatch(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("你好"), Encoding: text.EncodingUTF8, IsText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		DownloadTTL:    60 * time.Second,
+		UploadSem:      NewSemaphore(1),
+		TranscodeSem:   NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	do := func(method, url, ifMatch, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	sum := sha256.Sum256([]byte("你好"))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	var items []fileListItem
+	if err := json.Unmarshal(do(http.MethodGet, "/api/files", "", "").Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || `"`+items[0].SHA256+`"` != etag {
+		t.Fatalf("unexpected checksum in list: %#v", items)
+	}
+
+	rr := do(http.MethodPost, "/api/files/"+meta.ID+"/download-token", "", "")
+	var tok downloadTokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &tok); err != nil {
+		t.Fatal(err)
+	}
+	rr = do(http.MethodGet, tok.URL, "", "")
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != etag {
+		t.Fatalf("download: status=%d etag=%q", rr.Code, rr.Header().Get("ETag"))
+	}
+
+	// 先转码成功（内容改变），再用旧标签改名/转码/删除都返回 412 且不修改文件。
+	rr = do(http.MethodPost, "/api/files/"+meta.ID+"/transcode", etag, `{"sourceEncoding":"auto","targetEncoding":"GB18030"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("transcode: status=%d body=%s", rr.Code, rr.Body.String())
+	}
+	newTag := rr.Header().Get("ETag")
+	if newTag == "" {
+		t.Fatal("expected ETag on transcode response")
+	}
+	rr = do(http.MethodPost, "/api/files/"+meta.ID+"/transcode", etag, `{"sourceEncoding":"auto","targetEncoding":"UTF-8"}`)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale transcode: expected 412, got %d", rr.Code)
+	}
+	var e ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || e.Code != "PRECONDITION_FAILED" {
+		t.Fatalf("unexpected error body: %s", rr.Body.String())
+	}
+	if rr := do(http.MethodPatch, "/api/files/"+meta.ID, etag, `{"name":"b.txt"}`); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale rename: expected 412, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/api/files/"+meta.ID, "W/"+newTag, ""); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("weak tag delete: expected 412, got %d", rr.Code)
+	}
+	if got, _ := s.GetMeta(meta.ID); got.Name != "a.txt" || got.Version != 2 {
+		t.Fatalf("file modified by rejected requests: %#v", got)
+	}
+
+	if rr := do(http.MethodPatch, "/api/files/"+meta.ID, etag+", "+newTag, `{"name":"b.txt"}`); rr.Code != http.StatusOK || rr.Header().Get("ETag") != newTag {
+		t.Fatalf("rename: status=%d etag=%q", rr.Code, rr.Header().Get("ETag"))
+	}
+	if rr := do(http.MethodDelete, "/api/files/"+meta.ID, "*", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+	"go-learn/internal/tokens"
+)
+
+func TestUploadExpiryAndRemainingLifetime(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+		DefaultTTL:     24 * time.Hour,
+	})
+	upload := func(fields map[string]string, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, contentType := newMultipartBodyWithFields(t, fields, name, []byte("hello"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	remaining := func(rr *httptest.ResponseRecorder) int64 {
+		t.Helper()
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+		}
+		var item fileListItem
+		if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+			t.Fatal(err)
+		}
+		if item.ExpiresAt == nil || item.RemainingSeconds == nil {
+			t.Fatalf("expected expiry in item: %s", rr.Body.String())
+		}
+		return *item.RemainingSeconds
+	}
+
+	if got := remaining(upload(map[string]string{"expires_in": "3600"}, "hour.txt")); got < 3590 || got > 3600 {
+		t.Fatalf("unexpected remaining seconds for expires_in: %d", got)
+	}
+	if got := remaining(upload(nil, "default.txt")); got < 24*3600-10 || got > 24*3600 {
+		t.Fatalf("unexpected remaining seconds for default retention: %d", got)
+	}
+	at := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	if got := remaining(upload(map[string]string{"expires_at": at}, "at.txt")); got < 2*3600-10 || got > 2*3600 {
+		t.Fatalf("unexpected remaining seconds for expires_at: %d", got)
+	}
+
+	for _, fields := range []map[string]string{
+		{"expires_in": "-5"},
+		{"expires_in": "soon"},
+		{"expires_at": "2000-01-01T00:00:00Z"},
+		{"expires_at": at, "expires_in": "60"},
+	} {
+		if rr := upload(fields, "bad.txt"); rr.Code != http.StatusBadRequest {
+			t.Fatalf("fields %v: expected 400, got %d body=%s", fields, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestExpiredFileNotDownloadableBeforeSweep(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	meta, err := s.Add(store.AddParams{Name: "old.txt", Bytes: []byte("x"), Now: past, ExpiresAt: past.Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		DownloadTTL:    60 * time.Second,
+	})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/files/"+meta.ID+"/download-token", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for expired file, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/files", nil))
+	var items []fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("expired file should not be listed: %#v", items)
+	}
+}
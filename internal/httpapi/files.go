@@ -1,19 +1,52 @@
 package httpapi
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"go-learn/internal/store"
 )
 
 type fileListItem struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Folder 为所在目录（根目录为 ""），Name 为不含目录的文件名。
+	Folder    string    `json:"folder"`
 	CreatedAt time.Time `json:"created_at"`
 	SizeBytes int64     `json:"size_bytes"`
 	Encoding  string    `json:"encoding"`
 	IsText    bool      `json:"is_text"`
+	// Version 为当前版本号，PrevVersions 为可恢复的历史版本数。
+	Version      int       `json:"version"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	PrevVersions int       `json:"prev_versions"`
+	// Pinned 为 true 时不参与自动淘汰。
+	Pinned bool `json:"pinned"`
+	// ExpiresAt/RemainingSeconds 仅在设置了有效期时返回；剩余时间由服务端计算，避免客户端时钟偏差。
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	RemainingSeconds *int64     `json:"remaining_seconds,omitempty"`
+	// Tags 为用户标签（总是数组），Note 为备注。
+	Tags []string `json:"tags"`
+	Note string   `json:"note"`
+	// SHA256 为当前内容的十六进制摘要（旧数据可能为空），与下载响应的 ETag 一致，可用于 If-Match。
+	SHA256 string `json:"sha256"`
 }
 
+const (
+	// maxListLimit 为分页查询每页条数上限。
+	maxListLimit = 1000
+
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
+)
+
+// listFilesHandler 按查询参数筛选、排序并分页（见 parseListQuery），响应体仍为文件数组；
+// 符合条件的总数放在 X-Total-Count，还有下一页时游标放在 X-Next-Cursor。不带 limit 时返回全部结果。
 func listFilesHandler(d RouterDeps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if d.Store == nil {
@@ -21,22 +54,117 @@ func listFilesHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
-		metas := d.Store.List()
-		out := make([]fileListItem, 0, len(metas))
-		for _, m := range metas {
-			out = append(out, fileListItem{
-				ID:        m.ID,
-				Name:      m.Name,
-				CreatedAt: m.CreatedAt,
-				SizeBytes: m.SizeBytes,
-				Encoding:  normalizeEncoding(m.Encoding),
-				IsText:    m.IsText,
-			})
+		q, err := parseListQuery(r.URL.Query())
+		if err != nil {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "查询参数不合法", err.Error())
+			return
+		}
+		page, err := d.Store.Query(q)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrFolderNotFound):
+				Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
+			case errors.Is(err, store.ErrInvalidInput):
+				Error(w, http.StatusBadRequest, "BAD_REQUEST", "查询参数不合法", err.Error())
+			default:
+				Error(w, http.StatusInternalServerError, "INTERNAL", "读取文件列表失败", err.Error())
+			}
+			return
+		}
+
+		out := make([]fileListItem, 0, len(page.Files))
+		for _, m := range page.Files {
+			out = append(out, metaToFileListItem(m))
+		}
+		w.Header().Set(headerTotalCount, strconv.Itoa(page.Total))
+		if page.NextCursor != "" {
+			w.Header().Set(headerNextCursor, page.NextCursor)
 		}
 		JSON(w, http.StatusOK, out)
 	}
 }
 
+// parseListQuery 解析列表查询参数：
+//
+//	folder                          只列出该目录（folder= 为根目录）
+//	name                            文件名子串，含 * ? [ 时按 glob 匹配（不区分大小写）
+//	tag                             标签，可重复（须全部命中）
+//	encoding, is_text               编码（不区分大小写）、是否文本
+//	min_size, max_size              大小范围（字节，含端点）
+//	created_after, created_before   上传时间范围（RFC 3339，[after, before)）
+//	updated_after, updated_before   内容更新时间范围
+//	sort, order                     created|updated|name|size，asc|desc
+//	limit, cursor                   每页条数（1..1000）与上一页返回的游标
+func parseListQuery(v url.Values) (store.ListQuery, error) {
+	q := store.ListQuery{
+		Name:     strings.TrimSpace(v.Get("name")),
+		Tags:     v["tag"],
+		Encoding: strings.TrimSpace(v.Get("encoding")),
+		Sort:     v.Get("sort"),
+		Cursor:   v.Get("cursor"),
+	}
+	if v.Has("folder") {
+		folder := v.Get("folder")
+		q.Folder = &folder
+	}
+	if s := v.Get("is_text"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return store.ListQuery{}, fmt.Errorf("is_text: %w", err)
+		}
+		q.IsText = &b
+	}
+
+	ints := []struct {
+		name string
+		dst  *int64
+	}{{"min_size", &q.MinSize}, {"max_size", &q.MaxSize}}
+	for _, p := range ints {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return store.ListQuery{}, fmt.Errorf("%s must be a non-negative integer", p.name)
+			}
+			*p.dst = n
+		}
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"created_after", &q.CreatedFrom},
+		{"created_before", &q.CreatedTo},
+		{"updated_after", &q.UpdatedFrom},
+		{"updated_before", &q.UpdatedTo},
+	}
+	for _, p := range times {
+		if s := v.Get(p.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return store.ListQuery{}, fmt.Errorf("%s: %w", p.name, err)
+			}
+			*p.dst = t
+		}
+	}
+
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return store.ListQuery{}, errors.New("order must be asc or desc")
+	}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxListLimit {
+			return store.ListQuery{}, fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
 func normalizeEncoding(enc string) string {
 	if enc == "" {
 		return "Unknown"
@@ -54,3 +54,61 @@ func TestListFiles(t *testing.T) {
 	}
 }
 
+func TestListFilesQueryAndPaging(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"c.txt", "a.txt", "b.log"} {
+		if _, err := s.Add(store.AddParams{Name: name, Bytes: []byte(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	get := func(url string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		return rr
+	}
+
+	var names []string
+	url := "/api/files?name=*.txt&sort=name&order=desc&limit=1"
+	for url != "" {
+		rr := get(url)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status=%d body=%s", rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get(headerTotalCount); got != "2" {
+			t.Fatalf("expected total 2, got %q", got)
+		}
+		var items []fileListItem
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range items {
+			names = append(names, it.Name)
+		}
+		url = ""
+		if c := rr.Header().Get(headerNextCursor); c != "" {
+			url = "/api/files?name=*.txt&sort=name&order=desc&limit=1&cursor=" + c
+		}
+	}
+	if len(names) != 2 || names[0] != "c.txt" || names[1] != "a.txt" {
+		t.Fatalf("unexpected pages: %v", names)
+	}
+
+	for _, bad := range []string{"limit=0", "limit=1001", "sort=color", "order=up", "min_size=-1", "created_after=yesterday", "cursor=xyz", "is_text=maybe"} {
+		if rr := get("/api/files?" + bad); rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", bad, rr.Code)
+		}
+	}
+	if rr := get("/api/files?folder=missing"); rr.Code != http.StatusNotFound {
+		t.Fatalf("missing folder: expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+type folderItem struct {
+	Path string `json:"path"`
+}
+
+type createFolderRequest struct {
+	Path string `json:"path"`
+}
+
+type renameFolderRequest struct {
+	Path    string `json:"path"`
+	NewPath string `json:"new_path"`
+}
+
+type deleteFolderResponse struct {
+	DeletedFiles int `json:"deleted_files"`
+}
+
+type moveFileRequest struct {
+	Folder string `json:"folder"`
+}
+
+func listFoldersHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		folders := d.Store.Folders()
+		out := make([]folderItem, 0, len(folders))
+		for _, p := range folders {
+			out = append(out, folderItem{Path: p})
+		}
+		JSON(w, http.StatusOK, out)
+	}
+}
+
+func createFolderHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		var req createFolderRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		path, err := store.CleanFolder(req.Path)
+		if err != nil || path == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "目录路径不合法", "")
+			return
+		}
+
+		if err := d.Store.CreateFolder(path); err != nil {
+			writeFolderError(w, err, "创建目录失败")
+			return
+		}
+		JSON(w, http.StatusCreated, folderItem{Path: path})
+	}
+}
+
+func renameFolderHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		var req renameFolderRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		from, err := store.CleanFolder(req.Path)
+		if err != nil || from == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "目录路径不合法", "")
+			return
+		}
+		to, err := store.CleanFolder(req.NewPath)
+		if err != nil || to == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "新目录路径不合法", "")
+			return
+		}
+
+		if err := d.Store.RenameFolder(from, to); err != nil {
+			writeFolderError(w, err, "重命名目录失败")
+			return
+		}
+		JSON(w, http.StatusOK, folderItem{Path: to})
+	}
+}
+
+// deleteFolderHandler 处理 DELETE /folders?path=...；目录非空时需带 recursive=true 才会连同文件一起删除。
+func deleteFolderHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		q := r.URL.Query()
+		path, err := store.CleanFolder(q.Get("path"))
+		if err != nil || path == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "目录路径不合法", "")
+			return
+		}
+		recursive := false
+		if v := q.Get("recursive"); v != "" {
+			if recursive, err = strconv.ParseBool(v); err != nil {
+				Error(w, http.StatusBadRequest, "BAD_REQUEST", "recursive 不合法", "")
+				return
+			}
+		}
+
+		n, err := d.Store.DeleteFolder(path, recursive)
+		if err != nil {
+			writeFolderError(w, err, "删除目录失败")
+			return
+		}
+		JSON(w, http.StatusOK, deleteFolderResponse{DeletedFiles: n})
+	}
+}
+
+// moveFileHandler 处理 PUT /files/{id}/folder，把文件移动到已存在的目录（"" 为根目录）。
+func moveFileHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+		var req moveFileRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		meta, err := d.Store.Move(id, req.Folder)
+		if err != nil {
+			writeFolderError(w, err, "移动失败")
+			return
+		}
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
+
+func writeFolderError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, store.ErrFolderNotFound):
+		Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
+	case errors.Is(err, store.ErrNotFound):
+		Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+	case errors.Is(err, store.ErrNameConflict):
+		Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+	case errors.Is(err, store.ErrFolderNotEmpty):
+		Error(w, http.StatusConflict, "FOLDER_NOT_EMPTY", "目录非空", "")
+	case errors.Is(err, store.ErrInvalidInput):
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求不合法", err.Error())
+	default:
+		Error(w, http.StatusInternalServerError, "INTERNAL", msg, err.Error())
+	}
+}
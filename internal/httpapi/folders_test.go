@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+	"go-learn/internal/tokens"
+)
+
+func TestFolderLifecycle(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		DownloadTTL:    60 * time.Second,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   100,
+	})
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	upload := func(folder, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, contentType := newMultipartBodyWithFields(t, map[string]string{"folder": folder}, name, []byte("data"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	list := func(url string) []fileListItem {
+		t.Helper()
+		rr := do(http.MethodGet, url, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list %s: expected 200, got %d body=%s", url, rr.Code, rr.Body.String())
+		}
+		var items []fileListItem
+		if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+
+	if rr := do(http.MethodPost, "/api/folders", `{"path":"proj/docs"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/api/folders", `{"path":"proj"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("create existing: expected 409, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/folders", `{"path":"../x"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("create invalid: expected 400, got %d", rr.Code)
+	}
+
+	if rr := upload("", "a.txt"); rr.Code != http.StatusCreated {
+		t.Fatalf("upload root: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr := upload("proj/docs", "a.txt")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upload same name into folder: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var nested fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &nested); err != nil {
+		t.Fatal(err)
+	}
+	if nested.Folder != "proj/docs" || nested.Name != "a.txt" {
+		t.Fatalf("unexpected item: %#v", nested)
+	}
+	if rr := upload("proj/docs", "a.txt"); rr.Code != http.StatusConflict {
+		t.Fatalf("upload duplicate in folder: expected 409, got %d", rr.Code)
+	}
+	if rr := upload("missing", "b.txt"); rr.Code != http.StatusNotFound {
+		t.Fatalf("upload into missing folder: expected 404, got %d", rr.Code)
+	}
+
+	if items := list("/api/files"); len(items) != 2 {
+		t.Fatalf("expected all files listed, got %#v", items)
+	}
+	if items := list("/api/files?folder=proj/docs"); len(items) != 1 || items[0].ID != nested.ID {
+		t.Fatalf("unexpected folder listing: %#v", items)
+	}
+	if items := list("/api/files?folder="); len(items) != 1 || items[0].Folder != "" {
+		t.Fatalf("unexpected root listing: %#v", items)
+	}
+	if rr := do(http.MethodGet, "/api/files?folder=missing", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("list missing folder: expected 404, got %d", rr.Code)
+	}
+
+	// 下载沿用不含目录的文件名。
+	rr = do(http.MethodPost, "/api/files/"+nested.ID+"/download-token", "")
+	var tok downloadTokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &tok); err != nil {
+		t.Fatalf("unmarshal: %v body=%s", err, rr.Body.String())
+	}
+	rr = do(http.MethodGet, tok.URL, "")
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="a.txt"`) {
+		t.Fatalf("unexpected Content-Disposition: %q", cd)
+	}
+
+	if rr := do(http.MethodPatch, "/api/folders", `{"path":"proj","new_path":"archive/2024"}`); rr.Code != http.StatusOK {
+		t.Fatalf("rename folder: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if items := list("/api/files?folder=archive/2024/docs"); len(items) != 1 || items[0].ID != nested.ID {
+		t.Fatalf("file not moved with folder: %#v", items)
+	}
+	rr = do(http.MethodGet, "/api/folders", "")
+	var folders []folderItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &folders); err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 3 || folders[0].Path != "archive" || folders[2].Path != "archive/2024/docs" {
+		t.Fatalf("unexpected folders: %#v", folders)
+	}
+
+	if rr := do(http.MethodPut, "/api/files/"+nested.ID+"/folder", `{"folder":""}`); rr.Code != http.StatusConflict {
+		t.Fatalf("move into conflicting folder: expected 409, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPut, "/api/files/"+nested.ID+"/folder", `{"folder":"archive"}`); rr.Code != http.StatusOK {
+		t.Fatalf("move: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr = do(http.MethodDelete, "/api/folders?path=archive", "")
+	var e ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || rr.Code != http.StatusConflict || e.Code != "FOLDER_NOT_EMPTY" {
+		t.Fatalf("delete non-empty: expected 409 FOLDER_NOT_EMPTY, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodDelete, "/api/folders?path=archive&recursive=true", "")
+	var deleted deleteFolderResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &deleted); err != nil || rr.Code != http.StatusOK || deleted.DeletedFiles != 1 {
+		t.Fatalf("recursive delete: got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if items := list("/api/files"); len(items) != 1 {
+		t.Fatalf("expected only root file left, got %#v", items)
+	}
+}
+
+func TestUploadIntoMissingFolderDoesNotEvict(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 1, MaxTotalBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep, err := s.Add(store.AddParams{Name: "keep.txt", Bytes: []byte("keep")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   100,
+	})
+
+	body, contentType := newMultipartBodyWithFields(t, map[string]string{"folder": "missing"}, "b.txt", []byte("data"))
+	req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = int64(len(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if _, err := s.GetMeta(keep.ID); err != nil {
+		t.Fatalf("upload into missing folder evicted the existing file: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package httpapi
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 )
 
@@ -26,3 +28,23 @@ func Error(w http.ResponseWriter, status int, code, message, detail string) {
 	})
 }
 
+// decodeJSON 解析请求体中唯一的 JSON 值到 v；失败时已写出 400 响应。
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少请求体", "")
+			return false
+		}
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求体不是合法 JSON", err.Error())
+		return false
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected trailing tokens")
+		}
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求体不是合法 JSON", err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"go-learn/internal/store"
+)
+
+// writeNameError 在 err 为文件名不符合规则（store.NamePolicy）时写出对应的错误码并返回 true。
+func writeNameError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, store.ErrNameTooLong):
+		Error(w, http.StatusBadRequest, "NAME_TOO_LONG", "文件名过长", err.Error())
+	case errors.Is(err, store.ErrNameForbiddenChar):
+		Error(w, http.StatusBadRequest, "NAME_FORBIDDEN_CHAR", "文件名包含不允许的字符", err.Error())
+	case errors.Is(err, store.ErrNameReserved):
+		Error(w, http.StatusBadRequest, "NAME_RESERVED", "文件名是系统保留名称或以点结尾", err.Error())
+	case errors.Is(err, store.ErrInvalidName):
+		Error(w, http.StatusBadRequest, "NAME_INVALID", "文件名不合法（不能为空、含控制字符或首尾空白）", err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-learn/internal/store"
+)
+
+func TestNamePolicyErrorCodes(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{
+		MaxFiles:      10,
+		MaxTotalBytes: 1024 * 1024,
+		NamePolicy:    store.NamePolicy{MaxLength: 32, ForbiddenChars: `:*?`, NormalizeNFC: true, RejectReserved: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	upload := func(name string) *httptest.ResponseRecorder {
+		body, contentType := newMultipartBody(t, name, []byte("hello"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	code := func(rr *httptest.ResponseRecorder) string {
+		var e ErrorResponse
+		_ = json.Unmarshal(rr.Body.Bytes(), &e)
+		return e.Code
+	}
+
+	for name, want := range map[string]string{
+		"aux.txt":                               "NAME_RESERVED",
+		"notes.":                                "NAME_RESERVED",
+		"a:b.txt":                               "NAME_FORBIDDEN_CHAR",
+		"a-very-long-file-name-for-testing.txt": "NAME_TOO_LONG",
+	} {
+		if rr := upload(name); rr.Code != http.StatusBadRequest || code(rr) != want {
+			t.Errorf("upload %q: %d %s, want %s", name, rr.Code, rr.Body.String(), want)
+		}
+	}
+
+	rr := upload("cafe\u0301.txt")
+	var item fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); rr.Code != http.StatusCreated || err != nil || item.Name != "caf\u00e9.txt" {
+		t.Fatalf("expected NFC name: %d %s", rr.Code, rr.Body.String())
+	}
+
+	body, _ := json.Marshal(renameFileRequest{Name: "what?.txt"})
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/api/files/"+item.ID, bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest || code(rr) != "NAME_FORBIDDEN_CHAR" {
+		t.Fatalf("rename: %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestUploadChecksNameBeforeReserving(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{
+		MaxFiles:      1,
+		MaxTotalBytes: 1024 * 1024,
+		NamePolicy:    store.NamePolicy{NormalizeNFC: true, RejectReserved: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep, err := s.Add(store.AddParams{Name: "caf\u00e9.txt", Bytes: []byte("keep")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+
+	// 存储已满：若先预留再检查名称，已有文件会先被淘汰。
+	for name, want := range map[string]int{
+		"CON.txt":        http.StatusBadRequest,
+		"a\x01.txt":      http.StatusBadRequest,
+		"cafe\u0301.txt": http.StatusConflict,
+	} {
+		body, contentType := newMultipartBody(t, name, []byte("hello"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("upload %q: %d %s, want %d", name, rr.Code, rr.Body.String(), want)
+		}
+		if _, err := s.GetMeta(keep.ID); err != nil {
+			t.Fatalf("upload %q evicted the existing file: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+// setPinnedHandler 处理 PUT（置顶）与 DELETE（取消置顶）/files/{id}/pin，返回更新后的文件信息。
+func setPinnedHandler(d RouterDeps, pinned bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+
+		meta, err := d.Store.SetPinned(id, pinned)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			case errors.Is(err, store.ErrPinLimit):
+				Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "置顶文件已达上限", "")
+			default:
+				Error(w, http.StatusInternalServerError, "INTERNAL", "置顶失败", err.Error())
+			}
+			return
+		}
+
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
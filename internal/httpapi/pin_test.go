@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-learn/internal/store"
+)
+
+func TestPinnedFileBlocksEvictionUntilUnpinned(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 20, MaxPinnedBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := s.Add(store.AddParams{Name: "map.csv", Bytes: []byte("0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   15,
+	})
+	do := func(method, url string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(method, url, nil))
+		return rr
+	}
+	upload := func(name string, data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		body, contentType := newMultipartBody(t, name, data)
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodPut, "/api/files/"+meta.ID+"/pin")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("pin: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var item fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if !item.Pinned {
+		t.Fatalf("expected pinned item, got %#v", item)
+	}
+
+	rr = upload("big.log", []byte("abcdefghijklmno"))
+	if rr.Code != http.StatusInsufficientStorage {
+		t.Fatalf("upload with only pinned files: expected 507, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var e ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || e.Code != "INSUFFICIENT_STORAGE" {
+		t.Fatalf("unexpected error body: %s", rr.Body.String())
+	}
+
+	if rr := do(http.MethodDelete, "/api/files/"+meta.ID+"/pin"); rr.Code != http.StatusOK {
+		t.Fatalf("unpin: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := upload("big.log", []byte("abcdefghijklmno")); rr.Code != http.StatusCreated {
+		t.Fatalf("upload after unpin: expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if _, err := s.GetMeta(meta.ID); err != store.ErrNotFound {
+		t.Fatalf("expected unpinned file evicted, got %v", err)
+	}
+
+	if rr := do(http.MethodPut, "/api/files/missing/pin"); rr.Code != http.StatusNotFound {
+		t.Fatalf("pin missing: expected 404, got %d", rr.Code)
+	}
+}
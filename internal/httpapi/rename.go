@@ -12,6 +12,8 @@ import (
 
 type renameFileRequest struct {
 	Name string `json:"name"`
+	// OnConflict 为重名处理策略（见 store.Conflict*），空时使用配置的默认策略。
+	OnConflict string `json:"on_conflict"`
 }
 
 func renameFileHandler(d RouterDeps) http.HandlerFunc {
@@ -27,6 +29,12 @@ func renameFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
+		cond, ok := parseIfMatch(r)
+		if !ok {
+			writePreconditionFailed(w)
+			return
+		}
+
 		var req renameFileRequest
 		dec := json.NewDecoder(r.Body)
 		if err := dec.Decode(&req); err != nil {
@@ -50,14 +58,27 @@ func renameFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
-		meta, err := d.Store.Rename(id, req.Name)
+		onConflict, err := conflictPolicy(req.OnConflict, d)
 		if err != nil {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "重名处理方式不合法", err.Error())
+			return
+		}
+
+		meta, err := d.Store.RenameWith(store.RenameParams{ID: id, Name: req.Name, IfMatch: cond, OnConflict: onConflict})
+		if err != nil {
+			if writeNameError(w, err) {
+				return
+			}
 			switch {
 			case errors.Is(err, store.ErrNotFound):
 				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			case errors.Is(err, store.ErrPreconditionFailed):
+				writePreconditionFailed(w)
 			case errors.Is(err, store.ErrNameConflict):
 				// 需求口径：冲突直接拒绝，并保持原名不变（store 层已保证不修改）。
 				Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+			case errors.Is(err, store.ErrReplaceWouldExceed):
+				Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法覆盖同名文件", "")
 			case errors.Is(err, store.ErrInvalidInput):
 				Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求不合法", err.Error())
 			default:
@@ -66,13 +87,7 @@ func renameFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
-		JSON(w, http.StatusOK, fileListItem{
-			ID:        meta.ID,
-			Name:      meta.Name,
-			CreatedAt: meta.CreatedAt,
-			SizeBytes: meta.SizeBytes,
-			Encoding:  normalizeEncoding(meta.Encoding),
-			IsText:    meta.IsText,
-		})
+		setETag(w, meta)
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
 	}
 }
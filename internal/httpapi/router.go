@@ -10,15 +10,18 @@ import (
 )
 
 type RouterDeps struct {
-	ExternalOrigin string
-	Store          *store.InMemoryStore
-	Tokens         *tokens.Store
-	DownloadTTL    time.Duration
-	BridgeTTL      time.Duration
-	UploadSem      *Semaphore
-	TranscodeSem   *Semaphore
-	MaxFileBytes   int64
+	ExternalOrigin  string
+	Store           store.FileStore
+	Tokens          *tokens.Store
+	DownloadTTL     time.Duration
+	BridgeTTL       time.Duration
+	DefaultTTL      time.Duration
+	UploadSem       *Semaphore
+	TranscodeSem    *Semaphore
+	MaxFileBytes    int64
 	MaxRequestBytes int64
+	// OnNameConflict 为请求未指定 on_conflict 时的重名处理策略（store.Conflict*），空表示拒绝。
+	OnNameConflict string
 }
 
 func NewRouter(d RouterDeps) http.Handler {
@@ -29,13 +32,33 @@ func NewRouter(d RouterDeps) http.Handler {
 	r.Route("/api", func(r chi.Router) {
 		r.Get("/files", listFilesHandler(d))
 		r.Post("/files", uploadFileHandler(d))
+		r.Post("/files/check", uploadCheckHandler(d))
 		r.Patch("/files/{id}", renameFileHandler(d))
 		r.Delete("/files/{id}", deleteFileHandler(d))
 		r.Post("/files/{id}/download-token", createDownloadTokenHandler(d))
 		r.Post("/files/{id}/transcode", transcodeFileHandler(d))
+		r.Post("/files/{id}/copy", copyFileHandler(d))
+		r.Put("/files/{id}/pin", setPinnedHandler(d, true))
+		r.Delete("/files/{id}/pin", setPinnedHandler(d, false))
+		r.Put("/files/{id}/folder", moveFileHandler(d))
+		r.Put("/files/{id}/tags", setTagsHandler(d))
+		r.Put("/files/{id}/note", setNoteHandler(d))
+		r.Get("/files/{id}/versions", listVersionsHandler(d))
+		r.Post("/files/{id}/versions/{version}/download-token", createVersionDownloadTokenHandler(d))
+		r.Post("/files/{id}/versions/{version}/restore", restoreVersionHandler(d))
+		r.Get("/folders", listFoldersHandler(d))
+		r.Post("/folders", createFolderHandler(d))
+		r.Patch("/folders", renameFolderHandler(d))
+		r.Delete("/folders", deleteFolderHandler(d))
+		r.Get("/stats", statsHandler(d))
+		r.Get("/trash", listTrashHandler(d))
+		r.Delete("/trash", emptyTrashHandler(d))
+		r.Post("/trash/{id}/restore", restoreTrashHandler(d))
+		r.Delete("/trash/{id}", purgeTrashHandler(d))
 		r.Post("/bridge/upload", createBridgeUploadHandler(d))
 		r.Post("/bridge/download", createBridgeDownloadHandler(d))
 		r.Post("/bridge/{bridgeToken}/upload", bridgeUploadHandler(d))
+		r.Post("/bridge/{bridgeToken}/upload-check", bridgeUploadCheckHandler(d))
 		r.Get("/bridge/{bridgeToken}/download-info", bridgeDownloadInfoHandler(d))
 		r.Post("/bridge/{bridgeToken}/download-token", bridgeDownloadTokenHandler(d))
 	})
@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+)
+
+type statsResponse struct {
+	Files            int             `json:"files"`
+	MaxFiles         int             `json:"max_files"`
+	UsedBytes        int64           `json:"used_bytes"`
+	MaxTotalBytes    int64           `json:"max_total_bytes"`
+	LogicalBytes     int64           `json:"logical_bytes"`
+	ReservedBytes    int64           `json:"reserved_bytes"`
+	CompressionRatio float64         `json:"compression_ratio"`
+	TrashFiles       int             `json:"trash_files"`
+	TrashBytes       int64           `json:"trash_bytes"`
+	RecentEvictions  []evictionEntry `json:"recent_evictions"`
+}
+
+type evictionEntry struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Folder    string    `json:"folder"`
+	SizeBytes int64     `json:"size_bytes"`
+	Reason    string    `json:"reason"`
+	EvictedAt time.Time `json:"evicted_at"`
+	Trigger   string    `json:"trigger"`
+	Trashed   bool      `json:"trashed"`
+}
+
+// statsHandler 处理 GET /stats：用量、上限与最近的自动淘汰记录（最近的在前）。
+func statsHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		st := d.Store.Stats()
+		evictions := d.Store.RecentEvictions()
+		out := statsResponse{
+			Files:            st.Files,
+			MaxFiles:         st.MaxFiles,
+			UsedBytes:        st.PhysicalBytes,
+			MaxTotalBytes:    st.MaxTotalBytes,
+			LogicalBytes:     st.LogicalBytes,
+			ReservedBytes:    st.ReservedBytes,
+			CompressionRatio: st.CompressionRatio,
+			TrashFiles:       st.TrashFiles,
+			TrashBytes:       st.TrashBytes,
+			RecentEvictions:  make([]evictionEntry, 0, len(evictions)),
+		}
+		for _, e := range evictions {
+			out.RecentEvictions = append(out.RecentEvictions, evictionEntry{
+				ID:        e.ID,
+				Name:      e.Name,
+				Folder:    e.Folder,
+				SizeBytes: e.SizeBytes,
+				Reason:    e.Reason,
+				EvictedAt: e.At,
+				Trigger:   e.Trigger,
+				Trashed:   e.Trashed,
+			})
+		}
+		JSON(w, http.StatusOK, out)
+	}
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-learn/internal/store"
+)
+
+func TestStatsReportsRecentEvictions(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 1, MaxTotalBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, _ := s.Add(store.AddParams{Name: "old.txt", Bytes: []byte("old")})
+	if _, err := s.Add(store.AddParams{Name: "new.txt", Bytes: []byte("new!")}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewRouter(RouterDeps{ExternalOrigin: "http://127.0.0.1:8080", Store: s})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("stats: %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	var out statsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Files != 1 || out.MaxFiles != 1 || out.UsedBytes != 4 || out.MaxTotalBytes != 1024 {
+		t.Fatalf("unexpected usage: %s", rr.Body.String())
+	}
+	if len(out.RecentEvictions) != 1 {
+		t.Fatalf("expected one eviction: %s", rr.Body.String())
+	}
+	if e := out.RecentEvictions[0]; e.ID != old.ID || e.Name != "old.txt" || e.SizeBytes != 3 || e.Reason != store.EvictReasonMaxFiles || e.Trigger != "new.txt" || e.Trashed {
+		t.Fatalf("unexpected eviction: %#v", e)
+	}
+}
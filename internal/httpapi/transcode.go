@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go-learn/internal/store"
@@ -15,6 +16,8 @@ import (
 type transcodeFileRequest struct {
 	SourceEncoding string `json:"sourceEncoding"`
 	TargetEncoding string `json:"targetEncoding"`
+	// NewName 非空时把结果写入同一目录下的新文件（原文件不变），否则覆盖原文件（原内容转为历史版本）。
+	NewName string `json:"newName"`
 }
 
 func transcodeFileHandler(d RouterDeps) http.HandlerFunc {
@@ -34,6 +37,13 @@ func transcodeFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
+		// If-Match 在读取前与写回时各检查一次：转码期间文件被他人改写时返回 412，不覆盖对方的修改。
+		cond, ok := parseIfMatch(r)
+		if !ok {
+			writePreconditionFailed(w)
+			return
+		}
+
 		req, ok := decodeTranscodeRequest(w, r)
 		if !ok {
 			return
@@ -73,10 +83,28 @@ func transcodeFileHandler(d RouterDeps) http.HandlerFunc {
 			Error(w, http.StatusInternalServerError, "INTERNAL", "读取文件失败", err.Error())
 			return
 		}
+		if !cond.Matches(file.Meta) {
+			writePreconditionFailed(w)
+			return
+		}
 		if !file.Meta.IsText {
 			Error(w, http.StatusBadRequest, "BAD_REQUEST", "不支持转码（非可识别文本）", "")
 			return
 		}
+		// 新文件名按存储规则清理、规范化后再检查重名，与 Add 实际使用的名称一致。
+		newName := req.NewName
+		if newName != "" {
+			if newName, err = d.Store.CleanName(newName); err != nil {
+				if !writeNameError(w, err) {
+					Error(w, http.StatusBadRequest, "BAD_REQUEST", "文件名不合法", err.Error())
+				}
+				return
+			}
+			if d.Store.HasName(file.Meta.Folder, newName) {
+				Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+				return
+			}
+		}
 
 		out, resolvedTarget, err := text.StrictTranscode(file.Bytes, text.TranscodeParams{
 			SourceEncoding: sourceEncoding,
@@ -87,17 +115,45 @@ func transcodeFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
+		if newName != "" {
+			// 新文件沿用源文件的有效期；转码期间源文件已过期时不再创建。
+			now := time.Now()
+			if file.Meta.Expired(now) {
+				Error(w, http.StatusNotFound, "NOT_FOUND", "源文件已过期", "")
+				return
+			}
+			created, err := d.Store.Add(store.AddParams{
+				Name:      newName,
+				Folder:    file.Meta.Folder,
+				Bytes:     out,
+				Encoding:  resolvedTarget,
+				IsText:    true,
+				Now:       now,
+				ExpiresAt: file.Meta.ExpiresAt,
+			})
+			if err != nil {
+				writeNewFileError(w, err, "写入转码结果失败")
+				return
+			}
+			setETag(w, created)
+			JSON(w, http.StatusCreated, metaToFileListItem(created))
+			return
+		}
+
 		updated, err := d.Store.ReplaceBytes(store.ReplaceParams{
 			ID:       id,
 			Bytes:    out,
 			Encoding: resolvedTarget,
 			IsText:   true,
+			IfMatch:  cond,
 		})
 		if err != nil {
 			switch {
 			case errors.Is(err, store.ErrNotFound):
 				// 并发删除场景：转码流程中目标文件已不存在。
 				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			case errors.Is(err, store.ErrPreconditionFailed):
+				writePreconditionFailed(w)
 			case errors.Is(err, store.ErrReplaceWouldExceed), errors.Is(err, store.ErrTooLarge):
 				Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法保存转码结果", "")
 			case errors.Is(err, store.ErrInvalidInput):
@@ -108,6 +164,7 @@ func transcodeFileHandler(d RouterDeps) http.HandlerFunc {
 			return
 		}
 
+		setETag(w, updated)
 		JSON(w, http.StatusOK, metaToFileListItem(updated))
 	}
 }
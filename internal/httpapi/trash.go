@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+type trashListItem struct {
+	fileListItem
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+	Evicted   bool      `json:"evicted"`
+}
+
+type emptyTrashResponse struct {
+	PurgedFiles int `json:"purged_files"`
+}
+
+// listTrashHandler 处理 GET /trash，最近删除的在前。
+func listTrashHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		items := d.Store.ListTrash()
+		out := make([]trashListItem, 0, len(items))
+		for _, it := range items {
+			out = append(out, trashListItem{
+				fileListItem: metaToFileListItem(it.Meta),
+				DeletedAt:    it.DeletedAt,
+				PurgeAt:      it.PurgeAt,
+				Evicted:      it.Evicted,
+			})
+		}
+		JSON(w, http.StatusOK, out)
+	}
+}
+
+// restoreTrashHandler 处理 POST /trash/{id}/restore，把文件恢复到原目录。
+func restoreTrashHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+
+		meta, err := d.Store.RestoreTrash(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			case errors.Is(err, store.ErrNameConflict):
+				Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+			case errors.Is(err, store.ErrInsufficientSpace):
+				Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法恢复该文件", "")
+			default:
+				Error(w, http.StatusInternalServerError, "INTERNAL", "恢复失败", err.Error())
+			}
+			return
+		}
+
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
+
+// purgeTrashHandler 处理 DELETE /trash/{id}，从回收站彻底删除文件。
+func purgeTrashHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+
+		if err := d.Store.PurgeTrash(id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "INTERNAL", "删除失败", err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// emptyTrashHandler 处理 DELETE /trash，清空回收站。
+func emptyTrashHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		n, err := d.Store.EmptyTrash()
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "清空回收站失败", err.Error())
+			return
+		}
+		JSON(w, http.StatusOK, emptyTrashResponse{PurgedFiles: n})
+	}
+}
@@ -0,0 +1,70 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, TrashTTL: time.Hour, MaxTrashBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("hello")})
+	b, _ := s.Add(store.AddParams{Name: "b.txt", Bytes: []byte("world")})
+
+	h := NewRouter(RouterDeps{ExternalOrigin: "http://127.0.0.1:8080", Store: s})
+	do := func(method, url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(method, url, nil))
+		return rr
+	}
+
+	for _, id := range []string{a.ID, b.ID} {
+		if rr := do(http.MethodDelete, "/api/files/"+id); rr.Code != http.StatusNoContent {
+			t.Fatalf("delete: %d body=%s", rr.Code, rr.Body.String())
+		}
+	}
+	rr := do(http.MethodGet, "/api/trash")
+	var items []trashListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != b.ID || items[0].DeletedAt.IsZero() || !items[0].PurgeAt.After(items[0].DeletedAt) {
+		t.Fatalf("unexpected trash listing: %s", rr.Body.String())
+	}
+
+	if _, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	rr = do(http.MethodPost, "/api/trash/"+a.ID+"/restore")
+	var e ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || rr.Code != http.StatusConflict || e.Code != "NAME_CONFLICT" {
+		t.Fatalf("expected 409 NAME_CONFLICT, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	rr = do(http.MethodPost, "/api/trash/"+b.ID+"/restore")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("restore: %d body=%s", rr.Code, rr.Body.String())
+	}
+	if _, err := s.GetMeta(b.ID); err != nil {
+		t.Fatalf("restored file missing: %v", err)
+	}
+	if rr := do(http.MethodPost, "/api/trash/"+b.ID+"/restore"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+
+	if rr := do(http.MethodDelete, "/api/trash/"+a.ID); rr.Code != http.StatusNoContent {
+		t.Fatalf("purge: %d body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodDelete, "/api/trash/"+a.ID); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/api/trash"); rr.Code != http.StatusOK || rr.Body.String() != "{\"purged_files\":0}\n" {
+		t.Fatalf("empty trash: %d body=%q", rr.Code, rr.Body.String())
+	}
+}
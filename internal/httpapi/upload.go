@@ -1,11 +1,16 @@
 package httpapi
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,7 +18,14 @@ import (
 	"go-learn/internal/text"
 )
 
-const multipartOverheadBytes = 2 * 1024 * 1024
+const (
+	multipartOverheadBytes = 2 * 1024 * 1024
+	// maxFormFieldBytes 限制文件分片之前的普通表单字段（如 expires_in）的大小。
+	maxFormFieldBytes = 1024
+
+	// headerContentSHA256 携带客户端计算的文件内容 SHA-256（十六进制），也可用文件之前的 sha256 表单字段。
+	headerContentSHA256 = "X-Content-SHA256"
+)
 
 func uploadFileHandler(d RouterDeps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -78,7 +90,7 @@ func saveUploadedFile(w http.ResponseWriter, r *http.Request, d RouterDeps) (sto
 		return store.FileMeta{}, false
 	}
 
-	part, fileName, err := readFilePart(mr)
+	part, fileName, fields, err := readFilePart(mr)
 	if err != nil {
 		if isMaxBytesError(err) {
 			Error(w, http.StatusRequestEntityTooLarge, "TOO_LARGE", "请求体过大", "")
@@ -89,17 +101,53 @@ func saveUploadedFile(w http.ResponseWriter, r *http.Request, d RouterDeps) (sto
 	}
 	defer part.Close()
 
-	if d.Store.HasName(fileName) {
+	folder, err := store.CleanFolder(fields["folder"])
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "目录路径不合法", "")
+		return store.FileMeta{}, false
+	}
+	// 与文件名一样在预留、读取内容之前检查：目录不存在的上传不占用容量。
+	if !d.Store.HasFolder(folder) {
+		Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
+		return store.FileMeta{}, false
+	}
+	onConflict, err := conflictPolicy(fields["on_conflict"], d)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "重名处理方式不合法", err.Error())
+		return store.FileMeta{}, false
+	}
+	// 文件名在预留、读取内容之前检查；重名检查也要用规范化后的名称。
+	fileName, err = d.Store.CleanName(fileName)
+	if err != nil {
+		if !writeNameError(w, err) {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "文件名不合法", err.Error())
+		}
+		return store.FileMeta{}, false
+	}
+	// 其他策略由 store 在提交时处理重名。
+	if onConflict == store.ConflictReject && d.Store.HasName(folder, fileName) {
 		Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
 		return store.FileMeta{}, false
 	}
 
-	// 上传前的“最佳努力”预淘汰：使用 Content-Length 作为上界估算，尽量降低读取大文件前的内存压力。
-	estimated := r.ContentLength
-	if estimated <= 0 || estimated > d.MaxFileBytes {
-		estimated = d.MaxFileBytes
+	now := time.Now()
+	expiresAt, err := parseExpiry(fields, now, d.DefaultTTL)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "有效期不合法", err.Error())
+		return store.FileMeta{}, false
+	}
+	wantSum, err := expectedSHA256(r, fields)
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "校验值不合法", err.Error())
+		return store.FileMeta{}, false
 	}
-	if err := d.Store.EvictToFit(estimated); err != nil {
+
+	// 读取请求体之前按 Content-Length（上界）预留容量：获准的上传在保存时放得下，
+	// 同时读取中的请求体总量也不会超过总量上限。预留不淘汰文件，淘汰由 Add 按实际存放的大小
+	// （去重、压缩后）进行。
+	estimated := min(r.ContentLength, d.MaxFileBytes)
+	res, err := d.Store.Reserve(estimated)
+	if err != nil {
 		if errors.Is(err, store.ErrInsufficientSpace) || errors.Is(err, store.ErrTooLarge) {
 			Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法接收该上传", "")
 			return store.FileMeta{}, false
@@ -107,6 +155,7 @@ func saveUploadedFile(w http.ResponseWriter, r *http.Request, d RouterDeps) (sto
 		Error(w, http.StatusInternalServerError, "INTERNAL", "预处理失败", err.Error())
 		return store.FileMeta{}, false
 	}
+	defer res.Release()
 
 	data, err := readAtMost(part, d.MaxFileBytes)
 	if err != nil {
@@ -121,24 +170,43 @@ func saveUploadedFile(w http.ResponseWriter, r *http.Request, d RouterDeps) (sto
 		Error(w, http.StatusBadRequest, "BAD_REQUEST", "读取上传内容失败", err.Error())
 		return store.FileMeta{}, false
 	}
+	// 校验失败的内容不写入 store：弱网下传输损坏的文件直接拒绝，由客户端重传。
+	if wantSum != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != wantSum {
+			Error(w, http.StatusBadRequest, "CHECKSUM_MISMATCH", "内容校验失败，文件可能在传输中损坏，请重新上传", "expected "+wantSum+", got "+got)
+			return store.FileMeta{}, false
+		}
+	}
 
 	isText, enc := text.DetectTextAndEncoding(data)
 
 	meta, err := d.Store.Add(store.AddParams{
-		Name:     fileName,
-		Bytes:    data,
-		Encoding: enc,
-		IsText:   isText,
-		Now:      time.Now(),
+		Name:        fileName,
+		Folder:      folder,
+		Bytes:       data,
+		Encoding:    enc,
+		IsText:      isText,
+		Now:         now,
+		ExpiresAt:   expiresAt,
+		Reservation: res,
+		OnConflict:  onConflict,
 	})
 	if err != nil {
+		if writeNameError(w, err) {
+			return store.FileMeta{}, false
+		}
 		switch {
 		case errors.Is(err, store.ErrNameConflict):
 			Error(w, http.StatusConflict, "NAME_CONFLICT", "重名", "")
+		case errors.Is(err, store.ErrFolderNotFound):
+			Error(w, http.StatusNotFound, "NOT_FOUND", "目录不存在", "")
 		case errors.Is(err, store.ErrTooLarge):
 			Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "超过总内存上限，无法保存该文件", "")
-		case errors.Is(err, store.ErrInsufficientSpace):
+		case errors.Is(err, store.ErrInsufficientSpace), errors.Is(err, store.ErrReplaceWouldExceed):
 			Error(w, http.StatusInsufficientStorage, "INSUFFICIENT_STORAGE", "空间不足，无法保存该文件", "")
+		case errors.Is(err, store.ErrInvalidInput):
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "请求不合法", err.Error())
 		default:
 			Error(w, http.StatusInternalServerError, "INTERNAL", "保存失败", err.Error())
 		}
@@ -149,35 +217,128 @@ func saveUploadedFile(w http.ResponseWriter, r *http.Request, d RouterDeps) (sto
 }
 
 func metaToFileListItem(meta store.FileMeta) fileListItem {
-	return fileListItem{
-		ID:        meta.ID,
-		Name:      meta.Name,
-		CreatedAt: meta.CreatedAt,
-		SizeBytes: meta.SizeBytes,
-		Encoding:  normalizeEncoding(meta.Encoding),
-		IsText:    meta.IsText,
+	item := fileListItem{
+		ID:           meta.ID,
+		Name:         meta.Name,
+		Folder:       meta.Folder,
+		CreatedAt:    meta.CreatedAt,
+		SizeBytes:    meta.SizeBytes,
+		Encoding:     normalizeEncoding(meta.Encoding),
+		IsText:       meta.IsText,
+		Version:      meta.Version,
+		UpdatedAt:    meta.UpdatedAt,
+		PrevVersions: meta.PrevVersions,
+		Pinned:       meta.Pinned,
+		Tags:         meta.Tags,
+		Note:         meta.Note,
+		SHA256:       meta.SHA256,
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
 	}
+	if !meta.ExpiresAt.IsZero() {
+		expiresAt := meta.ExpiresAt
+		remaining := int64(time.Until(expiresAt) / time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		item.ExpiresAt = &expiresAt
+		item.RemainingSeconds = &remaining
+	}
+	return item
 }
 
-func readFilePart(mr *multipart.Reader) (*multipart.Part, string, error) {
+// readFilePart 返回 file 分片，以及它之前的普通表单字段（如 folder、expires_in；浏览器按表单顺序发送，字段需放在文件之前）。
+func readFilePart(mr *multipart.Reader) (*multipart.Part, string, map[string]string, error) {
+	fields := make(map[string]string)
 	for {
 		part, err := mr.NextPart()
 		if err != nil {
 			if err == io.EOF {
-				return nil, "", errors.New("missing file part")
+				return nil, "", nil, errors.New("missing file part")
 			}
-			return nil, "", err
+			return nil, "", nil, err
 		}
 		if part.FormName() != "file" {
+			if name := part.FormName(); name != "" && part.FileName() == "" {
+				v, err := readAtMost(part, maxFormFieldBytes)
+				if err != nil {
+					_ = part.Close()
+					return nil, "", nil, fmt.Errorf("form field %q: %w", name, err)
+				}
+				fields[name] = string(v)
+			}
 			_ = part.Close()
 			continue
 		}
 		if part.FileName() == "" {
 			_ = part.Close()
-			return nil, "", errors.New("file name is empty")
+			return nil, "", nil, errors.New("file name is empty")
 		}
-		return part, part.FileName(), nil
+		return part, part.FileName(), fields, nil
+	}
+}
+
+// conflictPolicy 返回请求指定的重名处理策略（on_conflict），未指定时使用配置的默认策略。
+func conflictPolicy(v string, d RouterDeps) (string, error) {
+	policy := strings.TrimSpace(v)
+	if policy == "" {
+		policy = d.OnNameConflict
+	}
+	if policy == "" {
+		policy = store.ConflictReject
+	}
+	return policy, store.CheckConflictPolicy(policy)
+}
+
+// parseExpiry 解析可选的有效期字段：expires_at（RFC 3339 时间）或 expires_in（秒），二者至多给一个；
+// 都未给出时使用 defaultTTL（0 表示永不过期）。
+func parseExpiry(fields map[string]string, now time.Time, defaultTTL time.Duration) (time.Time, error) {
+	at := strings.TrimSpace(fields["expires_at"])
+	in := strings.TrimSpace(fields["expires_in"])
+	switch {
+	case at != "" && in != "":
+		return time.Time{}, errors.New("expires_at and expires_in are mutually exclusive")
+	case at != "":
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expires_at: %w", err)
+		}
+		if !t.After(now) {
+			return time.Time{}, errors.New("expires_at must be in the future")
+		}
+		return t, nil
+	case in != "":
+		secs, err := strconv.ParseInt(in, 10, 64)
+		if err != nil || secs <= 0 || secs > int64(math.MaxInt64/time.Second) {
+			return time.Time{}, errors.New("expires_in must be a positive number of seconds")
+		}
+		return now.Add(time.Duration(secs) * time.Second), nil
+	case defaultTTL > 0:
+		return now.Add(defaultTTL), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// expectedSHA256 返回客户端给出的内容摘要（小写十六进制），未给出时返回空串；请求头与表单字段同时给出时须一致。
+func expectedSHA256(r *http.Request, fields map[string]string) (string, error) {
+	header := strings.ToLower(strings.TrimSpace(r.Header.Get(headerContentSHA256)))
+	field := strings.ToLower(strings.TrimSpace(fields["sha256"]))
+	if header != "" && field != "" && header != field {
+		return "", errors.New("sha256 header and form field differ")
+	}
+	want := header
+	if want == "" {
+		want = field
+	}
+	if want == "" {
+		return "", nil
+	}
+	if b, err := hex.DecodeString(want); err != nil || len(b) != sha256.Size {
+		return "", errors.New("sha256 must be 64 hex digits")
 	}
+	return want, nil
 }
 
 func readAtMost(r io.Reader, max int64) ([]byte, error) {
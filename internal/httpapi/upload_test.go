@@ -2,9 +2,15 @@ package httpapi
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"go-learn/internal/store"
@@ -144,10 +150,168 @@ func TestUploadSuccess(t *testing.T) {
 	}
 }
 
+// failingAddStore 仅覆盖 Add，用于验证 handler 只依赖 store.FileStore 接口。
+type failingAddStore struct {
+	store.FileStore
+}
+
+func (failingAddStore) Add(store.AddParams) (store.FileMeta, error) {
+	return store.FileMeta{}, errors.New("backend unavailable")
+}
+
+func TestUploadStoreFailureReturnsInternal(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, contentType := newMultipartBody(t, "a.txt", []byte("hello"))
+	req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = int64(len(body))
+
+	rr := httptest.NewRecorder()
+	NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          failingAddStore{FileStore: s},
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+	}).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if files := s.Stats().Files; files != 0 {
+		t.Fatalf("expected nothing stored, got %d files", files)
+	}
+	// 保存失败时预留同样归还。
+	res, err := s.Reserve(1024*1024)
+	if err != nil {
+		t.Fatalf("reservation leaked: %v", err)
+	}
+	res.Release()
+}
+
+func TestUploadChecksumVerified(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	sum := sha256.Sum256([]byte("hello"))
+	good := hex.EncodeToString(sum[:])
+	bad := strings.Repeat("0", 64)
+
+	cases := []struct {
+		name       string
+		header     string
+		field      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"header mismatch", bad, "", http.StatusBadRequest, "CHECKSUM_MISMATCH"},
+		{"field mismatch", "", bad, http.StatusBadRequest, "CHECKSUM_MISMATCH"},
+		{"malformed", "abc", "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"header and field differ", good, bad, http.StatusBadRequest, "BAD_REQUEST"},
+		{"field match", "", strings.ToUpper(good), http.StatusCreated, ""},
+		{"header match", good, "", http.StatusCreated, ""},
+	}
+	for i, c := range cases {
+		var fields map[string]string
+		if c.field != "" {
+			fields = map[string]string{"sha256": c.field}
+		}
+		body, contentType := newMultipartBodyWithFields(t, fields, fmt.Sprintf("%d.txt", i), []byte("hello"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if c.header != "" {
+			req.Header.Set(headerContentSHA256, c.header)
+		}
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		if rr.Code != c.wantStatus {
+			t.Fatalf("%s: expected %d, got %d body=%s", c.name, c.wantStatus, rr.Code, rr.Body.String())
+		}
+		if c.wantCode != "" {
+			var e ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &e); err != nil || e.Code != c.wantCode {
+				t.Fatalf("%s: expected code %s, got %s", c.name, c.wantCode, rr.Body.String())
+			}
+		}
+	}
+	// 校验失败的上传不会写入 store。
+	if files := s.Stats().Files; files != 2 {
+		t.Fatalf("expected only verified uploads stored, got %d files", files)
+	}
+}
+
+func TestUploadRespectsReservations(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(2),
+		MaxFileBytes:   1024,
+	})
+	upload := func(name string) *httptest.ResponseRecorder {
+		body, contentType := newMultipartBody(t, name, []byte("hello"))
+		req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.ContentLength = int64(len(body))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// 其他上传已预留了大部分空间：按 Content-Length 放不下的上传在读取内容前就被拒绝。
+	held, err := s.Reserve(4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr := upload("a.txt"); rr.Code != http.StatusInsufficientStorage {
+		t.Fatalf("expected 507, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	held.Release()
+
+	if rr := upload("a.txt"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	// 提交后预留按实际大小结算，剩余空间全部归还。
+	res, err := s.Reserve(4096-5)
+	if err != nil {
+		t.Fatalf("upload should not keep its reservation: %v", err)
+	}
+	res.Release()
+	if files := s.Stats().Files; files != 1 {
+		t.Fatalf("expected 1 file, got %d", files)
+	}
+}
+
 func newMultipartBody(t *testing.T, filename string, content []byte) ([]byte, string) {
+	t.Helper()
+	return newMultipartBodyWithFields(t, nil, filename, content)
+}
+
+// newMultipartBodyWithFields 在文件分片之前写入普通表单字段。
+func newMultipartBodyWithFields(t *testing.T, fields map[string]string, filename string, content []byte) ([]byte, string) {
 	t.Helper()
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
 	fw, err := w.CreateFormFile("file", filename)
 	if err != nil {
 		t.Fatal(err)
@@ -161,3 +325,43 @@ func newMultipartBody(t *testing.T, filename string, content []byte) ([]byte, st
 	return buf.Bytes(), w.FormDataContentType()
 }
 
+
+func TestCompressibleUploadDoesNotEvictByRawSize(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 4096, Compression: store.CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不可压缩的已有文件占去大部分空间。
+	var noise []byte
+	for i := 0; len(noise) < 3000; i++ {
+		sum := sha256.Sum256([]byte(fmt.Sprint(i)))
+		noise = append(noise, sum[:]...)
+	}
+	old, err := s.Add(store.AddParams{Name: "old.bin", Bytes: noise})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		UploadSem:      NewSemaphore(1),
+		MaxFileBytes:   2048,
+	})
+
+	// 原始大小放不下，压缩后放得下：预留不淘汰，Add 按压缩后的大小判断。
+	body, contentType := newMultipartBody(t, "a.txt", bytes.Repeat([]byte("a"), 1500))
+	req := httptest.NewRequest(http.MethodPost, "/api/files", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = int64(len(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if _, err := s.GetMeta(old.ID); err != nil {
+		t.Fatalf("existing file evicted for the raw upload size: %v", err)
+	}
+	if st := s.Stats(); st.Files != 2 || st.ReservedBytes != 0 || len(s.RecentEvictions()) != 0 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+}
@@ -0,0 +1,169 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go-learn/internal/store"
+)
+
+type versionListItem struct {
+	Version   int       `json:"version"`
+	SizeBytes int64     `json:"size_bytes"`
+	Encoding  string    `json:"encoding"`
+	IsText    bool      `json:"is_text"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"`
+}
+
+func listVersionsHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+			return
+		}
+
+		versions, err := d.Store.ListVersions(id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+				return
+			}
+			Error(w, http.StatusInternalServerError, "INTERNAL", "读取版本列表失败", err.Error())
+			return
+		}
+
+		// store 保证当前版本在第一个。
+		out := make([]versionListItem, 0, len(versions))
+		for i, v := range versions {
+			out = append(out, versionListItem{
+				Version:   v.Version,
+				SizeBytes: v.SizeBytes,
+				Encoding:  normalizeEncoding(v.Encoding),
+				IsText:    v.IsText,
+				CreatedAt: v.CreatedAt,
+				Current:   i == 0,
+			})
+		}
+		JSON(w, http.StatusOK, out)
+	}
+}
+
+func createVersionDownloadTokenHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+		if d.Tokens == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "token store not initialized", "")
+			return
+		}
+		if d.DownloadTTL <= 0 {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "download ttl not initialized", "")
+			return
+		}
+
+		id, version, ok := versionParams(w, r)
+		if !ok {
+			return
+		}
+		if !versionExists(w, d, id, version) {
+			return
+		}
+
+		// 版本号记录在 token 中；链接里的 ?version 只是便于辨认，下载时须与之一致。
+		it, err := d.Tokens.CreateVersion(tokenKindDownload, id, version, d.DownloadTTL)
+		if err != nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "创建下载链接失败", err.Error())
+			return
+		}
+
+		JSON(w, http.StatusOK, downloadTokenResponse{
+			Token: it.Token,
+			URL:   "/dl/" + it.Token + "?version=" + strconv.Itoa(version),
+		})
+	}
+}
+
+func restoreVersionHandler(d RouterDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if d.Store == nil {
+			Error(w, http.StatusInternalServerError, "INTERNAL", "store not initialized", "")
+			return
+		}
+
+		id, version, ok := versionParams(w, r)
+		if !ok {
+			return
+		}
+
+		meta, err := d.Store.RestoreVersion(id, version)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			case errors.Is(err, store.ErrVersionNotFound):
+				Error(w, http.StatusNotFound, "NOT_FOUND", "版本不存在", "")
+			default:
+				Error(w, http.StatusInternalServerError, "INTERNAL", "恢复版本失败", err.Error())
+			}
+			return
+		}
+
+		JSON(w, http.StatusOK, metaToFileListItem(meta))
+	}
+}
+
+func versionParams(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "缺少文件 id", "")
+		return "", 0, false
+	}
+	version, err := parseVersion(chi.URLParam(r, "version"))
+	if err != nil {
+		Error(w, http.StatusBadRequest, "BAD_REQUEST", "版本号不合法", "")
+		return "", 0, false
+	}
+	return id, version, true
+}
+
+func parseVersion(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, errors.New("version must be > 0")
+	}
+	return v, nil
+}
+
+func versionExists(w http.ResponseWriter, d RouterDeps, id string, version int) bool {
+	versions, err := d.Store.ListVersions(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			Error(w, http.StatusNotFound, "NOT_FOUND", "not found", "")
+			return false
+		}
+		Error(w, http.StatusInternalServerError, "INTERNAL", "读取版本列表失败", err.Error())
+		return false
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return true
+		}
+	}
+	Error(w, http.StatusNotFound, "NOT_FOUND", "版本不存在", "")
+	return false
+}
@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-learn/internal/store"
+	"go-learn/internal/text"
+	"go-learn/internal/tokens"
+)
+
+func TestTranscodeCanBeUndoneViaVersions(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, MaxVersions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gbk := []byte{0xc4, 0xe3, 0xba, 0xc3} // "你好" in GBK
+	meta, err := s.Add(store.AddParams{Name: "a.txt", Bytes: gbk, Encoding: text.EncodingGBK, IsText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{
+		ExternalOrigin: "http://127.0.0.1:8080",
+		Store:          s,
+		Tokens:         ts,
+		DownloadTTL:    60 * time.Second,
+		UploadSem:      NewSemaphore(1),
+		TranscodeSem:   NewSemaphore(1),
+		MaxFileBytes:   1024,
+	})
+	do := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	body, _ := json.Marshal(transcodeFileRequest{SourceEncoding: text.EncodingGBK, TargetEncoding: text.EncodingUTF8})
+	if rr := do(http.MethodPost, "/api/files/"+meta.ID+"/transcode", body); rr.Code != http.StatusOK {
+		t.Fatalf("transcode: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+
+	rr := do(http.MethodGet, "/api/files/"+meta.ID+"/versions", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list versions: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var versions []versionListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &versions); err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || !versions[0].Current || versions[0].Encoding != text.EncodingUTF8 ||
+		versions[1].Version != 1 || versions[1].Encoding != text.EncodingGBK {
+		t.Fatalf("unexpected versions: %#v", versions)
+	}
+
+	// 下载历史版本得到原始字节。
+	rr = do(http.MethodPost, "/api/files/"+meta.ID+"/versions/1/download-token", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("version token: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var tok downloadTokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &tok); err != nil {
+		t.Fatal(err)
+	}
+	rr = do(http.MethodGet, tok.URL, nil)
+	if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), gbk) {
+		t.Fatalf("version download: code=%d body=%x", rr.Code, rr.Body.Bytes())
+	}
+
+	if rr := do(http.MethodPost, "/api/files/"+meta.ID+"/versions/9/restore", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("restore missing version: expected 404, got %d", rr.Code)
+	}
+	if rr := do(http.MethodPost, "/api/files/"+meta.ID+"/versions/x/restore", nil); rr.Code != http.StatusBadRequest {
+		t.Fatalf("restore bad version: expected 400, got %d", rr.Code)
+	}
+
+	rr = do(http.MethodPost, "/api/files/"+meta.ID+"/versions/1/restore", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("restore: expected 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	var item fileListItem
+	if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Version != 1 || item.Encoding != text.EncodingGBK || item.PrevVersions != 1 {
+		t.Fatalf("unexpected restored item: %#v", item)
+	}
+	f, err := s.Get(meta.ID)
+	if err != nil || !bytes.Equal(f.Bytes, gbk) {
+		t.Fatalf("expected original bytes after undo, got %x err=%v", f.Bytes, err)
+	}
+}
+
+func TestVersionDownloadTokenBindsVersion(t *testing.T) {
+	s, err := store.NewInMemoryStore(store.NewParams{MaxFiles: 10, MaxTotalBytes: 1024 * 1024, MaxVersions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := s.Add(store.AddParams{Name: "a.txt", Bytes: []byte("v1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(store.ReplaceParams{ID: meta.ID, Bytes: []byte("v2")}); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tokens.NewStore(tokens.Options{})
+	t.Cleanup(ts.Close)
+	h := NewRouter(RouterDeps{Store: s, Tokens: ts, DownloadTTL: 60 * time.Second})
+	do := func(method, url string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(method, url, nil))
+		return rr
+	}
+	versionToken := func() string {
+		t.Helper()
+		rr := do(http.MethodPost, "/api/files/"+meta.ID+"/versions/1/download-token")
+		var tok downloadTokenResponse
+		if rr.Code != http.StatusOK || json.Unmarshal(rr.Body.Bytes(), &tok) != nil {
+			t.Fatalf("version token: code=%d body=%s", rr.Code, rr.Body.String())
+		}
+		return tok.Token
+	}
+
+	// 版本号以 token 为准：去掉 ?version 仍下载版本 1，改成其他版本被拒绝。
+	if rr := do(http.MethodGet, "/dl/"+versionToken()); rr.Code != http.StatusOK || rr.Body.String() != "v1" {
+		t.Fatalf("download without param: code=%d body=%q", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodGet, "/dl/"+versionToken()+"?version=2"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("mismatched version: expected 400, got %d body=%q", rr.Code, rr.Body.String())
+	}
+
+	// 普通下载链接不能借 ?version 读取历史版本。
+	it, err := ts.Create(tokenKindDownload, meta.ID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr := do(http.MethodGet, "/dl/"+it.Token+"?version=1"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("version on plain token: expected 400, got %d body=%q", rr.Code, rr.Body.String())
+	}
+}
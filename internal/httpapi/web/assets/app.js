@@ -1,12 +1,36 @@
 (() => {
-  const ENCODINGS = ["UTF-8", "GB18030", "GBK", "Big5", "Windows-1252", "ISO-8859-1"];
+  const ENCODINGS = ["UTF-8", "UTF-16LE", "UTF-16BE", "UTF-32LE", "UTF-32BE", "GB18030", "GBK", "Big5", "Windows-1252", "ISO-8859-1"];
+  // ALL_FOLDERS 表示列出全部目录中的文件；根目录为 ""。
+  const ALL_FOLDERS = "*";
+  // PAGE_SIZE 为每次加载的条数，其余通过“加载更多”按游标继续加载。
+  const PAGE_SIZE = 100;
   let selectedFileIdForBridgeDownload = "";
+  let currentFolder = ALL_FOLDERS;
+  let nextCursor = "";
+  let shownCount = 0;
 
   const uploadForm = document.getElementById("upload-form");
   const uploadMsg = document.getElementById("upload-msg");
+  const uploadFolder = document.getElementById("upload-folder");
+  const folderSelect = document.getElementById("folder-select");
+  const newFolderBtn = document.getElementById("new-folder-btn");
+  const renameFolderBtn = document.getElementById("rename-folder-btn");
+  const deleteFolderBtn = document.getElementById("delete-folder-btn");
   const refreshBtn = document.getElementById("refresh-btn");
+  const nameFilter = document.getElementById("name-filter");
+  const tagFilter = document.getElementById("tag-filter");
+  const sortSelect = document.getElementById("sort-select");
+  const moreBtn = document.getElementById("more-btn");
   const filesBody = document.getElementById("files-body");
   const listMsg = document.getElementById("list-msg");
+  const trashBody = document.getElementById("trash-body");
+  const trashMsg = document.getElementById("trash-msg");
+  const trashRefreshBtn = document.getElementById("trash-refresh-btn");
+  const trashEmptyBtn = document.getElementById("trash-empty-btn");
+  const statsUsage = document.getElementById("stats-usage");
+  const statsMsg = document.getElementById("stats-msg");
+  const statsRefreshBtn = document.getElementById("stats-refresh-btn");
+  const evictionsBody = document.getElementById("evictions-body");
   const bridgeUploadBtn = document.getElementById("bridge-upload-btn");
   const bridgeDownloadBtn = document.getElementById("bridge-download-btn");
   const qrMsg = document.getElementById("qr-msg");
@@ -19,6 +43,11 @@
   }
 
   async function requestJSON(url, init) {
+    return (await requestWithHeaders(url, init)).data;
+  }
+
+  // requestWithHeaders 同 requestJSON，另外返回响应头（分页信息在响应头中）。
+  async function requestWithHeaders(url, init) {
     const res = await fetch(url, init);
     const text = await res.text();
     let data = null;
@@ -31,9 +60,18 @@
     }
     if (!res.ok) {
       const message = data && data.message ? data.message : `HTTP ${res.status}`;
-      throw new Error(message);
+      const err = new Error(message);
+      err.code = data && data.code;
+      throw err;
     }
-    return data;
+    return { data, headers: res.headers };
+  }
+
+  // sha256Hex 计算文件内容摘要，随上传发送供服务端校验；非安全上下文（如局域网 http）没有 crypto.subtle，此时返回空串、不做校验。
+  async function sha256Hex(file) {
+    if (!file || !window.crypto || !window.crypto.subtle) return "";
+    const digest = await window.crypto.subtle.digest("SHA-256", await file.arrayBuffer());
+    return Array.from(new Uint8Array(digest), (b) => b.toString(16).padStart(2, "0")).join("");
   }
 
   function sizeText(n) {
@@ -50,6 +88,42 @@
     return d.toLocaleString();
   }
 
+  function remainingText(file) {
+    if (typeof file.remaining_seconds !== "number") return "永久";
+    const s = file.remaining_seconds;
+    if (s <= 0) return "已过期";
+    if (s < 60) return `${s} 秒`;
+    if (s < 3600) return `${Math.floor(s / 60)} 分钟`;
+    if (s < 86400) return `${Math.floor(s / 3600)} 小时 ${Math.floor((s % 3600) / 60)} 分钟`;
+    return `${Math.floor(s / 86400)} 天 ${Math.floor((s % 86400) / 3600)} 小时`;
+  }
+
+  // copyName 为副本建议的文件名：a.txt -> a (副本).txt。
+  function copyName(name) {
+    const dot = name.lastIndexOf(".");
+    if (dot <= 0) return `${name} (副本)`;
+    return `${name.slice(0, dot)} (副本)${name.slice(dot)}`;
+  }
+
+  function folderLabel(path) {
+    return path === "" ? "根目录" : `/${path}`;
+  }
+
+  function jsonInit(method, body) {
+    return {
+      method,
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(body),
+    };
+  }
+
+  // ifMatch 为改名/删除/转码附带列表中看到的内容摘要：文件已被他人修改时服务端返回 412，不会覆盖对方的修改。
+  function ifMatch(file, headers) {
+    const h = { ...(headers || {}) };
+    if (file.sha256) h["If-Match"] = `"${file.sha256}"`;
+    return h;
+  }
+
   function buildActionButton(label, cls, onClick) {
     const btn = document.createElement("button");
     btn.type = "button";
@@ -59,11 +133,11 @@
     return btn;
   }
 
-  function renderFiles(files) {
-    filesBody.innerHTML = "";
-    if (!files.length) {
+  function renderFiles(files, append) {
+    if (!append) filesBody.innerHTML = "";
+    if (!files.length && !append) {
       const tr = document.createElement("tr");
-      tr.innerHTML = `<td colspan="6">暂无文件</td>`;
+      tr.innerHTML = `<td colspan="7">暂无文件</td>`;
       filesBody.appendChild(tr);
       return;
     }
@@ -73,7 +147,20 @@
       const transcodeEnabled = !!file.is_text;
 
       const nameCell = document.createElement("td");
-      nameCell.textContent = file.name;
+      const shownName = currentFolder === ALL_FOLDERS && file.folder ? `${file.folder}/${file.name}` : file.name;
+      nameCell.textContent = file.pinned ? `[置顶] ${shownName}` : shownName;
+      if (file.tags && file.tags.length) {
+        const tags = document.createElement("span");
+        tags.className = "tags";
+        tags.textContent = file.tags.map((t) => `#${t}`).join(" ");
+        nameCell.appendChild(tags);
+      }
+      if (file.note) {
+        const note = document.createElement("span");
+        note.className = "note";
+        note.textContent = file.note;
+        nameCell.appendChild(note);
+      }
       tr.appendChild(nameCell);
 
       const timeCell = document.createElement("td");
@@ -92,6 +179,11 @@
       textCell.textContent = file.is_text ? "是" : "否";
       tr.appendChild(textCell);
 
+      const expiryCell = document.createElement("td");
+      expiryCell.textContent = remainingText(file);
+      if (file.expires_at) expiryCell.title = `过期时间: ${fmtDate(file.expires_at)}`;
+      tr.appendChild(expiryCell);
+
       const actionsCell = document.createElement("td");
       const actions = document.createElement("div");
       actions.className = "actions";
@@ -108,24 +200,81 @@
       actions.appendChild(buildActionButton("重命名", "alt", async () => {
         const next = window.prompt("输入新文件名", file.name);
         if (!next || next === file.name) return;
+        const rename = (onConflict) => requestJSON(`/api/files/${encodeURIComponent(file.id)}`, {
+          method: "PATCH",
+          headers: ifMatch(file, { "Content-Type": "application/json" }),
+          body: JSON.stringify({ name: next, on_conflict: onConflict }),
+        });
         try {
-          await requestJSON(`/api/files/${encodeURIComponent(file.id)}`, {
-            method: "PATCH",
-            headers: { "Content-Type": "application/json" },
-            body: JSON.stringify({ name: next }),
-          });
+          let meta;
+          try {
+            meta = await rename("");
+          } catch (err) {
+            if (err.code !== "NAME_CONFLICT" || !window.confirm(`已有名为 ${next} 的文件，是否自动编号（保留两者）?`)) throw err;
+            meta = await rename("suffix");
+          }
           await loadFiles();
-          setMsg(listMsg, "重命名成功");
+          setMsg(listMsg, `已重命名为 ${meta.name}`);
         } catch (err) {
           setMsg(listMsg, `重命名失败: ${err.message}`);
         }
       }));
 
+      actions.appendChild(buildActionButton("复制", "alt", async () => {
+        const next = window.prompt("输入副本文件名", copyName(file.name));
+        if (!next) return;
+        try {
+          await requestJSON(`/api/files/${encodeURIComponent(file.id)}/copy`, jsonInit("POST", { name: next }));
+          await loadFiles();
+          setMsg(listMsg, "已复制");
+        } catch (err) {
+          setMsg(listMsg, `复制失败: ${err.message}`);
+        }
+      }));
+
+      actions.appendChild(buildActionButton("标签/备注", "alt", async () => {
+        const tagsInput = window.prompt("输入标签（用逗号分隔，留空清除）", (file.tags || []).join(", "));
+        if (tagsInput === null) return;
+        const noteInput = window.prompt("输入备注（留空清除）", file.note || "");
+        if (noteInput === null) return;
+        const base = `/api/files/${encodeURIComponent(file.id)}`;
+        try {
+          const tags = tagsInput.split(",").map((t) => t.trim()).filter(Boolean);
+          await requestJSON(`${base}/tags`, jsonInit("PUT", { tags }));
+          await requestJSON(`${base}/note`, jsonInit("PUT", { note: noteInput }));
+          await loadFiles();
+          setMsg(listMsg, "已保存标签与备注");
+        } catch (err) {
+          setMsg(listMsg, `保存失败: ${err.message}`);
+        }
+      }));
+
+      actions.appendChild(buildActionButton("移动", "alt", async () => {
+        const next = window.prompt("输入目标目录（留空为根目录）", file.folder || "");
+        if (next === null || next.trim() === (file.folder || "")) return;
+        try {
+          await requestJSON(`/api/files/${encodeURIComponent(file.id)}/folder`, jsonInit("PUT", { folder: next.trim() }));
+          await loadFiles();
+          setMsg(listMsg, "移动成功");
+        } catch (err) {
+          setMsg(listMsg, `移动失败: ${err.message}`);
+        }
+      }));
+
+      actions.appendChild(buildActionButton(file.pinned ? "取消置顶" : "置顶", "alt", async () => {
+        try {
+          await requestJSON(`/api/files/${encodeURIComponent(file.id)}/pin`, { method: file.pinned ? "DELETE" : "PUT" });
+          await loadFiles();
+          setMsg(listMsg, file.pinned ? "已取消置顶" : "已置顶，不会被自动淘汰");
+        } catch (err) {
+          setMsg(listMsg, `置顶失败: ${err.message}`);
+        }
+      }));
+
       actions.appendChild(buildActionButton("删除", "danger", async () => {
         if (!window.confirm(`确认删除 ${file.name} ?`)) return;
         try {
-          const res = await fetch(`/api/files/${encodeURIComponent(file.id)}`, { method: "DELETE" });
-          if (!res.ok) throw new Error(`HTTP ${res.status}`);
+          await requestJSON(`/api/files/${encodeURIComponent(file.id)}`, { method: "DELETE", headers: ifMatch(file) });
           await loadFiles();
           setMsg(listMsg, "删除成功");
         } catch (err) {
@@ -141,14 +290,16 @@
           setMsg(listMsg, "转码失败: 目标编码不在允许列表");
           return;
         }
+        const newName = window.prompt("另存为新文件（输入文件名；留空则覆盖原文件）", "");
+        if (newName === null) return;
         try {
           await requestJSON(`/api/files/${encodeURIComponent(file.id)}/transcode`, {
             method: "POST",
-            headers: { "Content-Type": "application/json" },
-            body: JSON.stringify({ sourceEncoding: source, targetEncoding: target }),
+            headers: ifMatch(file, { "Content-Type": "application/json" }),
+            body: JSON.stringify({ sourceEncoding: source, targetEncoding: target, newName: newName.trim() }),
           });
           await loadFiles();
-          setMsg(listMsg, "转码成功");
+          setMsg(listMsg, newName.trim() ? `已转码为新文件 ${newName.trim()}` : "转码成功");
         } catch (err) {
           setMsg(listMsg, `转码失败: ${err.message}`);
         }
@@ -156,6 +307,42 @@
       transcodeBtn.disabled = !transcodeEnabled;
       actions.appendChild(transcodeBtn);
 
+      const versionsURL = `/api/files/${encodeURIComponent(file.id)}/versions`;
+      const undoBtn = buildActionButton("撤销转码", "alt", async () => {
+        try {
+          const versions = await requestJSON(versionsURL);
+          const prev = versions.find((v) => !v.current);
+          if (!prev) {
+            setMsg(listMsg, "撤销失败: 没有可恢复的历史版本");
+            return;
+          }
+          if (!window.confirm(`恢复到版本 ${prev.version}（${prev.encoding}，${sizeText(prev.size_bytes)}）?`)) return;
+          await requestJSON(`${versionsURL}/${prev.version}/restore`, { method: "POST" });
+          await loadFiles();
+          setMsg(listMsg, "已撤销转码");
+        } catch (err) {
+          setMsg(listMsg, `撤销失败: ${err.message}`);
+        }
+      });
+      undoBtn.disabled = !(file.prev_versions > 0);
+      actions.appendChild(undoBtn);
+
+      const historyBtn = buildActionButton("历史版本", "alt", async () => {
+        try {
+          const versions = await requestJSON(versionsURL);
+          const lines = versions.map((v) =>
+            `v${v.version}  ${v.encoding}  ${sizeText(v.size_bytes)}  ${fmtDate(v.created_at)}${v.current ? "（当前）" : ""}`);
+          const input = window.prompt(`${lines.join("\n")}\n\n输入版本号下载`, "");
+          if (!input) return;
+          const data = await requestJSON(`${versionsURL}/${encodeURIComponent(input.trim())}/download-token`, { method: "POST" });
+          window.location.href = data.url;
+        } catch (err) {
+          setMsg(listMsg, `读取历史版本失败: ${err.message}`);
+        }
+      });
+      historyBtn.disabled = !(file.prev_versions > 0);
+      actions.appendChild(historyBtn);
+
       actions.appendChild(buildActionButton("设为下载二维码目标", "alt", () => {
         selectedFileIdForBridgeDownload = file.id;
         setMsg(qrMsg, `已选择: ${file.name}`);
@@ -167,15 +354,170 @@
     });
   }
 
+  async function loadFolders() {
+    const folders = await requestJSON("/api/folders");
+    const paths = ["", ...(Array.isArray(folders) ? folders.map((f) => f.path) : [])];
+    if (currentFolder !== ALL_FOLDERS && !paths.includes(currentFolder)) currentFolder = ALL_FOLDERS;
+
+    folderSelect.innerHTML = "";
+    folderSelect.appendChild(new Option("全部文件", ALL_FOLDERS));
+    paths.forEach((p) => folderSelect.appendChild(new Option(folderLabel(p), p)));
+    folderSelect.value = currentFolder;
+    // 在“全部文件”视图中上传到根目录。
+    uploadFolder.value = currentFolder === ALL_FOLDERS ? "" : currentFolder;
+    renameFolderBtn.disabled = currentFolder === ALL_FOLDERS || currentFolder === "";
+    deleteFolderBtn.disabled = renameFolderBtn.disabled;
+  }
+
+  function listParams() {
+    const params = new URLSearchParams();
+    if (currentFolder !== ALL_FOLDERS) params.set("folder", currentFolder);
+    const name = nameFilter.value.trim();
+    if (name) params.set("name", name);
+    const tag = tagFilter.value.trim();
+    if (tag) params.set("tag", tag);
+    const [sort, order] = sortSelect.value.split(":");
+    params.set("sort", sort);
+    params.set("order", order);
+    params.set("limit", String(PAGE_SIZE));
+    return params;
+  }
+
+  // fetchPage 加载一页文件；cursor 为空时从第一页重新加载。
+  async function fetchPage(cursor) {
+    const params = listParams();
+    if (cursor) params.set("cursor", cursor);
+    const { data, headers } = await requestWithHeaders(`/api/files?${params}`);
+    const files = Array.isArray(data) ? data : [];
+    renderFiles(files, !!cursor);
+    shownCount = (cursor ? shownCount : 0) + files.length;
+    nextCursor = headers.get("X-Next-Cursor") || "";
+    moreBtn.classList.toggle("hidden", !nextCursor);
+    const total = Number(headers.get("X-Total-Count"));
+    setMsg(listMsg, nextCursor && total ? `已显示 ${shownCount} / ${total}` : "");
+  }
+
   async function loadFiles() {
     setMsg(listMsg, "加载中...");
     try {
-      const files = await requestJSON("/api/files");
-      renderFiles(Array.isArray(files) ? files : []);
-      setMsg(listMsg, "");
+      await loadFolders();
+      await fetchPage("");
     } catch (err) {
       setMsg(listMsg, `加载失败: ${err.message}`);
     }
+    await loadTrash();
+    await loadStats();
+  }
+
+  function renderTrash(items) {
+    trashBody.innerHTML = "";
+    trashEmptyBtn.disabled = !items.length;
+    if (!items.length) {
+      const tr = document.createElement("tr");
+      tr.innerHTML = `<td colspan="6">回收站为空</td>`;
+      trashBody.appendChild(tr);
+      return;
+    }
+
+    items.forEach((item) => {
+      const tr = document.createElement("tr");
+      const cells = [
+        item.evicted ? `${item.name}（自动淘汰）` : item.name,
+        folderLabel(item.folder),
+        fmtDate(item.deleted_at),
+        sizeText(item.size_bytes),
+        fmtDate(item.purge_at),
+      ];
+      cells.forEach((text) => {
+        const td = document.createElement("td");
+        td.textContent = text;
+        tr.appendChild(td);
+      });
+
+      const actionsCell = document.createElement("td");
+      const actions = document.createElement("div");
+      actions.className = "actions";
+      const url = `/api/trash/${encodeURIComponent(item.id)}`;
+      actions.appendChild(buildActionButton("恢复", "", async () => {
+        try {
+          await requestJSON(`${url}/restore`, { method: "POST" });
+          await loadFiles();
+          setMsg(trashMsg, `已恢复到 ${folderLabel(item.folder)}`);
+        } catch (err) {
+          const hint = err.code === "NAME_CONFLICT" ? "原目录中已有同名文件，请先改名或删除该文件" : err.message;
+          setMsg(trashMsg, `恢复失败: ${hint}`);
+        }
+      }));
+      actions.appendChild(buildActionButton("彻底删除", "danger", async () => {
+        if (!window.confirm(`确认彻底删除 ${item.name} ? 删除后无法恢复`)) return;
+        try {
+          await requestJSON(url, { method: "DELETE" });
+          await loadTrash();
+          setMsg(trashMsg, "已彻底删除");
+        } catch (err) {
+          setMsg(trashMsg, `删除失败: ${err.message}`);
+        }
+      }));
+      actionsCell.appendChild(actions);
+      tr.appendChild(actionsCell);
+      trashBody.appendChild(tr);
+    });
+  }
+
+  async function loadTrash() {
+    try {
+      const items = await requestJSON("/api/trash");
+      renderTrash(Array.isArray(items) ? items : []);
+      setMsg(trashMsg, "");
+    } catch (err) {
+      setMsg(trashMsg, `加载回收站失败: ${err.message}`);
+    }
+  }
+
+  // EVICT_REASONS 为淘汰原因的说明。
+  const EVICT_REASONS = {
+    max_files: "文件数已满",
+    max_total_bytes: "空间已满",
+  };
+
+  function renderStats(stats) {
+    setMsg(statsUsage, `文件 ${stats.files}/${stats.max_files}，已用 ${sizeText(stats.used_bytes)}/${sizeText(stats.max_total_bytes)}`);
+    const items = stats.recent_evictions || [];
+    evictionsBody.innerHTML = "";
+    if (!items.length) {
+      const tr = document.createElement("tr");
+      tr.innerHTML = `<td colspan="7">暂无自动淘汰的文件</td>`;
+      evictionsBody.appendChild(tr);
+      return;
+    }
+
+    items.forEach((item) => {
+      const tr = document.createElement("tr");
+      const cells = [
+        item.name,
+        folderLabel(item.folder),
+        sizeText(item.size_bytes),
+        EVICT_REASONS[item.reason] || item.reason,
+        fmtDate(item.evicted_at),
+        item.trigger || "-",
+        item.trashed ? "可恢复" : "已删除",
+      ];
+      cells.forEach((text) => {
+        const td = document.createElement("td");
+        td.textContent = text;
+        tr.appendChild(td);
+      });
+      evictionsBody.appendChild(tr);
+    });
+  }
+
+  async function loadStats() {
+    try {
+      renderStats(await requestJSON("/api/stats"));
+      setMsg(statsMsg, "");
+    } catch (err) {
+      setMsg(statsMsg, `加载统计失败: ${err.message}`);
+    }
   }
 
   function renderQR(resp) {
@@ -185,21 +527,119 @@
     qrLink.textContent = new URL(resp.pageUrl, window.location.origin).toString();
   }
 
+  // confirmUpload 上传前预演：不能上传时抛出原因；会淘汰已有文件时请用户确认，返回是否继续上传。
+  async function confirmUpload(file, folder, onConflict) {
+    const check = await requestJSON("/api/files/check", jsonInit("POST", { folder, name: file.name, size_bytes: file.size, on_conflict: onConflict }));
+    if (check.name_conflict && check.on_conflict === "reject") throw new Error("目录中已有同名文件");
+    if (check.too_large) throw new Error(`文件超过单文件上限 ${sizeText(check.max_file_bytes)}`);
+    if (!check.fits) throw new Error("空间不足，淘汰可淘汰的文件后仍放不下");
+    if (!check.evictions.length) return true;
+    const names = check.evictions.map((f) => `${f.folder ? `${f.folder}/` : ""}${f.name}（${sizeText(f.size_bytes)}）`);
+    return window.confirm(`上传后将自动淘汰以下 ${names.length} 个文件：\n${names.join("\n")}\n确认继续上传?`);
+  }
+
   uploadForm.addEventListener("submit", async (e) => {
     e.preventDefault();
     setMsg(uploadMsg, "上传中...");
     try {
       const fd = new FormData(uploadForm);
-      await requestJSON("/api/files", { method: "POST", body: fd });
+      if (!(await confirmUpload(fd.get("file"), fd.get("folder") || "", fd.get("on_conflict") || ""))) {
+        setMsg(uploadMsg, "已取消上传");
+        return;
+      }
+      const sum = await sha256Hex(fd.get("file"));
+      const meta = await requestJSON("/api/files", { method: "POST", body: fd, headers: sum ? { "X-Content-SHA256": sum } : {} });
       uploadForm.reset();
       await loadFiles();
-      setMsg(uploadMsg, "上传成功");
+      setMsg(uploadMsg, meta.version > 1 ? `已覆盖 ${meta.name}（第 ${meta.version} 版）` : `上传成功: ${meta.name}`);
     } catch (err) {
       setMsg(uploadMsg, `上传失败: ${err.message}`);
     }
   });
 
   refreshBtn.addEventListener("click", loadFiles);
+  trashRefreshBtn.addEventListener("click", loadTrash);
+  statsRefreshBtn.addEventListener("click", loadStats);
+
+  trashEmptyBtn.addEventListener("click", async () => {
+    if (!window.confirm("确认清空回收站? 清空后无法恢复")) return;
+    try {
+      const data = await requestJSON("/api/trash", { method: "DELETE" });
+      await loadTrash();
+      setMsg(trashMsg, `已清除 ${data.purged_files} 个文件`);
+    } catch (err) {
+      setMsg(trashMsg, `清空失败: ${err.message}`);
+    }
+  });
+
+  nameFilter.addEventListener("change", loadFiles);
+  tagFilter.addEventListener("change", loadFiles);
+  sortSelect.addEventListener("change", loadFiles);
+
+  moreBtn.addEventListener("click", async () => {
+    if (!nextCursor) return;
+    moreBtn.disabled = true;
+    try {
+      await fetchPage(nextCursor);
+    } catch (err) {
+      setMsg(listMsg, `加载失败: ${err.message}`);
+    } finally {
+      moreBtn.disabled = false;
+    }
+  });
+
+  folderSelect.addEventListener("change", () => {
+    currentFolder = folderSelect.value;
+    loadFiles();
+  });
+
+  newFolderBtn.addEventListener("click", async () => {
+    const base = currentFolder === ALL_FOLDERS || currentFolder === "" ? "" : `${currentFolder}/`;
+    const path = (window.prompt("输入目录路径（用 / 分隔多级目录）", base) || "").trim();
+    if (!path || path === base) return;
+    try {
+      const data = await requestJSON("/api/folders", jsonInit("POST", { path }));
+      currentFolder = data.path;
+      await loadFiles();
+      setMsg(listMsg, "目录已创建");
+    } catch (err) {
+      setMsg(listMsg, `创建目录失败: ${err.message}`);
+    }
+  });
+
+  renameFolderBtn.addEventListener("click", async () => {
+    const from = currentFolder;
+    const next = (window.prompt("输入新的目录路径", from) || "").trim();
+    if (!next || next === from) return;
+    try {
+      const data = await requestJSON("/api/folders", jsonInit("PATCH", { path: from, new_path: next }));
+      currentFolder = data.path;
+      await loadFiles();
+      setMsg(listMsg, "目录已重命名");
+    } catch (err) {
+      setMsg(listMsg, `重命名目录失败: ${err.message}`);
+    }
+  });
+
+  deleteFolderBtn.addEventListener("click", async () => {
+    const path = currentFolder;
+    if (!window.confirm(`确认删除目录 ${folderLabel(path)} ?`)) return;
+    const url = `/api/folders?path=${encodeURIComponent(path)}`;
+    try {
+      try {
+        await requestJSON(url, { method: "DELETE" });
+      } catch (err) {
+        if (err.code !== "FOLDER_NOT_EMPTY") throw err;
+        if (!window.confirm("目录非空，是否连同其中的文件和子目录一起删除?")) return;
+        await requestJSON(`${url}&recursive=true`, { method: "DELETE" });
+      }
+      currentFolder = ALL_FOLDERS;
+      await loadFiles();
+      setMsg(listMsg, "目录已删除");
+    } catch (err) {
+      setMsg(listMsg, `删除目录失败: ${err.message}`);
+    }
+  });
 
   bridgeUploadBtn.addEventListener("click", async () => {
     setMsg(qrMsg, "生成中...");
@@ -11,6 +11,36 @@
     msg.textContent = v || "";
   }
 
+  // sha256Hex 计算文件内容摘要，随上传发送供服务端校验；非安全上下文（如局域网 http）没有 crypto.subtle，此时返回空串、不做校验。
+  async function sha256Hex(file) {
+    if (!file || !window.crypto || !window.crypto.subtle) return "";
+    const digest = await window.crypto.subtle.digest("SHA-256", await file.arrayBuffer());
+    return Array.from(new Uint8Array(digest), (b) => b.toString(16).padStart(2, "0")).join("");
+  }
+
+  // checkUpload 上传前预演：返回不能上传的原因，空串表示可以上传；会淘汰已有文件时先请用户确认。
+  // 预演请求失败时不阻止上传，由上传本身报告错误。
+  async function checkUpload(file) {
+    let check;
+    try {
+      const res = await fetch(`/api/bridge/${encodeURIComponent(token)}/upload-check`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ name: file.name, size_bytes: file.size }),
+      });
+      if (!res.ok) return "";
+      check = await res.json();
+    } catch (_) {
+      return "";
+    }
+    if (check.name_conflict && check.on_conflict === "reject") return "已有同名文件，请改名后再上传";
+    if (check.too_large) return `文件超过单文件上限 ${Math.floor(check.max_file_bytes / 1024 / 1024)}MB`;
+    if (!check.fits) return "空间不足，无法上传该文件";
+    if (!check.evictions.length) return "";
+    const names = check.evictions.map((f) => f.name).join("\n");
+    return window.confirm(`上传后将自动淘汰以下 ${check.evictions.length} 个文件：\n${names}\n确认继续上传?`) ? "" : "已取消上传";
+  }
+
   const token = bridgeTokenFromPath();
   if (!token) {
     setMsg("链接不合法");
@@ -20,10 +50,19 @@
 
   form.addEventListener("submit", async (e) => {
     e.preventDefault();
-    setMsg("上传中...");
     const fd = new FormData(form);
+    setMsg("检查中...");
+    // 上传会消费二维码，预演发现不能上传时直接提示，二维码仍可继续使用。
+    const problem = await checkUpload(fd.get("file"));
+    if (problem) {
+      setMsg(problem);
+      return;
+    }
+    setMsg("上传中...");
+    const sum = await sha256Hex(fd.get("file"));
     const res = await fetch(`/api/bridge/${encodeURIComponent(token)}/upload`, {
       method: "POST",
+      headers: sum ? { "X-Content-SHA256": sum } : {},
       body: fd,
     });
     const text = await res.text();
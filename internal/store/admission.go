@@ -0,0 +1,60 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Admission 为 CheckAdmission 的结果：按当前状态预演上传一个文件会发生什么。
+type Admission struct {
+	// NameConflict 为 true 表示目录中已有同名文件，上传会返回 ErrNameConflict。
+	NameConflict bool
+	// Fits 为 false 表示即使淘汰所有可淘汰的文件也放不下（或超过总量上限），上传会失败。
+	Fits bool
+	// Evictions 为上传时将按淘汰策略淘汰的文件，按淘汰顺序排列。
+	Evictions []FileMeta
+}
+
+// CheckAdmission 预演在 folder 下上传 name（size 字节）：是否重名、能否放下、会淘汰哪些文件。
+// 淘汰过程与 Add 相同（连同其他上传的预留），但不改变存储。
+// 结果只是估计：去重、压缩后实际占用可能更小，其他请求也可能在上传前改变存储。
+func (s *engine) CheckAdmission(folder, name string, size int64) (Admission, error) {
+	name, err := s.CleanName(name)
+	if err != nil {
+		return Admission{}, err
+	}
+	folder, err = CleanFolder(folder)
+	if err != nil {
+		return Admission{}, err
+	}
+	if size < 0 {
+		return Admission{}, fmt.Errorf("%w: size must be >= 0", ErrInvalidInput)
+	}
+
+	// 读路径的 touch 需要在持有写锁时应用，预演的淘汰顺序才与实际一致。
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.folderExistsLocked(folder) {
+		return Admission{}, ErrFolderNotFound
+	}
+	var out Admission
+	now := time.Now()
+	if id, ok := s.byName[nameKey{folder: folder, name: name}]; ok && !s.byID[id].meta.Expired(now) {
+		out.NameConflict = true
+	}
+	if size > s.maxTotalBytes {
+		if !s.compresses() {
+			return out, nil
+		}
+		size = s.maxTotalBytes
+	}
+
+	// 与 Add 共用 runEvictionLocked，只是不实际淘汰。
+	victims, _, fits := s.runEvictionLocked(size, "", now, true)
+	out.Fits = fits
+	for _, en := range victims {
+		out.Evictions = append(out.Evictions, en.meta)
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCheckAdmissionMatchesAdd(t *testing.T) {
+	for _, policy := range []string{EvictionFIFO, EvictionLRU, EvictionLFU, EvictionLargest} {
+		t.Run(policy, func(t *testing.T) {
+			s, err := NewInMemoryStore(NewParams{MaxFiles: 5, MaxTotalBytes: 100, MaxPinnedBytes: 50, EvictionPolicy: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for i, size := range []int{10, 20, 15, 10} {
+				meta, err := s.Add(AddParams{Name: string(rune('a' + i)), Bytes: bytes.Repeat([]byte{byte('a' + i)}, size)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, meta.ID)
+			}
+			// 与 a 共享内容的副本：淘汰 a 时不释放空间。
+			if _, err := s.Copy(ids[0], "a2"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SetPinned(ids[1], true); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{ids[0], ids[0], ids[2]} {
+				if _, err := s.Get(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			before := s.Stats()
+			adm, err := s.CheckAdmission("", "new", 60)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if adm.NameConflict || !adm.Fits || len(adm.Evictions) == 0 {
+				t.Fatalf("unexpected admission: %#v", adm)
+			}
+			if after := s.Stats(); after != before || len(s.RecentEvictions()) != 0 {
+				t.Fatalf("dry run changed the store: %#v -> %#v", before, after)
+			}
+
+			if _, err := s.Add(AddParams{Name: "new", Bytes: bytes.Repeat([]byte("n"), 60)}); err != nil {
+				t.Fatal(err)
+			}
+			got := s.RecentEvictions()
+			if len(got) != len(adm.Evictions) {
+				t.Fatalf("planned %d evictions, Add evicted %d", len(adm.Evictions), len(got))
+			}
+			for i, planned := range adm.Evictions {
+				if actual := got[len(got)-1-i]; actual.ID != planned.ID {
+					t.Fatalf("eviction %d: planned %q, Add evicted %q", i, planned.Name, actual.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAdmissionReportsConflictAndNoRoom(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100, MaxPinnedBytes: 50})
+	a, _ := s.Add(AddParams{Name: "a", Bytes: make([]byte, 40)})
+	if _, err := s.SetPinned(a.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	s.Add(AddParams{Name: "b", Bytes: []byte("b")})
+
+	adm, err := s.CheckAdmission("", "b", 10)
+	if err != nil || !adm.NameConflict || !adm.Fits || len(adm.Evictions) != 0 {
+		t.Fatalf("unexpected admission: %#v err=%v", adm, err)
+	}
+	// 置顶文件不会被淘汰，淘汰 b 之后仍放不下。
+	adm, err = s.CheckAdmission("", "c", 70)
+	if err != nil || adm.Fits || len(adm.Evictions) != 1 || adm.Evictions[0].Name != "b" {
+		t.Fatalf("unexpected admission: %#v err=%v", adm, err)
+	}
+	if adm, err := s.CheckAdmission("", "c", 101); err != nil || adm.Fits || len(adm.Evictions) != 0 {
+		t.Fatalf("expected too large to never fit: %#v err=%v", adm, err)
+	}
+	if _, err := s.CheckAdmission("nope", "c", 1); err != ErrFolderNotFound {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+}
+
+func TestEvictionDryRunMatchesRealEviction(t *testing.T) {
+	for _, policy := range []string{EvictionFIFO, EvictionLRU, EvictionLFU, EvictionLargest} {
+		for _, incoming := range []int64{30, 70, 95} {
+			s, err := NewInMemoryStore(NewParams{MaxFiles: 8, MaxTotalBytes: 100, MaxPinnedBytes: 50, EvictionPolicy: policy, TrashTTL: time.Hour, MaxTrashBytes: 100, TrashEvicted: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			past := time.Now().Add(-time.Hour)
+			var ids []string
+			for i, size := range []int{10, 20, 15, 10, 5} {
+				p := AddParams{Name: string(rune('a' + i)), Bytes: bytes.Repeat([]byte{byte('a' + i)}, size)}
+				if i == 3 {
+					// 已过期但尚未清理。
+					p.Now, p.ExpiresAt = past, past.Add(time.Minute)
+				}
+				meta, err := s.Add(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, meta.ID)
+			}
+			if _, err := s.Copy(ids[0], "a2"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.SetPinned(ids[1], true); err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{ids[4], ids[0], ids[4]} {
+				if _, err := s.Get(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			now := time.Now()
+			s.mu.Lock()
+			planned, _, planFits := s.runEvictionLocked(incoming, "", now, true)
+			evicted, _, fits := s.runEvictionLocked(incoming, "x", now, false)
+			s.mu.Unlock()
+
+			if planFits != fits || len(planned) != len(evicted) {
+				t.Fatalf("%s/%d: planned %d (fits=%v), evicted %d (fits=%v)", policy, incoming, len(planned), planFits, len(evicted), fits)
+			}
+			for i := range planned {
+				if planned[i] != evicted[i] {
+					t.Fatalf("%s/%d: eviction %d: planned %q, evicted %q", policy, incoming, i, planned[i].meta.Name, evicted[i].meta.Name)
+				}
+			}
+		}
+	}
+}
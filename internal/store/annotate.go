@@ -0,0 +1,88 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 标签与备注只是附加在文件上的说明，不参与重名判断；改名、移动、转码与恢复版本都不会改变它们。
+const (
+	MaxTags      = 32
+	MaxTagRunes  = 64
+	MaxNoteRunes = 4096
+)
+
+// NormalizeTags 去掉首尾空白与空标签，去重后按字典序排列；
+// 标签数量、长度超限或含有逗号时返回 ErrInvalidInput。
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if utf8.RuneCountInString(t) > MaxTagRunes || strings.Contains(t, ",") {
+			return nil, fmt.Errorf("%w: bad tag %q", ErrInvalidInput, t)
+		}
+		if _, dup := seen[t]; dup {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	if len(out) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", ErrInvalidInput, MaxTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// HasTag 报告文件是否带有标签 tag（区分大小写）。
+func (m FileMeta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTags 替换文件的标签（先经 NormalizeTags 规范化）。
+func (s *engine) SetTags(id string, tags []string) (FileMeta, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	return s.annotate(id, func(m *FileMeta) { m.Tags = tags })
+}
+
+// SetNote 替换文件的备注（去掉首尾空白，空串表示清除）。
+func (s *engine) SetNote(id string, note string) (FileMeta, error) {
+	note = strings.TrimSpace(note)
+	if !utf8.ValidString(note) || utf8.RuneCountInString(note) > MaxNoteRunes {
+		return FileMeta{}, fmt.Errorf("%w: bad note", ErrInvalidInput)
+	}
+	return s.annotate(id, func(m *FileMeta) { m.Note = note })
+}
+
+// annotate 修改标签/备注。返回的 FileMeta 与 store 共享 Tags，调用方不得原地修改；
+// store 自身也只整体替换 Tags，不会修改已返回的切片。
+func (s *engine) annotate(id string, set func(m *FileMeta)) (FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	en, ok := s.liveLocked(id)
+	if !ok {
+		return FileMeta{}, ErrNotFound
+	}
+	set(&en.meta)
+	s.publishLocked(en)
+	s.logLocked(journalRecord{Op: opAnnotate, Meta: en.meta})
+	return en.meta, s.commitLocked()
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" needs GBK", "customer X", "", "needs GBK", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "customer X", "needs GBK"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	tooMany := make([]string, MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("x", i+1)
+	}
+	for _, bad := range [][]string{{"a,b"}, {strings.Repeat("字", MaxTagRunes+1)}, tooMany} {
+		if _, err := NormalizeTags(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("expected ErrInvalidInput for %d tags, got %v", len(bad), err)
+		}
+	}
+}
+
+func TestAnnotationsSurviveRenameMoveAndReplace(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("v1")})
+	if _, err := s.SetTags(a.ID, []string{"customer X", "needs GBK"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetNote(a.ID, "  from mail  "); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.CreateFolder("x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rename(a.ID, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Move(a.ID, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("v2")}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := s.RestoreVersion(a.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta.Tags, []string{"customer X", "needs GBK"}) || meta.Note != "from mail" {
+		t.Fatalf("annotations lost: %#v", meta)
+	}
+	if !meta.HasTag("needs GBK") || meta.HasTag("needs gbk") {
+		t.Fatal("HasTag should match exactly")
+	}
+
+	meta, err = s.SetTags(a.ID, nil)
+	if err != nil || meta.Tags != nil {
+		t.Fatalf("expected tags cleared: %#v err=%v", meta, err)
+	}
+	if _, err := s.SetNote("missing", "x"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.SetNote(a.ID, strings.Repeat("n", MaxNoteRunes+1)); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestAnnotationsSurvivePersistence(t *testing.T) {
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100}
+	mutate := func(t *testing.T, s FileStore) string {
+		t.Helper()
+		a, err := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SetTags(a.ID, []string{"t2", "t1"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SetNote(a.ID, "备注"); err != nil {
+			t.Fatal(err)
+		}
+		return a.ID
+	}
+	check := func(t *testing.T, s FileStore, id string) {
+		t.Helper()
+		meta, err := s.GetMeta(id)
+		if err != nil || !reflect.DeepEqual(meta.Tags, []string{"t1", "t2"}) || meta.Note != "备注" {
+			t.Fatalf("unexpected meta after reload: %#v err=%v", meta, err)
+		}
+	}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		id := mutate(t, s)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		check(t, reopened, id)
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		dir := t.TempDir()
+		s, _ := openJournalStore(t, dir, params)
+		id := mutate(t, s)
+		restored, j := openJournalStore(t, dir, params)
+		defer j.Close()
+		check(t, restored, id)
+	})
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newBenchStore 返回已装满 n 个文件的 store：之后每次 Add 都会触发淘汰。
+func newBenchStore(b *testing.B, policy string, n int) (*InMemoryStore, []string) {
+	b.Helper()
+	s, err := NewInMemoryStore(NewParams{MaxFiles: n, MaxTotalBytes: int64(n) * 8 << 10, EvictionPolicy: policy})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ids := make([]string, n)
+	for i := range ids {
+		m, err := s.Add(AddParams{Name: "f" + strconv.Itoa(i), Bytes: benchContent(i)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids[i] = m.ID
+	}
+	return s, ids
+}
+
+func benchContent(i int) []byte {
+	buf := make([]byte, 4<<10)
+	copy(buf, strconv.Itoa(i))
+	return buf
+}
+
+// BenchmarkMixedLoad 模拟大量并发下载与少量上传：每 writeEvery 次操作中有一次上传（装满后伴随淘汰），
+// 其余为 GetMeta + HasName + 打开并读取内容。读取的目标取自最近上传的一半文件，基本不会已被淘汰。
+// 只用于比较不同实现的开销；分片读索引在单核上比单把读写锁慢，多核下的收益须在目标机器上实测。
+func BenchmarkMixedLoad(b *testing.B) {
+	for _, policy := range []string{EvictionFIFO, EvictionLRU} {
+		for _, writeEvery := range []int{100, 10} {
+			b.Run(fmt.Sprintf("%s/writes=1in%d", policy, writeEvery), func(b *testing.B) {
+				s, ids := newBenchStore(b, policy, 1000)
+				recent := make([]atomic.Pointer[string], len(ids)/2)
+				for i := range recent {
+					recent[i].Store(&ids[len(ids)-len(recent)+i])
+				}
+				var seq atomic.Int64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					buf := make([]byte, 4<<10)
+					for pb.Next() {
+						n := int(seq.Add(1))
+						if n%writeEvery == 0 {
+							m, err := s.Add(AddParams{Name: "w" + strconv.Itoa(n), Bytes: benchContent(n)})
+							if err != nil {
+								b.Error(err)
+								return
+							}
+							recent[(n/writeEvery)%len(recent)].Store(&m.ID)
+							continue
+						}
+						id := *recent[n%len(recent)].Load()
+						meta, err := s.GetMeta(id)
+						if err != nil {
+							continue
+						}
+						s.HasName(meta.Folder, meta.Name)
+						_, rc, err := s.Open(id)
+						if err != nil {
+							continue
+						}
+						_, _ = io.ReadFull(rc, buf)
+						_ = rc.Close()
+					}
+				})
+			})
+		}
+	}
+}
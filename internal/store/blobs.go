@@ -0,0 +1,66 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+var errBlobNotFound = errors.New("blob not found")
+
+// blobBackend 只负责按 key 存取文件内容；元数据、索引与淘汰由 engine 统一维护。
+// key 由 engine 生成且每次写入都不同，后端无需处理覆盖写。
+type blobBackend interface {
+	put(key string, data []byte) error
+	get(key string) ([]byte, error)
+	open(key string, size int64) (io.ReadSeekCloser, error)
+	remove(key string) error
+}
+
+type memBlobs struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+func newMemBlobs() *memBlobs {
+	return &memBlobs{data: make(map[string][]byte)}
+}
+
+func (m *memBlobs) put(key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = data
+	return nil
+}
+
+func (m *memBlobs) get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	b, ok := m.data[key]
+	if !ok {
+		return nil, errBlobNotFound
+	}
+	return b, nil
+}
+
+func (m *memBlobs) open(key string, _ int64) (io.ReadSeekCloser, error) {
+	b, err := m.get(key)
+	if err != nil {
+		return nil, err
+	}
+	return nopReadSeekCloser{bytes.NewReader(b)}, nil
+}
+
+func (m *memBlobs) remove(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error { return nil }
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 静态压缩方式（NewParams.Compression）。
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+const (
+	// gzipKeySuffix 标记压缩存放的 blob：解压方式由 key 决定，无需额外元数据，
+	// 切换配置后已有内容仍按原方式读取。
+	gzipKeySuffix = ".gz"
+	// minCompressBytes 以下的内容不尝试压缩。
+	minCompressBytes = 512
+)
+
+func validCompression(c string) bool {
+	return c == "" || c == CompressionNone || c == CompressionGzip
+}
+
+func (s *engine) compresses() bool {
+	return s.compression == CompressionGzip
+}
+
+func isCompressedKey(key string) bool {
+	return strings.HasSuffix(key, gzipKeySuffix)
+}
+
+// encodeBlob 为新内容生成 blob key 并返回实际要写入的字节。
+// 只有压缩能省下至少 1/8 空间时才压缩存放（已压缩的图片、压缩包等原样保存）。
+func (s *engine) encodeBlob(data []byte) (key string, stored []byte) {
+	key = newID()
+	if !s.compresses() || len(data) < minCompressBytes {
+		return key, data
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	_, _ = zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return key, data
+	}
+	if buf.Len() > len(data)-len(data)/8 {
+		return key, data
+	}
+	return key + gzipKeySuffix, buf.Bytes()
+}
+
+// readBlob 读取并（按需）解压完整内容；size 为解压后的大小，用于预分配。
+func (s *engine) readBlob(key string, size int64) ([]byte, error) {
+	b, err := s.blobs.get(key)
+	if err != nil || !isCompressedKey(key) {
+		return b, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", key, err)
+	}
+	out := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := io.Copy(out, zr); err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", key, err)
+	}
+	return out.Bytes(), nil
+}
+
+// openBlob 打开内容流；压缩存放的内容边读边解压。size 为解压后的大小。
+func (s *engine) openBlob(b blobRef, size int64) (io.ReadSeekCloser, error) {
+	if !isCompressedKey(b.key) {
+		return s.blobs.open(b.key, size)
+	}
+	return newGzipReadSeeker(func() (io.ReadCloser, error) { return s.blobs.open(b.key, b.size) }, size)
+}
+
+// gzipReadSeeker 以流式解压实现 io.ReadSeekCloser，内存占用与文件大小无关：
+// 解压后的总大小已知，Seek 本身只记录位置；读取时向前跳过解压结果，向后则从头重新解压。
+// http.ServeContent 的典型用法（Seek 到末尾取大小、回到开头或 Range 起点后顺序读）只需解压一遍。
+type gzipReadSeeker struct {
+	open func() (io.ReadCloser, error)
+	size int64
+
+	src io.ReadCloser
+	zr  *gzip.Reader
+	pos int64 // 已解压输出的位置
+	off int64 // 调用方请求的位置
+}
+
+func newGzipReadSeeker(open func() (io.ReadCloser, error), size int64) (*gzipReadSeeker, error) {
+	g := &gzipReadSeeker{open: open, size: size}
+	if err := g.reset(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gzipReadSeeker) reset() error {
+	if g.src != nil {
+		_ = g.src.Close()
+		g.src = nil
+	}
+	src, err := g.open()
+	if err != nil {
+		return err
+	}
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		_ = src.Close()
+		return err
+	}
+	g.src, g.zr, g.pos = src, zr, 0
+	return nil
+}
+
+func (g *gzipReadSeeker) Read(p []byte) (int, error) {
+	if g.off >= g.size {
+		return 0, io.EOF
+	}
+	if g.off < g.pos {
+		if err := g.reset(); err != nil {
+			return 0, err
+		}
+	}
+	if g.off > g.pos {
+		n, err := io.CopyN(io.Discard, g.zr, g.off-g.pos)
+		g.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := g.zr.Read(p)
+	g.pos += int64(n)
+	g.off = g.pos
+	return n, err
+}
+
+func (g *gzipReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = g.off + offset
+	case io.SeekEnd:
+		abs = g.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	g.off = abs
+	return abs, nil
+}
+
+func (g *gzipReadSeeker) Close() error {
+	if g.src == nil {
+		return nil
+	}
+	err := g.src.Close()
+	g.src = nil
+	return err
+}
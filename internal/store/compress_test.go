@@ -0,0 +1,172 @@
+package store
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func textContent(lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&buf, "2024-01-01 00:00:%02d INFO request handled id=%d status=200\n", i%60, i)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressionAccountsStoredSize(t *testing.T) {
+	content := textContent(2000)
+	// 原始内容超过总量上限，压缩后放得下。
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: int64(len(content)) / 2, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := s.Add(AddParams{Name: "app.log", Bytes: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.SizeBytes != int64(len(content)) {
+		t.Fatalf("SizeBytes should be the uncompressed size, got %d", meta.SizeBytes)
+	}
+
+	st := s.Stats()
+	if st.LogicalBytes != int64(len(content)) || st.PhysicalBytes >= st.LogicalBytes/4 || st.CompressionRatio < 4 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+
+	f, err := s.Get(meta.ID)
+	if err != nil || !bytes.Equal(f.Bytes, content) {
+		t.Fatalf("Get returned different content, err=%v", err)
+	}
+	_, r, err := s.Open(meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("Open returned different content, err=%v", err)
+	}
+}
+
+func TestIncompressibleContentStoredRaw(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 4096)
+	_, _ = rand.Read(data)
+	if _, err := s.Add(AddParams{Name: "a.bin", Bytes: data}); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.PhysicalBytes != 4096 || st.CompressionRatio != 1 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+}
+
+func TestCompressedOpenSeeks(t *testing.T) {
+	content := textContent(500)
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := s.Add(AddParams{Name: "a.log", Bytes: content})
+	_, r, err := s.Open(meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	readAt := func(whence int, offset int64, n int) []byte {
+		t.Helper()
+		pos, err := r.Seek(offset, whence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, n)
+		got, err := io.ReadFull(r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("read at %d: %v", pos, err)
+		}
+		return buf[:got]
+	}
+
+	if end, _ := r.Seek(0, io.SeekEnd); end != int64(len(content)) {
+		t.Fatalf("SeekEnd: got %d want %d", end, len(content))
+	}
+	if got := readAt(io.SeekStart, 1000, 50); !bytes.Equal(got, content[1000:1050]) {
+		t.Fatalf("forward seek mismatch: %q", got)
+	}
+	if got := readAt(io.SeekStart, 10, 20); !bytes.Equal(got, content[10:30]) {
+		t.Fatalf("backward seek mismatch: %q", got)
+	}
+	if got := readAt(io.SeekEnd, -5, 10); !bytes.Equal(got, content[len(content)-5:]) {
+		t.Fatalf("tail read mismatch: %q", got)
+	}
+}
+
+func TestCompressedContentSurvivesPersistence(t *testing.T) {
+	content := textContent(1000)
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20, Compression: CompressionGzip}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		meta, _ := s.Add(AddParams{Name: "a.log", Bytes: content})
+		before := s.Stats()
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		// 关闭压缩后重新打开：已压缩的内容仍可读取，用量不变。
+		reopened, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		if after := reopened.Stats(); after != before {
+			t.Fatalf("stats changed across reopen: %#v -> %#v", before, after)
+		}
+		f, err := reopened.Get(meta.ID)
+		if err != nil || !bytes.Equal(f.Bytes, content) {
+			t.Fatalf("content changed across reopen, err=%v", err)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta, _ := s.Add(AddParams{Name: "a.log", Bytes: content})
+		var buf bytes.Buffer
+		if err := s.WriteSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := restored.LoadSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if restored.Stats() != s.Stats() {
+			t.Fatalf("stats changed across snapshot: %#v -> %#v", s.Stats(), restored.Stats())
+		}
+		f, err := restored.Get(meta.ID)
+		if err != nil || !bytes.Equal(f.Bytes, content) {
+			t.Fatalf("content changed across snapshot, err=%v", err)
+		}
+	})
+}
+
+func TestUnknownCompressionRejected(t *testing.T) {
+	if _, err := NewInMemoryStore(NewParams{MaxFiles: 1, MaxTotalBytes: 1, Compression: "zstd"}); err == nil {
+		t.Fatal("expected error for unknown compression")
+	}
+}
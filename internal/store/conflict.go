@@ -0,0 +1,141 @@
+package store
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// 重名处理策略（AddParams.OnConflict、RenameParams.OnConflict），空串等同 ConflictReject。
+// 可用名称在 engine 写锁内确定并占用，并发上传同名文件时不会互相覆盖或得到相同的名称。
+const (
+	// ConflictReject 返回 ErrNameConflict。
+	ConflictReject = "reject"
+	// ConflictSuffix 改用第一个可用的“名称 (n).扩展名”。
+	ConflictSuffix = "suffix"
+	// ConflictOverwrite 把内容写入同名文件，作为它的新版本（原内容转为历史版本）。
+	ConflictOverwrite = "overwrite"
+	// ConflictKeepBoth 把已有的同名文件改名为第一个可用的“名称 (n).扩展名”，新文件使用原名。
+	ConflictKeepBoth = "keep_both"
+)
+
+// CheckConflictPolicy 检查重名处理策略是否合法。
+func CheckConflictPolicy(policy string) error {
+	switch policy {
+	case "", ConflictReject, ConflictSuffix, ConflictOverwrite, ConflictKeepBoth:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown name conflict policy %q", ErrInvalidInput, policy)
+}
+
+// suffixedName 在扩展名之前加上序号：report.csv → report (1).csv；以点开头的名称（.env）整体视为主名。
+func suffixedName(name string, n int) string {
+	ext := path.Ext(name)
+	if ext == name || strings.TrimSuffix(name, ext) == "" {
+		ext = ""
+	}
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+}
+
+// RenameParams 为 RenameWith 的参数。
+type RenameParams struct {
+	ID   string
+	Name string
+	// IfMatch 非空时，当前内容须满足该条件才改名（否则返回 ErrPreconditionFailed）。
+	IfMatch IfMatch
+	// OnConflict 为目录中已有同名文件时的处理策略；ConflictOverwrite 时同名文件以本文件内容写入新版本，
+	// 本文件随后被删除（启用回收站时移入回收站），返回的是同名文件。
+	OnConflict string
+}
+
+// liveNameLocked 返回 folder 下占用 name 的未过期文件，没有时返回 nil。
+func (s *engine) liveNameLocked(folder, name string) *entry {
+	id, ok := s.byName[nameKey{folder: folder, name: name}]
+	if !ok {
+		return nil
+	}
+	if en := s.byID[id]; !en.meta.Expired(time.Now()) {
+		return en
+	}
+	return nil
+}
+
+// claimNameLocked 按 policy 为 folder 下的 name 腾出名称，返回最终使用的名称。
+// policy 为 ConflictOverwrite 且名称被占用时不改名，返回占用该名称的文件，由调用方写入新版本。
+// self 为正在改名的文件（新增时为 nil），它自己的名称视为可用。
+func (s *engine) claimNameLocked(folder, name, policy string, self *entry) (string, *entry, []string, error) {
+	removed, err := s.takeNameLocked(folder, name)
+	if err != ErrNameConflict {
+		return name, nil, removed, err
+	}
+	holder := s.byID[s.byName[nameKey{folder: folder, name: name}]]
+	switch policy {
+	case ConflictSuffix:
+		free, removed, err := s.freeNameLocked(folder, name, self)
+		return free, nil, removed, err
+	case ConflictKeepBoth:
+		moved, removed, err := s.freeNameLocked(folder, name, holder)
+		if err != nil {
+			return "", nil, removed, err
+		}
+		s.setNameLocked(holder, moved)
+		return name, nil, removed, nil
+	case ConflictOverwrite:
+		return name, holder, nil, nil
+	default:
+		return "", nil, nil, ErrNameConflict
+	}
+}
+
+// freeNameLocked 返回 folder 下第一个可用的“name (n)”（被已过期文件占用的名称先清理后使用）；
+// self 当前的名称视为可用。
+func (s *engine) freeNameLocked(folder, name string, self *entry) (string, []string, error) {
+	for n := 1; ; n++ {
+		candidate := suffixedName(name, n)
+		// 加上序号后仍须符合文件名规则（长度上限）。
+		if err := s.names.check(candidate); err != nil {
+			return "", nil, err
+		}
+		if self != nil && self.meta.Folder == folder && self.meta.Name == candidate {
+			return candidate, nil, nil
+		}
+		removed, err := s.takeNameLocked(folder, candidate)
+		if err != ErrNameConflict {
+			return candidate, removed, err
+		}
+	}
+}
+
+// setNameLocked 在目录内改名并记入日志；调用方已确认新名称可用。
+func (s *engine) setNameLocked(en *entry, name string) {
+	delete(s.byName, en.nameKey())
+	en.meta.Name = name
+	s.byName[en.nameKey()] = en.meta.ID
+	s.publishLocked(en)
+	s.logLocked(journalRecord{Op: opRename, Meta: en.meta})
+}
+
+// overwriteLocked 把 src 的当前内容写入 dst 作为新版本，然后删除 src（重名策略 ConflictOverwrite 的改名）。
+func (s *engine) overwriteLocked(src, dst *entry, now time.Time) (FileMeta, []string, error) {
+	// 操作日志按替换内容记录，只有记日志时才需要读出内容。
+	var data []byte
+	if s.journal != nil {
+		var err error
+		if data, err = s.readBlob(src.blobKey, src.meta.SizeBytes); err != nil {
+			return FileMeta{}, nil, err
+		}
+	}
+	meta, removed, err := s.replaceLocked(ReplaceParams{
+		ID:       dst.meta.ID,
+		Bytes:    data,
+		Encoding: src.meta.Encoding,
+		IsText:   src.meta.IsText,
+		Now:      now,
+	}, src.meta.SizeBytes, src.blobKey, src.meta.SHA256)
+	if err != nil {
+		return FileMeta{}, removed, err
+	}
+	removed = append(removed, s.discardLocked(src, opDelete, now)...)
+	return meta, removed, s.commitLocked()
+}
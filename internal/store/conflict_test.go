@@ -0,0 +1,134 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSuffixedName(t *testing.T) {
+	cases := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"report.csv", 1, "report (1).csv"},
+		{"archive.tar.gz", 2, "archive.tar (2).gz"},
+		{"README", 1, "README (1)"},
+		{".env", 1, ".env (1)"},
+	}
+	for _, c := range cases {
+		if got := suffixedName(c.name, c.n); got != c.want {
+			t.Errorf("suffixedName(%q, %d) = %q, want %q", c.name, c.n, got, c.want)
+		}
+	}
+}
+
+func TestAddConflictPolicies(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000, MaxVersions: 2})
+	orig, _ := s.Add(AddParams{Name: "report.csv", Bytes: []byte("v1")})
+
+	if _, err := s.Add(AddParams{Name: "report.csv", Bytes: []byte("x")}); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "report.csv", Bytes: []byte("x"), OnConflict: "ask"}); err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+
+	suffixed, err := s.Add(AddParams{Name: "report.csv", Bytes: []byte("s"), OnConflict: ConflictSuffix})
+	if err != nil || suffixed.Name != "report (1).csv" {
+		t.Fatalf("suffix: %#v err=%v", suffixed, err)
+	}
+
+	over, err := s.Add(AddParams{Name: "report.csv", Bytes: []byte("v2"), OnConflict: ConflictOverwrite})
+	if err != nil || over.ID != orig.ID || over.Version != 2 || over.PrevVersions != 1 {
+		t.Fatalf("overwrite: %#v err=%v", over, err)
+	}
+
+	both, err := s.Add(AddParams{Name: "report.csv", Bytes: []byte("v3"), OnConflict: ConflictKeepBoth})
+	if err != nil || both.Name != "report.csv" || both.ID == orig.ID {
+		t.Fatalf("keep both: %#v err=%v", both, err)
+	}
+	// 原文件改名为下一个可用的编号。
+	if meta, err := s.GetMeta(orig.ID); err != nil || meta.Name != "report (2).csv" {
+		t.Fatalf("existing file after keep both: %#v err=%v", meta, err)
+	}
+	if !s.HasName("", "report (2).csv") || len(s.List()) != 3 {
+		t.Fatalf("unexpected files: %#v", s.List())
+	}
+}
+
+func TestAddSuffixIsAtomic(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 100, MaxTotalBytes: 10000})
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Add(AddParams{Name: "photo.jpg", Bytes: []byte(fmt.Sprint(i)), OnConflict: ConflictSuffix})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, meta := range s.List() {
+		names[meta.Name] = true
+	}
+	if len(names) != n || !names["photo.jpg"] || !names[fmt.Sprintf("photo (%d).jpg", n-1)] {
+		t.Fatalf("expected %d distinct names, got %v", n, names)
+	}
+}
+
+func TestRenameConflictPolicies(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1000, MaxVersions: 2, TrashTTL: time.Hour, MaxTrashBytes: 1000}
+	s, j := openJournalStore(t, dir, params)
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("a")})
+	b, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("b")})
+	c, _ := s.Add(AddParams{Name: "c.txt", Bytes: []byte("c")})
+
+	if _, err := s.RenameWith(RenameParams{ID: a.ID, Name: "b.txt"}); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	meta, err := s.RenameWith(RenameParams{ID: a.ID, Name: "b.txt", OnConflict: ConflictSuffix})
+	if err != nil || meta.Name != "b (1).txt" {
+		t.Fatalf("suffix: %#v err=%v", meta, err)
+	}
+	meta, err = s.RenameWith(RenameParams{ID: c.ID, Name: "b.txt", OnConflict: ConflictKeepBoth})
+	if err != nil || meta.Name != "b.txt" {
+		t.Fatalf("keep both: %#v err=%v", meta, err)
+	}
+	if got, _ := s.GetMeta(b.ID); got.Name != "b (2).txt" {
+		t.Fatalf("existing file after keep both: %#v", got)
+	}
+
+	// 覆盖：目标写入新版本，源文件进入回收站。
+	meta, err = s.RenameWith(RenameParams{ID: a.ID, Name: "b.txt", OnConflict: ConflictOverwrite})
+	if err != nil || meta.ID != c.ID || meta.Version != 2 {
+		t.Fatalf("overwrite: %#v err=%v", meta, err)
+	}
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound || len(s.ListTrash()) != 1 {
+		t.Fatalf("source should be in trash: err=%v trash=%#v", err, s.ListTrash())
+	}
+	j.Close()
+
+	recovered, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	f, err := recovered.Get(c.ID)
+	if err != nil || f.Meta.Name != "b.txt" || string(f.Bytes) != "a" || f.Meta.Version != 2 {
+		t.Fatalf("recovered overwrite: %#v err=%v", f.Meta, err)
+	}
+	if got, _ := recovered.GetMeta(b.ID); got.Name != "b (2).txt" {
+		t.Fatalf("recovered keep both: %#v", got)
+	}
+}
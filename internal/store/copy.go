@@ -0,0 +1,71 @@
+package store
+
+import "time"
+
+// Copy 在源文件所在目录以 newName 复制一份当前内容（不含历史版本），保留标签、备注与有效期，不保留置顶。
+// 副本与源文件共享内容，不增加物理用量，但占一个文件名额：文件数已满时按淘汰策略淘汰（源文件同样可能被淘汰）。
+// 目录中已有同名文件时返回 ErrNameConflict。
+func (s *engine) Copy(id, newName string) (FileMeta, error) {
+	newName, err := s.CleanName(newName)
+	if err != nil {
+		return FileMeta{}, err
+	}
+
+	s.mu.Lock()
+	meta, removed, err := s.copyLocked(id, newName, time.Now())
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return meta, err
+}
+
+func (s *engine) copyLocked(id, newName string, now time.Time) (FileMeta, []string, error) {
+	src, ok := s.liveLocked(id)
+	if !ok {
+		return FileMeta{}, nil, ErrNotFound
+	}
+	removed, err := s.takeNameLocked(src.meta.Folder, newName)
+	if err != nil {
+		return FileMeta{}, nil, err
+	}
+	// 操作日志按新增文件记录（重放时与源内容去重），只有记日志时才需要读出内容。
+	var data []byte
+	if s.journal != nil {
+		if data, err = s.readBlob(src.blobKey, src.meta.SizeBytes); err != nil {
+			return FileMeta{}, removed, err
+		}
+	}
+
+	// 先持有临时引用，源文件在淘汰中被移除时内容仍留给副本。
+	key := src.blobKey
+	s.retainLocked(key)
+	evicted, err := s.evictLocked(0, filePath(src.meta.Folder, newName))
+	removed = append(removed, evicted...)
+	if err != nil {
+		removed = append(removed, s.releaseLocked(key)...)
+		if len(removed) > 0 {
+			_ = s.commitLocked()
+		}
+		return FileMeta{}, removed, err
+	}
+
+	meta := FileMeta{
+		ID:        newID(),
+		Name:      newName,
+		Folder:    src.meta.Folder,
+		CreatedAt: now.UTC(),
+		SizeBytes: src.meta.SizeBytes,
+		Encoding:  src.meta.Encoding,
+		IsText:    src.meta.IsText,
+		Version:   1,
+		UpdatedAt: now.UTC(),
+		SHA256:    src.meta.SHA256,
+		ExpiresAt: src.meta.ExpiresAt,
+		Tags:      src.meta.Tags,
+		Note:      src.meta.Note,
+	}
+	s.insertLocked(&entry{meta: meta, blobKey: key})
+	removed = append(removed, s.releaseLocked(key)...)
+	s.logLocked(journalRecord{Op: opAdd, Meta: meta, Data: data})
+	return meta, removed, s.commitLocked()
+}
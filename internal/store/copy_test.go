@@ -0,0 +1,77 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCopySharesContent(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100, MaxVersions: 2})
+	if err := s.CreateFolder("docs"); err != nil {
+		t.Fatal(err)
+	}
+	src, _ := s.Add(AddParams{Name: "a.txt", Folder: "docs", Bytes: []byte("v1"), ExpiresAt: time.Now().Add(time.Hour)})
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: src.ID, Bytes: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetTags(src.ID, []string{"k"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := s.Copy(src.ID, "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.ID == src.ID || cp.Folder != "docs" || cp.Version != 1 || cp.PrevVersions != 0 || !cp.HasTag("k") || cp.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected copy: %#v", cp)
+	}
+	if f, err := s.Get(cp.ID); err != nil || string(f.Bytes) != "hello" {
+		t.Fatalf("copy content: %q err=%v", f.Bytes, err)
+	}
+	// 副本共享内容：物理用量不变。
+	if st := s.Stats(); st.Files != 2 || st.PhysicalBytes != 7 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+
+	if _, err := s.Copy(src.ID, "b.txt"); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Copy("nope", "c.txt"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// 文件数已满时按淘汰策略腾出名额；源文件被淘汰时副本的内容仍保留。
+	s.Add(AddParams{Name: "c.txt", Bytes: []byte("c")})
+	cp2, err := s.Copy(src.ID, "d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(src.ID); err != ErrNotFound {
+		t.Fatalf("oldest file should be evicted, got %v", err)
+	}
+	if f, err := s.Get(cp2.ID); err != nil || string(f.Bytes) != "hello" {
+		t.Fatalf("copy content after evicting source: %q err=%v", f.Bytes, err)
+	}
+}
+
+func TestCopySurvivesJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 2, MaxTotalBytes: 100}
+	s, j := openJournalStore(t, dir, params)
+	src, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")})
+	s.Add(AddParams{Name: "b.txt", Bytes: []byte("b")})
+	cp, err := s.Copy(src.ID, "c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	recovered, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	if f, err := recovered.Get(cp.ID); err != nil || string(f.Bytes) != "hello" || f.Meta.Name != "c.txt" {
+		t.Fatalf("recovered copy: %#v err=%v", f, err)
+	}
+	if st := recovered.Stats(); st.Files != 2 || st.PhysicalBytes != 6 {
+		t.Fatalf("unexpected stats after replay: %#v", st)
+	}
+}
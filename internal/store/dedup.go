@@ -0,0 +1,203 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// 内容按 SHA-256 去重：内容相同的文件/版本共享同一个 blob，按引用计数回收。
+// blob key 仍是每次写入新生成的随机 key（后端无需处理覆盖写）；bySum 只记录当前可复用的 blob，
+// 引用归零时先从 bySum 摘除再回收，之后相同内容会写入新的 key，不会与回收并发冲突。
+// size 为实际存放的字节数（压缩后），raw 为内容本身的字节数。
+// refs 为文件与历史版本的引用，trashRefs 为回收站条目的引用（见 trash.go）：
+// 有 refs 的 blob 计入 totalBytes，只被回收站引用的计入 trashBytes，两者都归零时回收。
+type blobRef struct {
+	key       string
+	sum       string
+	size      int64
+	raw       int64
+	refs      int
+	trashRefs int
+	// holds 为锁外读取（如写快照）期间的临时保留：不计入用量，只推迟回收，见 holdLocked。
+	holds int
+}
+
+func contentSum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// prepareBlob 返回内容对应的 blob key，并为调用方持有一个临时引用（提交后需 releaseLocked）。
+// 已有相同内容时直接复用；否则在锁外写入新 blob 再登记，并发写入相同内容时只保留先登记的一份。
+// 新 blob 在登记时即计入物理用量（按压缩后的大小），随后的淘汰会为它腾出空间。
+// res 非 nil 时，新增的物理用量从该预留中扣除。返回值 stored 为该 blob 实际占用的字节数。
+func (s *engine) prepareBlob(sum string, data []byte, res *Reservation) (key string, stored int64, err error) {
+	s.mu.Lock()
+	if r := s.bySum[sum]; r != nil {
+		s.retainReservedLocked(r, res)
+		s.mu.Unlock()
+		return r.key, r.size, nil
+	}
+	s.mu.Unlock()
+
+	key, enc := s.encodeBlob(data)
+	if err := s.blobs.put(key, enc); err != nil {
+		return "", 0, err
+	}
+
+	s.mu.Lock()
+	r := s.bySum[sum]
+	if r == nil {
+		r = s.addBlobLocked(key, sum, int64(len(enc)), int64(len(data)))
+		key = ""
+	}
+	s.retainReservedLocked(r, res)
+	s.mu.Unlock()
+
+	if key != "" {
+		_ = s.blobs.remove(key)
+	}
+	return r.key, r.size, nil
+}
+
+// retainReservedLocked 与 retainBlobLocked 相同，新增的物理用量从 res 中扣除。
+func (s *engine) retainReservedLocked(r *blobRef, res *Reservation) {
+	before := s.totalBytes
+	s.retainBlobLocked(r)
+	res.takeLocked(s.totalBytes - before)
+}
+
+// discardBlob 放弃 prepareBlob 持有的临时引用。
+func (s *engine) discardBlob(key string) {
+	s.mu.Lock()
+	removed := s.releaseLocked(key)
+	s.mu.Unlock()
+	s.removeBlobs(removed)
+}
+
+// storeBlobLocked 用于装载（快照/日志重放）：复用相同内容的 blob，否则写入并登记。
+// 返回的 blob 尚未被引用，由 insertLocked 等计入引用。
+func (s *engine) storeBlobLocked(sum string, data []byte) (key, gotSum string, err error) {
+	if sum == "" {
+		sum = contentSum(data)
+	}
+	if r := s.bySum[sum]; r != nil {
+		return r.key, sum, nil
+	}
+	key, enc := s.encodeBlob(data)
+	if err := s.blobs.put(key, enc); err != nil {
+		return "", "", err
+	}
+	s.addBlobLocked(key, sum, int64(len(enc)), int64(len(data)))
+	return key, sum, nil
+}
+
+// ensureBlobLocked 登记已存在于后端的 blob（从索引装载时）；同一 key 可被多个条目引用。
+func (s *engine) ensureBlobLocked(key, sum string, size, raw int64) {
+	if _, ok := s.blobRefs[key]; ok {
+		return
+	}
+	s.addBlobLocked(key, sum, size, raw)
+}
+
+// addBlobLocked 登记新 blob；物理用量在首次被引用时计入（retainBlobLocked）。
+// sum 为空（旧数据未记录摘要）时不参与去重。
+func (s *engine) addBlobLocked(key, sum string, size, raw int64) *blobRef {
+	r := &blobRef{key: key, sum: sum, size: size, raw: raw}
+	s.blobRefs[key] = r
+	if sum != "" {
+		if _, taken := s.bySum[sum]; !taken {
+			s.bySum[sum] = r
+		}
+	}
+	return r
+}
+
+func (s *engine) retainLocked(key string) {
+	s.retainBlobLocked(s.blobRefs[key])
+}
+
+// retainBlobLocked 增加一个引用；只被回收站引用的内容重新被使用时，用量从回收站转回总量。
+func (s *engine) retainBlobLocked(r *blobRef) {
+	r.refs++
+	if r.refs > 1 {
+		return
+	}
+	s.totalBytes += r.size
+	s.rawBytes += r.raw
+	if r.trashRefs > 0 {
+		s.trashBytes -= r.size
+	}
+}
+
+// releaseLocked 释放一个引用；引用归零时返回需回收的 key（调用方在释放锁后回收）。
+// 仍被回收站引用的内容不回收，用量转入回收站。
+func (s *engine) releaseLocked(key string) []string {
+	r, ok := s.blobRefs[key]
+	if !ok {
+		return nil
+	}
+	r.refs--
+	if r.refs > 0 {
+		return nil
+	}
+	s.totalBytes -= r.size
+	s.rawBytes -= r.raw
+	if r.trashRefs > 0 {
+		s.trashBytes += r.size
+		return nil
+	}
+	return s.dropBlobLocked(r)
+}
+
+// dropBlobLocked 注销已无任何引用的 blob，返回需回收的 key；仍被临时保留时等 unhold 再回收。
+func (s *engine) dropBlobLocked(r *blobRef) []string {
+	if r.holds > 0 {
+		return nil
+	}
+	delete(s.blobRefs, r.key)
+	if s.bySum[r.sum] == r {
+		delete(s.bySum, r.sum)
+	}
+	return []string{r.key}
+}
+
+// holdLocked 临时保留 key 对应的内容，使其在锁外读取期间不被回收；用完须调用 unhold。
+func (s *engine) holdLocked(key string) {
+	s.blobRefs[key].holds++
+}
+
+// unhold 结束 keys 的临时保留，回收保留期间已不再被引用的内容。
+func (s *engine) unhold(keys []string) {
+	s.mu.Lock()
+	var removed []string
+	for _, key := range keys {
+		r, ok := s.blobRefs[key]
+		if !ok {
+			continue
+		}
+		r.holds--
+		if r.refs == 0 && r.trashRefs == 0 {
+			removed = append(removed, s.dropBlobLocked(r)...)
+		}
+	}
+	s.mu.Unlock()
+	s.removeBlobs(removed)
+}
+
+// freedIfReleasedLocked 估算依次释放 keys 后可回收的物理字节数（不修改状态）。
+func (s *engine) freedIfReleasedLocked(keys []string) int64 {
+	pending := make(map[string]int, len(keys))
+	var freed int64
+	for _, key := range keys {
+		r, ok := s.blobRefs[key]
+		if !ok {
+			continue
+		}
+		pending[key]++
+		if pending[key] == r.refs {
+			freed += r.size
+		}
+	}
+	return freed
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func memBlobCount(s *InMemoryStore) int {
+	m := s.blobs.(*memBlobs)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.data)
+}
+
+func TestDuplicateContentSharesStorage(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("same log line\n")
+	a, err := s.Add(AddParams{Name: "a.log", Bytes: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(AddParams{Name: "b.log", Bytes: append([]byte(nil), content...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SHA256 == "" || a.SHA256 != b.SHA256 {
+		t.Fatalf("expected equal checksums, got %q and %q", a.SHA256, b.SHA256)
+	}
+
+	n := int64(len(content))
+	if st := s.Stats(); st.Files != 2 || st.LogicalBytes != 2*n || st.PhysicalBytes != n {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+	if memBlobCount(s) != 1 {
+		t.Fatalf("expected one shared blob, got %d", memBlobCount(s))
+	}
+
+	if _, err := s.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.Get(b.ID)
+	if err != nil || !bytes.Equal(f.Bytes, content) {
+		t.Fatalf("shared content lost after deleting a: %q err=%v", f.Bytes, err)
+	}
+	if st := s.Stats(); st.PhysicalBytes != n {
+		t.Fatalf("physical bytes should be kept while referenced: %#v", st)
+	}
+
+	if _, err := s.Delete(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.LogicalBytes != 0 || st.PhysicalBytes != 0 || memBlobCount(s) != 0 {
+		t.Fatalf("expected content freed after last reference: %#v blobs=%d", st, memBlobCount(s))
+	}
+}
+
+func TestDuplicateUploadNeedsNoSpace(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.csv", Bytes: []byte("12345678")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.csv", Bytes: []byte("12345678")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != nil {
+		t.Fatalf("duplicate upload should not evict a: %v", err)
+	}
+}
+
+func TestEvictionFreesSharedContentOnlyAtLastReference(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("xxxx")})
+	b, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("xxxx")})
+
+	// 4 + 7 > 10：淘汰 a 不释放空间，需要继续淘汰 b。
+	if _, err := s.Add(AddParams{Name: "c.txt", Bytes: []byte("yyyyyyy")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if _, err := s.GetMeta(id); err != ErrNotFound {
+			t.Fatalf("expected %s evicted, got %v", id, err)
+		}
+	}
+	if st := s.Stats(); st.Files != 1 || st.PhysicalBytes != 7 || memBlobCount(s) != 1 {
+		t.Fatalf("unexpected stats: %#v blobs=%d", st, memBlobCount(s))
+	}
+}
+
+func TestReplaceKeepsSharedContentForOtherFiles(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("shared")})
+	b, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("shared")})
+
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("changed")}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := s.Get(b.ID)
+	if err != nil || string(f.Bytes) != "shared" {
+		t.Fatalf("b changed by replacing a: %q err=%v", f.Bytes, err)
+	}
+	if st := s.Stats(); st.LogicalBytes != 13 || st.PhysicalBytes != 13 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+
+	// 替换回与 b 相同的内容时重新共享。
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("shared")}); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.LogicalBytes != 6+6 || st.PhysicalBytes != 6 || memBlobCount(s) != 1 {
+		t.Fatalf("unexpected stats after replacing back: %#v blobs=%d", st, memBlobCount(s))
+	}
+}
+
+func TestDiskStoreDedupSurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100}
+	s, err := NewDiskStore(dir, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("dup")})
+	b, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("dup")})
+
+	items, err := os.ReadDir(filepath.Join(dir, diskBlobsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected one blob file, got %d", len(items))
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDiskStore(dir, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	if st := reopened.Stats(); st.Files != 2 || st.LogicalBytes != 6 || st.PhysicalBytes != 3 {
+		t.Fatalf("unexpected stats after reopen: %#v", st)
+	}
+	if _, err := reopened.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	f, err := reopened.Get(b.ID)
+	if err != nil || string(f.Bytes) != "dup" {
+		t.Fatalf("shared content lost after reopen+delete: %q err=%v", f.Bytes, err)
+	}
+
+	// 重新上传相同内容时复用已有 blob。
+	if _, err := reopened.Add(AddParams{Name: "c.txt", Bytes: []byte("dup")}); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := os.ReadDir(filepath.Join(dir, diskBlobsDir)); len(items) != 1 {
+		t.Fatalf("expected blob to be reused, got %d files", len(items))
+	}
+}
@@ -0,0 +1,350 @@
+package store
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	diskIndexFile   = "index.json"
+	diskBlobsDir    = "blobs"
+	diskTmpSuffix   = ".tmp"
+	metaIndexFormat = 1
+)
+
+// DiskStore 把文件内容与元数据索引保存在本地目录，重启后可恢复：
+//
+//	<dir>/index.json   元数据索引（按 FIFO 顺序）
+//	<dir>/blobs/<key>  文件内容
+//
+// 淘汰、重名与 ReplaceBytes 口径与 InMemoryStore 完全一致（共用 engine）。
+// 索引由后台在写锁之外整体重写（见 indexWriter），连续的变更合并为一次写入；内容先于索引落盘、
+// 晚于索引删除，崩溃只会丢失最近未落盘的变更并留下孤儿内容，启动时清理。退出前应调用 Close。
+type DiskStore struct {
+	*engine
+	dir string
+}
+
+// metaIndex 是落盘的元数据索引（DiskStore / S3Store 共用）。Folders 为全部目录（含空目录），
+// Trash 为回收站条目（按删除顺序）。
+type metaIndex struct {
+	Version int              `json:"version"`
+	Files   []metaIndexEntry `json:"files"`
+	Folders []string         `json:"folders,omitempty"`
+	Trash   []metaIndexTrash `json:"trash,omitempty"`
+}
+
+// BlobBytes 为内容实际存放的字节数（压缩后）；旧索引中缺省时等于 SizeBytes。
+type metaIndexEntry struct {
+	FileMeta
+	Blob      string             `json:"blob"`
+	BlobBytes int64              `json:"blob_bytes,omitempty"`
+	Versions  []metaIndexVersion `json:"versions,omitempty"`
+}
+
+type metaIndexTrash struct {
+	metaIndexEntry
+	DeletedAt time.Time `json:"deleted_at"`
+	Evicted   bool      `json:"evicted,omitempty"`
+}
+
+type metaIndexVersion struct {
+	VersionMeta
+	Blob      string `json:"blob"`
+	BlobBytes int64  `json:"blob_bytes,omitempty"`
+}
+
+func storedBytes(blobBytes, size int64) int64 {
+	if blobBytes > 0 {
+		return blobBytes
+	}
+	return size
+}
+
+func NewDiskStore(dir string, p NewParams) (*DiskStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("%w: storage dir is required", ErrInvalidInput)
+	}
+	blobs := &diskBlobs{dir: filepath.Join(dir, diskBlobsDir)}
+	if err := os.MkdirAll(blobs.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage dir: %w", err)
+	}
+
+	e, err := newEngine(p, blobs)
+	if err != nil {
+		return nil, err
+	}
+	s := &DiskStore{engine: e, dir: dir}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	w, err := newIndexWriter(e, filepath.Join(dir, diskIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	e.index = w
+	return s, nil
+}
+
+// Flush 等待此前的变更写入索引。
+func (s *DiskStore) Flush() error {
+	return s.index.Flush()
+}
+
+// Close 停止后台写索引并把最后的变更落盘（用于优雅退出）。
+func (s *DiskStore) Close() error {
+	return s.index.Close()
+}
+
+// load 读取索引并与 blobs 目录对账：丢弃内容缺失/大小不符的条目，删除未被引用的内容，
+// 若配置的上限变小则按淘汰策略淘汰到满足上限，并清除回收站中已到期的条目。
+func (s *DiskStore) load() error {
+	idx, _, err := readIndexFile(filepath.Join(s.dir, diskIndexFile))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blobOK := func(key string, size int64) bool {
+		st, err := os.Stat(s.blobPath(key))
+		return err == nil && st.Mode().IsRegular() && st.Size() == size
+	}
+	s.loadFoldersLocked(idx.Folders)
+	for _, f := range idx.Files {
+		s.insertIndexedLocked(f, blobOK)
+	}
+	for _, t := range idx.Trash {
+		s.trashIndexedLocked(t, blobOK)
+	}
+
+	s.fitLimitsLocked()
+	s.sweepTrashLocked(time.Now())
+	return s.removeOrphansLocked()
+}
+
+// insertIndexedLocked 装载一条索引记录，blobOK 判断内容是否存在且存放大小相符。
+// 当前内容不可用时丢弃整条记录；历史版本不可用时只丢弃该版本。
+func (s *engine) insertIndexedLocked(f metaIndexEntry, blobOK func(key string, size int64) bool) {
+	if _, dup := s.byName[nameKey{folder: f.Folder, name: f.Name}]; dup {
+		return
+	}
+	if en := s.indexedEntryLocked(f, blobOK); en != nil {
+		s.insertLocked(en)
+	}
+}
+
+// trashIndexedLocked 装载一条回收站索引记录，规则同 insertIndexedLocked（回收站条目不占文件名）。
+func (s *engine) trashIndexedLocked(t metaIndexTrash, blobOK func(key string, size int64) bool) {
+	if en := s.indexedEntryLocked(t.metaIndexEntry, blobOK); en != nil {
+		s.loadTrashLocked(en, t.DeletedAt, t.Evicted)
+	}
+}
+
+// indexedEntryLocked 校验索引记录并登记其内容，记录不可用时返回 nil。
+func (s *engine) indexedEntryLocked(f metaIndexEntry, blobOK func(key string, size int64) bool) *entry {
+	if f.ID == "" || f.Name == "" || !validFolder(f.Folder) || !isBlobKey(f.Blob) {
+		return nil
+	}
+	if _, dup := s.byID[f.ID]; dup {
+		return nil
+	}
+	if _, dup := s.trash[f.ID]; dup {
+		return nil
+	}
+	if !blobOK(f.Blob, storedBytes(f.BlobBytes, f.SizeBytes)) {
+		return nil
+	}
+	en := &entry{meta: f.FileMeta, blobKey: f.Blob}
+	s.ensureBlobLocked(f.Blob, f.SHA256, storedBytes(f.BlobBytes, f.SizeBytes), f.SizeBytes)
+	for _, v := range f.Versions {
+		if v.Version <= 0 || v.Version == f.Version || en.findVersion(v.Version) >= 0 {
+			continue
+		}
+		stored := storedBytes(v.BlobBytes, v.SizeBytes)
+		if !isBlobKey(v.Blob) || !blobOK(v.Blob, stored) {
+			continue
+		}
+		en.versions = append(en.versions, version{VersionMeta: v.VersionMeta, blobKey: v.Blob})
+		s.ensureBlobLocked(v.Blob, v.SHA256, stored, v.SizeBytes)
+	}
+	return en
+}
+
+// loadFoldersLocked 装载索引中的目录，忽略不合法的路径。
+func (s *engine) loadFoldersLocked(folders []string) {
+	for _, f := range folders {
+		if f != "" && validFolder(f) {
+			s.addFolderLocked(f)
+		}
+	}
+}
+
+// readIndexFile 读取元数据索引；文件不存在时返回空索引，found 为 false。
+func readIndexFile(path string) (idx metaIndex, found bool, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return metaIndex{Version: metaIndexFormat}, false, nil
+		}
+		return metaIndex{}, false, fmt.Errorf("read index: %w", err)
+	}
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return metaIndex{}, false, fmt.Errorf("parse index: %w", err)
+	}
+	if idx.Version != metaIndexFormat {
+		return metaIndex{}, false, fmt.Errorf("unsupported index version %d", idx.Version)
+	}
+	return idx, true, nil
+}
+
+// referencedLocked 返回文件（含历史版本）与回收站条目引用的全部内容 key。
+func (s *engine) referencedLocked() map[string]struct{} {
+	referenced := make(map[string]struct{}, len(s.byID)+len(s.trash))
+	for _, en := range s.byID {
+		for _, key := range en.blobKeys() {
+			referenced[key] = struct{}{}
+		}
+	}
+	for _, te := range s.trash {
+		for _, key := range te.en.blobKeys() {
+			referenced[key] = struct{}{}
+		}
+	}
+	return referenced
+}
+
+func (s *DiskStore) removeOrphansLocked() error {
+	referenced := s.referencedLocked()
+
+	blobsDir := filepath.Join(s.dir, diskBlobsDir)
+	items, err := os.ReadDir(blobsDir)
+	if err != nil {
+		return fmt.Errorf("read blobs dir: %w", err)
+	}
+	for _, it := range items {
+		if it.IsDir() {
+			continue
+		}
+		if _, ok := referenced[it.Name()]; ok {
+			continue
+		}
+		_ = os.Remove(filepath.Join(blobsDir, it.Name()))
+	}
+	return nil
+}
+
+// indexLocked 复制当前状态为落盘索引（不含内容）。
+func (s *engine) indexLocked() metaIndex {
+	idx := metaIndex{Version: metaIndexFormat, Files: make([]metaIndexEntry, 0, len(s.byID)), Folders: s.folderListLocked()}
+	for e := s.fifo.Front(); e != nil; e = e.Next() {
+		idx.Files = append(idx.Files, s.indexEntryLocked(e.Value.(*entry)))
+	}
+	for e := s.trashList.Front(); e != nil; e = e.Next() {
+		te := e.Value.(*trashEntry)
+		idx.Trash = append(idx.Trash, metaIndexTrash{metaIndexEntry: s.indexEntryLocked(te.en), DeletedAt: te.deletedAt, Evicted: te.evicted})
+	}
+	return idx
+}
+
+func (s *engine) indexEntryLocked(en *entry) metaIndexEntry {
+	f := metaIndexEntry{FileMeta: en.meta, Blob: en.blobKey, BlobBytes: s.blobRefs[en.blobKey].size}
+	for _, v := range en.versions {
+		f.Versions = append(f.Versions, metaIndexVersion{VersionMeta: v.VersionMeta, Blob: v.blobKey, BlobBytes: s.blobRefs[v.blobKey].size})
+	}
+	return f
+}
+
+func (s *DiskStore) blobPath(key string) string {
+	return filepath.Join(s.dir, diskBlobsDir, key)
+}
+
+type diskBlobs struct {
+	dir string
+}
+
+func (d *diskBlobs) put(key string, data []byte) error {
+	return writeFileAtomic(filepath.Join(d.dir, key), data)
+}
+
+func (d *diskBlobs) get(key string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(d.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errBlobNotFound
+	}
+	return b, err
+}
+
+func (d *diskBlobs) open(key string, _ int64) (io.ReadSeekCloser, error) {
+	f, err := os.Open(filepath.Join(d.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (d *diskBlobs) remove(key string) error {
+	err := os.Remove(filepath.Join(d.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// writeFileAtomic 先写临时文件并 fsync，再 rename 覆盖目标，避免读到半截内容。
+func writeFileAtomic(path string, data []byte) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func writeFileAtomicFunc(path string, write func(w io.Writer) error) error {
+	tmp := path + diskTmpSuffix
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// isBlobKey 校验索引里的内容 key，防止被篡改的索引引用 blobs 目录之外的路径。
+// 压缩存放的内容 key 带 gzipKeySuffix 后缀。
+func isBlobKey(key string) bool {
+	key = strings.TrimSuffix(key, gzipKeySuffix)
+	if len(key) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskStorePersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello"), Encoding: "UTF-8", IsText: true, Now: time.Unix(1, 0)})
+	if err != nil {
+		t.Fatalf("add a: %v", err)
+	}
+	b, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("world"), Now: time.Unix(2, 0)})
+	if err != nil {
+		t.Fatalf("add b: %v", err)
+	}
+	if _, err := s.Rename(a.ID, "A.txt"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: b.ID, Bytes: []byte("world!"), Encoding: "GBK", IsText: true}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	items := reopened.List()
+	if len(items) != 2 || items[0].ID != a.ID || items[1].ID != b.ID {
+		t.Fatalf("expected FIFO order kept, got %#v", items)
+	}
+	if items[0].Name != "A.txt" || items[0].Encoding != "UTF-8" || !items[0].IsText {
+		t.Fatalf("unexpected meta for a: %#v", items[0])
+	}
+	got, err := reopened.Get(b.ID)
+	if err != nil {
+		t.Fatalf("get b: %v", err)
+	}
+	if string(got.Bytes) != "world!" || got.Meta.Encoding != "GBK" {
+		t.Fatalf("unexpected b after reopen: %q %#v", got.Bytes, got.Meta)
+	}
+	if st := reopened.Stats(); st.Files != 2 || st.PhysicalBytes != 11 {
+		t.Fatalf("unexpected stats: files=%d total=%d", st.Files, st.PhysicalBytes)
+	}
+}
+
+func TestDiskStoreFIFOEvictionRemovesContent(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, NewParams{MaxFiles: 2, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("1")})
+	if err != nil {
+		t.Fatalf("add a: %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("2")}); err != nil {
+		t.Fatalf("add b: %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "c.txt", Bytes: []byte("3")}); err != nil {
+		t.Fatalf("add c: %v", err)
+	}
+
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("expected oldest evicted (a), got %v", err)
+	}
+	// 内容在索引落盘之后才删除。
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := os.ReadDir(filepath.Join(dir, diskBlobsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("expected evicted content removed from disk, got %d blobs", len(blobs))
+	}
+}
+
+func TestDiskStoreNameConflictAndReplaceLimits(t *testing.T) {
+	s, err := NewDiskStore(t.TempDir(), NewParams{MaxFiles: 10, MaxTotalBytes: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("1")})
+	if err != nil {
+		t.Fatalf("add a: %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("2")}); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "A.txt", Bytes: []byte("2")}); err != nil {
+		t.Fatalf("expected case-sensitive names, got %v", err)
+	}
+
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("111")}); err != ErrReplaceWouldExceed {
+		t.Fatalf("expected ErrReplaceWouldExceed, got %v", err)
+	}
+	f, err := s.Get(a.ID)
+	if err != nil {
+		t.Fatalf("get a: %v", err)
+	}
+	if string(f.Bytes) != "1" {
+		t.Fatalf("expected original bytes kept, got %q", string(f.Bytes))
+	}
+}
+
+func TestDiskStoreOpenStreamsFromDisk(t *testing.T) {
+	s, err := NewDiskStore(t.TempDir(), NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, rc, err := s.Open(a.ID)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rc.Close()
+	if _, ok := rc.(*os.File); !ok {
+		t.Fatalf("expected file-backed reader, got %T", rc)
+	}
+	if _, err := rc.Seek(5, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.SizeBytes != 10 || string(rest) != "56789" {
+		t.Fatalf("unexpected read: size=%d rest=%q", meta.SizeBytes, rest)
+	}
+}
+
+func TestDiskStoreLoadDropsMissingAndOrphanContent(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("a")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟崩溃：a 的内容丢失，另有一份未登记的孤儿内容。
+	if err := os.Remove(s.blobPath(s.byID[a.ID].blobKey)); err != nil {
+		t.Fatal(err)
+	}
+	orphan := filepath.Join(dir, diskBlobsDir, newID())
+	if err := os.WriteFile(orphan, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	items := reopened.List()
+	if len(items) != 1 || items[0].ID != b.ID {
+		t.Fatalf("expected only b kept, got %#v", items)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatalf("expected orphan removed, stat err=%v", err)
+	}
+}
+
+func TestDiskStoreLoadEnforcesSmallerLimits(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := s.Add(AddParams{Name: name, Bytes: []byte("1")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDiskStore(dir, NewParams{MaxFiles: 2, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	items := reopened.List()
+	if len(items) != 2 || items[0].Name != "b.txt" || items[1].Name != "c.txt" {
+		t.Fatalf("expected oldest dropped, got %#v", items)
+	}
+}
@@ -4,10 +4,23 @@ import "errors"
 
 var (
 	ErrNotFound           = errors.New("not found")
+	ErrVersionNotFound    = errors.New("version not found")
+	ErrFolderNotFound     = errors.New("folder not found")
+	ErrFolderNotEmpty     = errors.New("folder not empty")
 	ErrNameConflict       = errors.New("name conflict")
 	ErrTooLarge           = errors.New("too large")
 	ErrInsufficientSpace  = errors.New("insufficient space")
 	ErrInvalidInput       = errors.New("invalid input")
 	ErrReplaceWouldExceed = errors.New("replace would exceed limits")
+	ErrPinLimit           = errors.New("pinned limit exceeded")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrSnapshotCorrupt    = errors.New("snapshot corrupt")
+	ErrJournalCorrupt     = errors.New("journal corrupt")
+
+	// 文件名不符合 NamePolicy（同时匹配 ErrInvalidInput）。
+	ErrInvalidName       = errors.New("invalid name")
+	ErrNameTooLong       = errors.New("name too long")
+	ErrNameForbiddenChar = errors.New("name contains forbidden character")
+	ErrNameReserved      = errors.New("reserved name")
 )
 
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"slices"
+)
+
+// 淘汰策略名（limits.eviction_policy）。
+const (
+	EvictionFIFO    = "fifo"
+	EvictionLRU     = "lru"
+	EvictionLFU     = "lfu"
+	EvictionLargest = "largest"
+)
+
+// evictionPolicy 决定超限时先淘汰哪个文件。置顶文件不加入策略（fifoPolicy 除外，由其自行跳过）。
+// 所有方法都在持有 engine 写锁时调用；读路径的 touch 先记入缓冲，选择淘汰对象前统一应用（见 views.go）。
+// 访问记录只在内存中维护，重启后按装载顺序重新开始。
+type evictionPolicy interface {
+	add(en *entry)
+	remove(en *entry)
+	// update 在条目大小变化（替换内容、恢复版本）后调用。
+	update(en *entry)
+	// touch 在文件内容被读取（下载、转码）时调用。
+	touch(en *entry)
+	// victim 返回下一个应被淘汰的条目；为空时返回 nil。
+	victim() *entry
+	// walk 按淘汰顺序（与依次淘汰 victim 的顺序相同）遍历条目，fn 返回 false 时停止；不改变策略状态。
+	walk(fn func(en *entry) bool)
+}
+
+func newEvictionPolicy(name string, fifo *list.List) (evictionPolicy, error) {
+	switch name {
+	case "", EvictionFIFO:
+		return fifoPolicy{fifo: fifo}, nil
+	case EvictionLRU:
+		return &lruPolicy{order: list.New()}, nil
+	case EvictionLFU:
+		return &heapPolicy{less: lessFrequentlyUsed, countHits: true}, nil
+	case EvictionLargest:
+		return &heapPolicy{less: largerFirst}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown eviction policy %q", ErrInvalidInput, name)
+	}
+}
+
+// fifoPolicy 淘汰最早上传的文件，直接复用 engine 的上传顺序链表（跳过其中的置顶文件）。
+type fifoPolicy struct {
+	fifo *list.List
+}
+
+func (fifoPolicy) add(*entry)    {}
+func (fifoPolicy) remove(*entry) {}
+func (fifoPolicy) update(*entry) {}
+func (fifoPolicy) touch(*entry)  {}
+
+func (p fifoPolicy) victim() *entry {
+	for e := p.fifo.Front(); e != nil; e = e.Next() {
+		if en := e.Value.(*entry); !en.meta.Pinned {
+			return en
+		}
+	}
+	return nil
+}
+
+func (p fifoPolicy) walk(fn func(en *entry) bool) {
+	for e := p.fifo.Front(); e != nil; e = e.Next() {
+		if en := e.Value.(*entry); !en.meta.Pinned && !fn(en) {
+			return
+		}
+	}
+}
+
+// lruPolicy 淘汰最久未被读取的文件（新上传视为一次访问）。
+type lruPolicy struct {
+	order *list.List
+}
+
+func (p *lruPolicy) add(en *entry) {
+	en.policyElem = p.order.PushBack(en)
+}
+
+func (p *lruPolicy) remove(en *entry) {
+	p.order.Remove(en.policyElem)
+	en.policyElem = nil
+}
+
+func (p *lruPolicy) update(*entry) {}
+
+func (p *lruPolicy) touch(en *entry) {
+	p.order.MoveToBack(en.policyElem)
+}
+
+func (p *lruPolicy) victim() *entry {
+	if front := p.order.Front(); front != nil {
+		return front.Value.(*entry)
+	}
+	return nil
+}
+
+func (p *lruPolicy) walk(fn func(en *entry) bool) {
+	for e := p.order.Front(); e != nil; e = e.Next() {
+		if !fn(e.Value.(*entry)) {
+			return
+		}
+	}
+}
+
+// heapPolicy 用最小堆维护淘汰顺序，less 为 true 的条目先被淘汰。
+type heapPolicy struct {
+	less      func(a, b *entry) bool
+	countHits bool
+	clock     uint64
+	h         entryHeap
+}
+
+// lessFrequentlyUsed：访问次数少的先淘汰，次数相同时淘汰最久未访问的。
+func lessFrequentlyUsed(a, b *entry) bool {
+	if a.hits != b.hits {
+		return a.hits < b.hits
+	}
+	return a.lastUse < b.lastUse
+}
+
+// largerFirst：占用空间（含历史版本）大的先淘汰，相同时淘汰先上传的。
+func largerFirst(a, b *entry) bool {
+	if sa, sb := a.bytes(), b.bytes(); sa != sb {
+		return sa > sb
+	}
+	return a.lastUse < b.lastUse
+}
+
+func (p *heapPolicy) add(en *entry) {
+	p.clock++
+	en.hits, en.lastUse = 0, p.clock
+	p.h.less = p.less
+	heap.Push(&p.h, en)
+}
+
+func (p *heapPolicy) remove(en *entry) {
+	heap.Remove(&p.h, en.heapIndex)
+	en.heapIndex = -1
+}
+
+func (p *heapPolicy) update(en *entry) {
+	heap.Fix(&p.h, en.heapIndex)
+}
+
+func (p *heapPolicy) touch(en *entry) {
+	if !p.countHits {
+		return
+	}
+	p.clock++
+	en.hits++
+	en.lastUse = p.clock
+	heap.Fix(&p.h, en.heapIndex)
+}
+
+func (p *heapPolicy) victim() *entry {
+	if len(p.h.items) == 0 {
+		return nil
+	}
+	return p.h.items[0]
+}
+
+// walk 对堆的副本排序后遍历；less 是严格全序（lastUse 各不相同），顺序与依次弹出堆顶相同。
+func (p *heapPolicy) walk(fn func(en *entry) bool) {
+	items := slices.Clone(p.h.items)
+	slices.SortFunc(items, func(a, b *entry) int {
+		switch {
+		case p.less(a, b):
+			return -1
+		case p.less(b, a):
+			return 1
+		}
+		return 0
+	})
+	for _, en := range items {
+		if !fn(en) {
+			return
+		}
+	}
+}
+
+type entryHeap struct {
+	items []*entry
+	less  func(a, b *entry) bool
+}
+
+func (h entryHeap) Len() int           { return len(h.items) }
+func (h entryHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+
+func (h entryHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].heapIndex = i
+	h.items[j].heapIndex = j
+}
+
+func (h *entryHeap) Push(x any) {
+	en := x.(*entry)
+	en.heapIndex = len(h.items)
+	h.items = append(h.items, en)
+}
+
+func (h *entryHeap) Pop() any {
+	n := len(h.items)
+	en := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return en
+}
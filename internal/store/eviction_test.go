@@ -0,0 +1,98 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// seedEvictionOrder 上传 a(1B) b(2B) c(6B) d(3B)，再按 d d d a a c c b 的顺序读取。
+// 各策略的下一个淘汰对象因此互不相同：fifo=a、lru=d、lfu=b、largest=c。
+func seedEvictionOrder(t *testing.T, s FileStore) map[string]string {
+	t.Helper()
+	ids := make(map[string]string)
+	for _, f := range []struct {
+		name string
+		size int
+	}{{"a", 1}, {"b", 2}, {"c", 6}, {"d", 3}} {
+		meta, err := s.Add(AddParams{Name: f.name, Bytes: []byte(strings.Repeat(f.name, f.size))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[f.name] = meta.ID
+	}
+	for _, name := range []string{"d", "d", "d", "a", "a", "c", "c", "b"} {
+		if _, err := s.Get(ids[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func TestEvictionPolicyOrder(t *testing.T) {
+	want := map[string]string{
+		EvictionFIFO:    "a",
+		EvictionLRU:     "d",
+		EvictionLFU:     "b",
+		EvictionLargest: "c",
+	}
+	limits := map[string]NewParams{
+		"max_files":       {MaxFiles: 4, MaxTotalBytes: 100},
+		"max_total_bytes": {MaxFiles: 10, MaxTotalBytes: 12},
+	}
+	for policy, victim := range want {
+		for limit, p := range limits {
+			t.Run(policy+"/"+limit, func(t *testing.T) {
+				p.EvictionPolicy = policy
+				s, err := NewInMemoryStore(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids := seedEvictionOrder(t, s)
+				if _, err := s.Add(AddParams{Name: "e", Bytes: []byte("e")}); err != nil {
+					t.Fatal(err)
+				}
+				for name, id := range ids {
+					_, err := s.GetMeta(id)
+					if name == victim && err != ErrNotFound {
+						t.Fatalf("expected %s evicted, got %v", name, err)
+					}
+					if name != victim && err != nil {
+						t.Fatalf("expected %s kept, got %v", name, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLargestPolicyFollowsReplace(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100, EvictionPolicy: EvictionLargest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+	b, _ := s.Add(AddParams{Name: "b", Bytes: []byte("bbbb")})
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("aaaaaaaa")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "c", Bytes: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "d", Bytes: []byte("d")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("expected a (largest after replace) evicted, got %v", err)
+	}
+	if _, err := s.GetMeta(b.ID); err != nil {
+		t.Fatalf("expected b kept, got %v", err)
+	}
+}
+
+func TestUnknownEvictionPolicyRejected(t *testing.T) {
+	_, err := NewInMemoryStore(NewParams{MaxFiles: 1, MaxTotalBytes: 1, EvictionPolicy: "random"})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package store
+
+import "time"
+
+// 淘汰原因（EvictionRecord.Reason）。
+const (
+	EvictReasonMaxFiles = "max_files"
+	EvictReasonMaxBytes = "max_total_bytes"
+)
+
+// maxEvictionLog 为保留的最近淘汰记录条数。
+const maxEvictionLog = 100
+
+// EvictionRecord 记录一次自动淘汰，让用户知道文件为什么不见了。只保存在内存中，重启后清空。
+type EvictionRecord struct {
+	ID        string
+	Name      string
+	Folder    string
+	SizeBytes int64
+	Reason    string
+	At        time.Time
+	// Trigger 为触发淘汰的新文件（“目录/文件名”），即正在上传、复制或恢复的文件；装载时按上限丢弃为空。
+	Trigger string
+	// Trashed 为 true 表示被淘汰的文件进入了回收站，可以恢复。
+	Trashed bool
+}
+
+// RecentEvictions 返回最近的淘汰记录（最多 100 条），最近的在前。
+func (s *engine) RecentEvictions() []EvictionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]EvictionRecord, 0, len(s.evictions))
+	for i := len(s.evictions) - 1; i >= 0; i-- {
+		out = append(out, s.evictions[i])
+	}
+	return out
+}
+
+// evictReasonLocked 返回当前淘汰时触及的上限：文件数已满时为 max_files，否则为 max_total_bytes。
+func (s *engine) evictReasonLocked() string {
+	if len(s.byID)+s.reservedFiles >= s.maxFiles {
+		return EvictReasonMaxFiles
+	}
+	return EvictReasonMaxBytes
+}
+
+func (s *engine) recordEvictionLocked(en *entry, reason, trigger string, at time.Time) {
+	_, trashed := s.trash[en.meta.ID]
+	if len(s.evictions) == maxEvictionLog {
+		copy(s.evictions, s.evictions[1:])
+		s.evictions = s.evictions[:len(s.evictions)-1]
+	}
+	s.evictions = append(s.evictions, EvictionRecord{
+		ID:        en.meta.ID,
+		Name:      en.meta.Name,
+		Folder:    en.meta.Folder,
+		SizeBytes: en.meta.SizeBytes,
+		Reason:    reason,
+		At:        at.UTC(),
+		Trigger:   trigger,
+		Trashed:   trashed,
+	})
+}
+
+// filePath 返回“目录/文件名”形式的路径（根目录下只有文件名）。
+func filePath(folder, name string) string {
+	if folder == "" {
+		return name
+	}
+	return folder + "/" + name
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRecentEvictionsRecordsReasonAndTrigger(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100, TrashTTL: time.Hour, MaxTrashBytes: 100, TrashEvicted: true})
+	if err := s.CreateFolder("docs"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+	b, _ := s.Add(AddParams{Folder: "docs", Name: "b", Bytes: make([]byte, 10)})
+
+	// 空间不足：为 90 字节的新文件淘汰最早的 a，记下触发淘汰的上传。
+	big, err := s.Add(AddParams{Name: "big.bin", Bytes: make([]byte, 90)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Delete(big.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 文件数已满：淘汰 b。
+	for _, name := range []string{"c", "d", "e"} {
+		if _, err := s.Add(AddParams{Folder: "docs", Name: name, Bytes: []byte(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := s.RecentEvictions()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 records, got %#v", got)
+	}
+	if r := got[0]; r.ID != b.ID || r.Folder != "docs" || r.SizeBytes != 10 || r.Reason != EvictReasonMaxFiles || r.Trigger != "docs/e" || !r.Trashed {
+		t.Fatalf("unexpected newest record: %#v", r)
+	}
+	if r := got[1]; r.ID != a.ID || r.Reason != EvictReasonMaxBytes || r.Trigger != "big.bin" || r.At.IsZero() {
+		t.Fatalf("unexpected oldest record: %#v", r)
+	}
+}
+
+func TestRecentEvictionsIsBounded(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 1, MaxTotalBytes: 100})
+	n := maxEvictionLog + 10
+	var names []string
+	for i := 0; i <= n; i++ {
+		name := fmt.Sprintf("f%d", i)
+		if _, err := s.Add(AddParams{Name: name, Bytes: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	got := s.RecentEvictions()
+	if len(got) != maxEvictionLog {
+		t.Fatalf("expected %d records, got %d", maxEvictionLog, len(got))
+	}
+	// 保留最近的记录：最新一条是倒数第二个上传的文件，最旧的更早的记录已被丢弃。
+	if got[0].Name != names[n-1] || got[len(got)-1].Name != names[n-maxEvictionLog] {
+		t.Fatalf("unexpected window: newest %q, oldest %q", got[0].Name, got[len(got)-1].Name)
+	}
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// Expired 报告文件在 now 时是否已过期（ExpiresAt 为零表示永不过期）。
+func (m FileMeta) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// SweepExpired 删除在 now 时已过期的文件（置顶文件同样会过期），返回删除的个数；
+// 同时清除回收站中已到期的条目（不计入返回值）。
+// 过期文件在被清理前就已对读取、列表与重名检查不可见，清理只负责回收空间。
+func (s *engine) SweepExpired(now time.Time) (int, error) {
+	s.mu.Lock()
+	removed, n := s.sweepLocked(now)
+	purged, pn := s.sweepTrashLocked(now)
+	removed = append(removed, purged...)
+	var err error
+	if n > 0 || pn > 0 {
+		err = s.commitLocked()
+	}
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return n, err
+}
+
+func (s *engine) sweepLocked(now time.Time) ([]string, int) {
+	var (
+		removed []string
+		n       int
+	)
+	for _, en := range s.byID {
+		if en.meta.Expired(now) {
+			removed = append(removed, s.expireLocked(en)...)
+			n++
+		}
+	}
+	return removed, n
+}
+
+func (s *engine) expireLocked(en *entry) []string {
+	removed := s.deleteLocked(en)
+	s.logLocked(journalRecord{Op: opExpire, Meta: en.meta})
+	return removed
+}
+
+// liveLocked 返回未过期的条目。
+func (s *engine) liveLocked(id string) (*entry, bool) {
+	en, ok := s.byID[id]
+	if !ok || en.meta.Expired(time.Now()) {
+		return nil, false
+	}
+	return en, true
+}
+
+// takeNameLocked 检查 folder 下的 name 是否可用；被已过期（尚未清理）的文件占用时先删除它。
+func (s *engine) takeNameLocked(folder, name string) ([]string, error) {
+	id, exists := s.byName[nameKey{folder: folder, name: name}]
+	if !exists {
+		return nil, nil
+	}
+	en := s.byID[id]
+	if !en.meta.Expired(time.Now()) {
+		return nil, ErrNameConflict
+	}
+	return s.expireLocked(en), nil
+}
+
+type SweeperOptions struct {
+	// Interval 为清理周期；<= 0 时不启动后台清理。
+	Interval time.Duration
+	// OnError 接收清理失败的错误（可为 nil）。
+	OnError func(error)
+}
+
+// Sweeper 周期性清理过期文件。
+type Sweeper struct {
+	store   FileStore
+	onError func(error)
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewSweeper(s FileStore, opt SweeperOptions) *Sweeper {
+	p := &Sweeper{
+		store:   s,
+		onError: opt.OnError,
+		stopCh:  make(chan struct{}),
+	}
+	if opt.Interval > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ticker := time.NewTicker(opt.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if _, err := p.store.SweepExpired(time.Now()); err != nil && p.onError != nil {
+						p.onError(err)
+					}
+				case <-p.stopCh:
+					return
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// Close 停止后台清理并等待进行中的一轮结束。
+func (p *Sweeper) Close() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.wg.Wait()
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// addExpired 添加一个已经过期（但尚未清理）的文件。
+func addExpired(t *testing.T, s FileStore, name string, data []byte) FileMeta {
+	t.Helper()
+	past := time.Now().Add(-time.Hour)
+	meta, err := s.Add(AddParams{Name: name, Bytes: data, Now: past, ExpiresAt: past.Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestExpiredFileInvisibleBeforeSweep(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := addExpired(t, s, "a.txt", []byte("aaa"))
+
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("GetMeta: expected ErrNotFound, got %v", err)
+	}
+	if _, _, err := s.Open(a.ID); err != ErrNotFound {
+		t.Fatalf("Open: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.Rename(a.ID, "b.txt"); err != ErrNotFound {
+		t.Fatalf("Rename: expected ErrNotFound, got %v", err)
+	}
+	if len(s.List()) != 0 || s.HasName("", "a.txt") {
+		t.Fatalf("expired file still visible: %#v", s.List())
+	}
+	if st := s.Stats(); st.Files != 1 {
+		t.Fatalf("expected file kept until sweep, got %#v", st)
+	}
+
+	// 过期文件占用的名字可以直接复用。
+	b, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("new")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.Files != 1 || st.PhysicalBytes != 3 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+	if b.ExpiresAt != (time.Time{}) {
+		t.Fatalf("expected no expiry, got %v", b.ExpiresAt)
+	}
+}
+
+func TestSweepExpiredRemovesOnlyExpired(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxPinnedBytes: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addExpired(t, s, "old.txt", []byte("old"))
+	live, err := s.Add(AddParams{Name: "live.txt", Bytes: []byte("live"), ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forever, _ := s.Add(AddParams{Name: "forever.txt", Bytes: []byte("forever")})
+
+	n, err := s.SweepExpired(time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 swept, got %d err=%v", n, err)
+	}
+	if st := s.Stats(); st.Files != 2 || st.PhysicalBytes != 4+7 {
+		t.Fatalf("unexpected stats after sweep: %#v", st)
+	}
+
+	// 到期时间之后再清理。
+	n, err = s.SweepExpired(time.Now().Add(2 * time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 swept, got %d err=%v", n, err)
+	}
+	if _, err := s.GetMeta(live.ID); err != ErrNotFound {
+		t.Fatalf("expected live.txt swept, got %v", err)
+	}
+	if _, err := s.GetMeta(forever.ID); err != nil {
+		t.Fatalf("file without expiry swept: %v", err)
+	}
+}
+
+func TestAddRejectsPastExpiry(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := s.Add(AddParams{Name: "a", Bytes: []byte("a"), Now: now, ExpiresAt: now}); err == nil {
+		t.Fatal("expected error for expiry not after now")
+	}
+}
+
+func TestExpiredFilesReclaimedBeforeEviction(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 2, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+	addExpired(t, s, "b", []byte("b"))
+
+	if _, err := s.Add(AddParams{Name: "c", Bytes: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != nil {
+		t.Fatalf("live file evicted while an expired one was available: %v", err)
+	}
+}
+
+func TestSweeperRunsInBackground(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addExpired(t, s, "a", []byte("a"))
+
+	p := NewSweeper(s, SweeperOptions{Interval: 5 * time.Millisecond})
+	defer p.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Stats().Files != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("sweeper did not remove expired file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExpirySurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100}
+	s, err := NewDiskStore(dir, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	a, err := s.Add(AddParams{Name: "a", Bytes: []byte("a"), ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := addExpired(t, s, "b", []byte("b"))
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := NewDiskStore(dir, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = reopened.Close() })
+	meta, err := reopened.GetMeta(a.ID)
+	if err != nil || !meta.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected meta after reopen: %#v err=%v", meta, err)
+	}
+	if _, err := reopened.GetMeta(b.ID); err != ErrNotFound {
+		t.Fatalf("expired file visible after reopen: %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 目录：文件按 FileMeta.Folder 归属到目录，文件名只在同一目录内唯一。
+// 目录路径形如 "a/b"（根目录为 ""），需显式创建（CreateFolder 会补齐上级目录），可以为空；
+// 目录本身不计入文件数与总量，淘汰文件也不会删除目录。
+
+// nameKey 为 byName 的键：同一目录内文件名唯一。
+type nameKey struct {
+	folder string
+	name   string
+}
+
+func (en *entry) nameKey() nameKey {
+	return nameKey{folder: en.meta.Folder, name: en.meta.Name}
+}
+
+// CleanFolder 规范化目录路径：去掉首尾的 "/"，拒绝空段、"." 与 ".."（返回 ErrInvalidInput）。
+func CleanFolder(p string) (string, error) {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return "", nil
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" || seg == "." || seg == ".." || strings.TrimSpace(seg) != seg {
+			return "", fmt.Errorf("%w: bad folder path %q", ErrInvalidInput, p)
+		}
+	}
+	return p, nil
+}
+
+// validFolder 用于装载已有数据：路径须已是规范形式。
+func validFolder(p string) bool {
+	c, err := CleanFolder(p)
+	return err == nil && c == p
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: %w: name is required", ErrInvalidInput, ErrInvalidName)
+	}
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %w: name must not contain '/'", ErrInvalidInput, ErrInvalidName)
+	}
+	return nil
+}
+
+func parentFolder(p string) string {
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		return p[:i]
+	}
+	return ""
+}
+
+// inFolder 报告 p 是否为 root 本身或其下级目录（root 非空）。
+func inFolder(p, root string) bool {
+	return p == root || strings.HasPrefix(p, root+"/")
+}
+
+func (s *engine) folderExistsLocked(p string) bool {
+	if p == "" {
+		return true
+	}
+	_, ok := s.folders[p]
+	return ok
+}
+
+// addFolderLocked 创建目录及缺失的上级目录。
+func (s *engine) addFolderLocked(p string) {
+	for ; p != ""; p = parentFolder(p) {
+		s.folders[p] = struct{}{}
+	}
+}
+
+func (s *engine) folderListLocked() []string {
+	out := make([]string, 0, len(s.folders))
+	for p := range s.folders {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// HasFolder 报告目录是否存在（根目录总是存在）；p 须已经过 CleanFolder。
+func (s *engine) HasFolder(p string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.folderExistsLocked(p)
+}
+
+// Folders 返回全部目录（不含根目录），按路径排序。
+func (s *engine) Folders() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.folderListLocked()
+}
+
+// ListFolder 按上传顺序返回直接位于 folder 下的文件（不含下级目录中的文件）。
+func (s *engine) ListFolder(folder string) ([]FileMeta, error) {
+	folder, err := CleanFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.folderExistsLocked(folder) {
+		return nil, ErrFolderNotFound
+	}
+	now := time.Now()
+	var out []FileMeta
+	for e := s.fifo.Front(); e != nil; e = e.Next() {
+		en := e.Value.(*entry)
+		if en.meta.Folder == folder && !en.meta.Expired(now) {
+			out = append(out, en.meta)
+		}
+	}
+	return out, nil
+}
+
+// CreateFolder 创建目录（缺失的上级目录一并创建）；目录已存在时返回 ErrNameConflict。
+func (s *engine) CreateFolder(path string) error {
+	p, err := CleanFolder(path)
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		return fmt.Errorf("%w: folder path is required", ErrInvalidInput)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.folderExistsLocked(p) {
+		return ErrNameConflict
+	}
+	s.addFolderLocked(p)
+	s.logLocked(journalRecord{Op: opMkdir, Folder: p})
+	return s.commitLocked()
+}
+
+// RenameFolder 把目录（连同下级目录与其中的文件）移动到 to；to 已存在时返回 ErrNameConflict。
+// to 的上级目录不存在时自动创建；不能移动到自身之下。
+func (s *engine) RenameFolder(from, to string) error {
+	from, err := CleanFolder(from)
+	if err != nil {
+		return err
+	}
+	to, err = CleanFolder(to)
+	if err != nil {
+		return err
+	}
+	if from == "" || to == "" {
+		return fmt.Errorf("%w: folder path is required", ErrInvalidInput)
+	}
+	if inFolder(to, from) {
+		if to == from {
+			return nil
+		}
+		return fmt.Errorf("%w: cannot move folder into itself", ErrInvalidInput)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.folderExistsLocked(from) {
+		return ErrFolderNotFound
+	}
+	if s.folderExistsLocked(to) {
+		return ErrNameConflict
+	}
+	s.moveFolderLocked(from, to)
+	s.logLocked(journalRecord{Op: opMoveDir, Folder: from, To: to})
+	return s.commitLocked()
+}
+
+// moveFolderLocked 要求 from 存在、to 不存在：目标子树为空，移动不会产生重名。
+func (s *engine) moveFolderLocked(from, to string) {
+	s.addFolderLocked(parentFolder(to))
+	var moved []string
+	for p := range s.folders {
+		if inFolder(p, from) {
+			moved = append(moved, p)
+		}
+	}
+	for _, p := range moved {
+		delete(s.folders, p)
+		s.folders[to+strings.TrimPrefix(p, from)] = struct{}{}
+	}
+	for _, en := range s.byID {
+		if en.meta.Folder != "" && inFolder(en.meta.Folder, from) {
+			delete(s.byName, en.nameKey())
+			en.meta.Folder = to + strings.TrimPrefix(en.meta.Folder, from)
+			s.byName[en.nameKey()] = en.meta.ID
+			s.publishLocked(en)
+		}
+	}
+}
+
+// DeleteFolder 删除目录。目录（含下级目录）中还有文件或下级目录时，
+// 只有 recursive 为 true 才一并删除（包括置顶文件，启用回收站时移入回收站），否则返回 ErrFolderNotEmpty。返回删除的文件数。
+func (s *engine) DeleteFolder(path string, recursive bool) (int, error) {
+	p, err := CleanFolder(path)
+	if err != nil {
+		return 0, err
+	}
+	if p == "" {
+		return 0, fmt.Errorf("%w: cannot delete root folder", ErrInvalidInput)
+	}
+
+	s.mu.Lock()
+	if !s.folderExistsLocked(p) {
+		s.mu.Unlock()
+		return 0, ErrFolderNotFound
+	}
+	now := time.Now()
+	var victims []*entry
+	empty := true
+	for e := s.fifo.Front(); e != nil; e = e.Next() {
+		en := e.Value.(*entry)
+		if en.meta.Folder != "" && inFolder(en.meta.Folder, p) {
+			victims = append(victims, en)
+			empty = empty && en.meta.Expired(now)
+		}
+	}
+	for f := range s.folders {
+		if f != p && inFolder(f, p) {
+			empty = false
+		}
+	}
+	if !empty && !recursive {
+		s.mu.Unlock()
+		return 0, ErrFolderNotEmpty
+	}
+
+	var (
+		removed []string
+		n       int
+	)
+	for _, en := range victims {
+		if en.meta.Expired(now) {
+			removed = append(removed, s.expireLocked(en)...)
+			continue
+		}
+		removed = append(removed, s.discardLocked(en, opDelete, now)...)
+		n++
+	}
+	s.removeFolderLocked(p)
+	s.logLocked(journalRecord{Op: opRmdir, Folder: p})
+	err = s.commitLocked()
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return n, err
+}
+
+func (s *engine) removeFolderLocked(p string) {
+	for f := range s.folders {
+		if inFolder(f, p) {
+			delete(s.folders, f)
+		}
+	}
+}
+
+// Move 把文件移动到已存在的目录 folder，文件名不变；目标目录中已有同名文件时返回 ErrNameConflict。
+func (s *engine) Move(id, folder string) (FileMeta, error) {
+	folder, err := CleanFolder(folder)
+	if err != nil {
+		return FileMeta{}, err
+	}
+
+	s.mu.Lock()
+	meta, removed, err := s.moveLocked(id, folder)
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return meta, err
+}
+
+func (s *engine) moveLocked(id, folder string) (FileMeta, []string, error) {
+	en, ok := s.liveLocked(id)
+	if !ok {
+		return FileMeta{}, nil, ErrNotFound
+	}
+	if !s.folderExistsLocked(folder) {
+		return FileMeta{}, nil, ErrFolderNotFound
+	}
+	if en.meta.Folder == folder {
+		return en.meta, nil, nil
+	}
+	removed, err := s.takeNameLocked(folder, en.meta.Name)
+	if err != nil {
+		return FileMeta{}, nil, err
+	}
+
+	delete(s.byName, en.nameKey())
+	en.meta.Folder = folder
+	s.byName[en.nameKey()] = id
+	s.publishLocked(en)
+	s.logLocked(journalRecord{Op: opMove, Meta: en.meta})
+	return en.meta, removed, s.commitLocked()
+}
+
+// applyFolderJournalLocked 重放目录相关的记录。
+func (s *engine) applyFolderJournalLocked(rec journalRecord) error {
+	if !validFolder(rec.Folder) || rec.Folder == "" {
+		return fmt.Errorf("bad folder %q", rec.Folder)
+	}
+	switch rec.Op {
+	case opMkdir:
+		s.addFolderLocked(rec.Folder)
+	case opMoveDir:
+		if !validFolder(rec.To) || rec.To == "" || inFolder(rec.To, rec.Folder) {
+			return fmt.Errorf("bad folder %q", rec.To)
+		}
+		if !s.folderExistsLocked(rec.Folder) || s.folderExistsLocked(rec.To) {
+			return fmt.Errorf("cannot move folder %q to %q", rec.Folder, rec.To)
+		}
+		s.moveFolderLocked(rec.Folder, rec.To)
+	case opRmdir:
+		for _, en := range s.byID {
+			if en.meta.Folder != "" && inFolder(en.meta.Folder, rec.Folder) {
+				return fmt.Errorf("folder %q not empty", rec.Folder)
+			}
+		}
+		s.removeFolderLocked(rec.Folder)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newFolderStore(t *testing.T) *InMemoryStore {
+	t.Helper()
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNameUniquePerFolder(t *testing.T) {
+	s := newFolderStore(t)
+	if err := s.CreateFolder("/proj/docs/"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Folders(); !reflect.DeepEqual(got, []string{"proj", "proj/docs"}) {
+		t.Fatalf("unexpected folders: %v", got)
+	}
+
+	root, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := s.Add(AddParams{Name: "a.txt", Folder: "proj/docs", Bytes: []byte("2")})
+	if err != nil {
+		t.Fatalf("same name in another folder should be allowed: %v", err)
+	}
+	if nested.Folder != "proj/docs" || nested.Name != "a.txt" {
+		t.Fatalf("unexpected meta: %#v", nested)
+	}
+	if _, err := s.Add(AddParams{Name: "a.txt", Folder: "proj/docs", Bytes: []byte("3")}); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.txt", Folder: "missing", Bytes: []byte("3")}); err != ErrFolderNotFound {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+	if _, err := s.Add(AddParams{Name: "x/b.txt", Bytes: []byte("3")}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for name with '/', got %v", err)
+	}
+	if err := s.CreateFolder("proj"); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict for existing folder, got %v", err)
+	}
+	for _, bad := range []string{"a//b", "a/../b", "./a", " a"} {
+		if err := s.CreateFolder(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%q: expected ErrInvalidInput, got %v", bad, err)
+		}
+	}
+
+	if !s.HasName("", "a.txt") || !s.HasName("proj/docs", "a.txt") || s.HasName("proj", "a.txt") {
+		t.Fatal("HasName should be scoped per folder")
+	}
+	if _, err := s.Rename(root.ID, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rename(nested.ID, "b.txt"); err != nil {
+		t.Fatalf("rename should only conflict within the folder: %v", err)
+	}
+
+	files, err := s.ListFolder("proj/docs")
+	if err != nil || len(files) != 1 || files[0].ID != nested.ID {
+		t.Fatalf("unexpected folder listing: %#v err=%v", files, err)
+	}
+	if files, _ := s.ListFolder("proj"); len(files) != 0 {
+		t.Fatalf("listing should not include subfolders: %#v", files)
+	}
+	if len(s.List()) != 2 {
+		t.Fatalf("List should include files in all folders")
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	s := newFolderStore(t)
+	if err := s.CreateFolder("x"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("1")})
+	b, _ := s.Add(AddParams{Name: "a.txt", Folder: "x", Bytes: []byte("2")})
+
+	if _, err := s.Move(a.ID, "x"); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Move(a.ID, "y"); err != ErrFolderNotFound {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+	if _, err := s.Move(b.ID, ""); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict moving into root, got %v", err)
+	}
+
+	if _, err := s.Rename(b.ID, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := s.Move(b.ID, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Folder != "" || moved.Name != "b.txt" {
+		t.Fatalf("unexpected meta after move: %#v", moved)
+	}
+	if !s.HasName("", "b.txt") || s.HasName("x", "b.txt") {
+		t.Fatal("name index not updated by move")
+	}
+}
+
+func TestRenameFolderMovesSubtree(t *testing.T) {
+	s := newFolderStore(t)
+	if err := s.CreateFolder("a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateFolder("c"); err != nil {
+		t.Fatal(err)
+	}
+	top, _ := s.Add(AddParams{Name: "1.txt", Folder: "a", Bytes: []byte("1")})
+	deep, _ := s.Add(AddParams{Name: "2.txt", Folder: "a/b", Bytes: []byte("2")})
+	// 前缀相同但不属于 a 的目录不受影响。
+	if err := s.CreateFolder("ab"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RenameFolder("a", "c"); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if err := s.RenameFolder("a", "a/b/z"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput moving into itself, got %v", err)
+	}
+	if err := s.RenameFolder("nope", "z"); err != ErrFolderNotFound {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+
+	if err := s.RenameFolder("a", "x/y"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Folders(); !reflect.DeepEqual(got, []string{"ab", "c", "x", "x/y", "x/y/b"}) {
+		t.Fatalf("unexpected folders: %v", got)
+	}
+	for id, want := range map[string]string{top.ID: "x/y", deep.ID: "x/y/b"} {
+		meta, err := s.GetMeta(id)
+		if err != nil || meta.Folder != want {
+			t.Fatalf("expected folder %q, got %#v err=%v", want, meta, err)
+		}
+	}
+	if !s.HasName("x/y/b", "2.txt") || s.HasName("a/b", "2.txt") {
+		t.Fatal("name index not updated by folder rename")
+	}
+}
+
+func TestDeleteFolder(t *testing.T) {
+	s := newFolderStore(t)
+	if err := s.CreateFolder("a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateFolder("empty"); err != nil {
+		t.Fatal(err)
+	}
+	keep, _ := s.Add(AddParams{Name: "keep.txt", Bytes: []byte("k")})
+	if _, err := s.Add(AddParams{Name: "1.txt", Folder: "a", Bytes: []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+	nested, _ := s.Add(AddParams{Name: "2.txt", Folder: "a/b", Bytes: []byte("22")})
+
+	if _, err := s.DeleteFolder("a", false); err != ErrFolderNotEmpty {
+		t.Fatalf("expected ErrFolderNotEmpty, got %v", err)
+	}
+	if n, err := s.DeleteFolder("empty", false); err != nil || n != 0 {
+		t.Fatalf("delete empty folder: n=%d err=%v", n, err)
+	}
+	if _, err := s.DeleteFolder("", true); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput deleting root, got %v", err)
+	}
+
+	n, err := s.DeleteFolder("a", true)
+	if err != nil || n != 2 {
+		t.Fatalf("recursive delete: n=%d err=%v", n, err)
+	}
+	if got := s.Folders(); len(got) != 0 {
+		t.Fatalf("expected no folders left, got %v", got)
+	}
+	if _, err := s.GetMeta(nested.ID); err != ErrNotFound {
+		t.Fatalf("expected nested file deleted, got %v", err)
+	}
+	if _, err := s.GetMeta(keep.ID); err != nil {
+		t.Fatalf("file outside folder deleted: %v", err)
+	}
+	if st := s.Stats(); st.Files != 1 || st.PhysicalBytes != 1 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+}
+
+func TestFoldersSurvivePersistence(t *testing.T) {
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100}
+	mutate := func(t *testing.T, s FileStore) string {
+		t.Helper()
+		for _, p := range []string{"a/b", "gone", "empty"} {
+			if err := s.CreateFolder(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := s.Add(AddParams{Name: "f.txt", Folder: "a", Bytes: []byte("f")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RenameFolder("a", "z"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Move(f.ID, "z/b"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeleteFolder("gone", false); err != nil {
+			t.Fatal(err)
+		}
+		return f.ID
+	}
+	check := func(t *testing.T, s FileStore, id string) {
+		t.Helper()
+		if got := s.Folders(); !reflect.DeepEqual(got, []string{"empty", "z", "z/b"}) {
+			t.Fatalf("unexpected folders after reload: %v", got)
+		}
+		meta, err := s.GetMeta(id)
+		if err != nil || meta.Folder != "z/b" {
+			t.Fatalf("unexpected meta after reload: %#v err=%v", meta, err)
+		}
+		if !s.HasName("z/b", "f.txt") {
+			t.Fatal("name index not restored")
+		}
+	}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		id := mutate(t, s)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		check(t, reopened, id)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := mutate(t, s)
+		var buf bytes.Buffer
+		if err := s.WriteSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := restored.LoadSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		check(t, restored, id)
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		dir := t.TempDir()
+		s, _ := openJournalStore(t, dir, params)
+		id := mutate(t, s)
+		restored, j := openJournalStore(t, dir, params)
+		defer j.Close()
+		check(t, restored, id)
+	})
+}
@@ -0,0 +1,29 @@
+package store
+
+// IfMatch 为写操作的内容前提条件（对应 HTTP If-Match）：非空时文件当前内容的 SHA256 须为其中之一，
+// 否则返回 ErrPreconditionFailed 且不做任何修改。检查与修改在同一把锁内完成，
+// 读取—修改期间被他人改写的文件不会被静默覆盖。未记录摘要的旧数据不满足任何非空条件。
+type IfMatch []string
+
+// Matches 报告 meta 是否满足条件（空条件总是满足）。
+func (c IfMatch) Matches(meta FileMeta) bool {
+	if len(c) == 0 {
+		return true
+	}
+	if meta.SHA256 == "" {
+		return false
+	}
+	for _, sum := range c {
+		if sum == meta.SHA256 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c IfMatch) check(meta FileMeta) error {
+	if !c.Matches(meta) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
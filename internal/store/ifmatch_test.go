@@ -0,0 +1,51 @@
+package store
+
+import "testing"
+
+func TestIfMatchGuardsWrites(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("v1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SHA256 != contentSum([]byte("v1")) {
+		t.Fatalf("unexpected checksum %q", a.SHA256)
+	}
+	stale := IfMatch{a.SHA256}
+
+	// 另一方先写入新内容，持旧摘要的写操作全部失败且不修改文件。
+	b, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("v2"), IfMatch: stale})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SHA256 == a.SHA256 || b.SHA256 != contentSum([]byte("v2")) {
+		t.Fatalf("checksum not updated: %q", b.SHA256)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("v3"), IfMatch: stale}); err != ErrPreconditionFailed {
+		t.Fatalf("replace: expected ErrPreconditionFailed, got %v", err)
+	}
+	if _, err := s.RenameIf(a.ID, "b.txt", stale); err != ErrPreconditionFailed {
+		t.Fatalf("rename: expected ErrPreconditionFailed, got %v", err)
+	}
+	if _, err := s.DeleteIf(a.ID, stale); err != ErrPreconditionFailed {
+		t.Fatalf("delete: expected ErrPreconditionFailed, got %v", err)
+	}
+	if meta, _ := s.GetMeta(a.ID); meta.Name != "a.txt" || meta.Version != 2 {
+		t.Fatalf("file modified by failed writes: %#v", meta)
+	}
+
+	// 任一摘要匹配即可。
+	cur := IfMatch{a.SHA256, b.SHA256}
+	if _, err := s.RenameIf(a.ID, "b.txt", cur); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeleteIf(a.ID, cur); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeleteIf(a.ID, cur); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// indexRetryInterval 为后台写索引失败后的重试间隔。
+const indexRetryInterval = time.Second
+
+// indexWriter 在写锁之外把元数据索引落盘（DiskStore / S3Store 共用）：提交变更时只唤醒后台协程，
+// 后台在读锁内复制索引、锁外序列化并写入（临时文件 + rename），期间的多次变更合并为一次写入。
+// 不再被引用的内容推迟到反映该变更的索引落盘之后再删除，磁盘上的索引不会引用已删除的内容；
+// 崩溃时最近未落盘的变更丢失，其内容成为孤儿，由启动时的对账清理。
+type indexWriter struct {
+	s    *engine
+	path string
+
+	// mu 串行化写入；lastRev 为已落盘索引对应的修订号。
+	mu      sync.Mutex
+	lastRev uint64
+
+	// pendMu 保护 rev（最近一次提交的修订号）与等待删除的内容。
+	pendMu   sync.Mutex
+	rev      uint64
+	removals []pendingRemoval
+
+	wakeCh   chan struct{}
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// pendingRemoval 为等待删除的内容：修订号 rev 的索引落盘后才可删除。
+type pendingRemoval struct {
+	key string
+	rev uint64
+}
+
+// newIndexWriter 同步写一次当前索引，成功后启动后台写入。
+func newIndexWriter(s *engine, path string) (*indexWriter, error) {
+	w := &indexWriter{
+		s:      s,
+		path:   path,
+		wakeCh: make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}
+	if err := w.flush(true); err != nil {
+		return nil, err
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w, nil
+}
+
+func (w *indexWriter) loop() {
+	defer w.wg.Done()
+
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-w.wakeCh:
+		case <-retry:
+		case <-w.stopCh:
+			return
+		}
+		retry = nil
+		if err := w.Flush(); err != nil {
+			retry = time.After(indexRetryInterval)
+		}
+	}
+}
+
+// markLocked 在持有 engine 写锁、变更提交后调用，只记录修订号并唤醒后台写入。
+func (w *indexWriter) markLocked(rev uint64) {
+	w.pendMu.Lock()
+	w.rev = rev
+	w.pendMu.Unlock()
+	select {
+	case w.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// removeAfterWrite 登记不再被引用的内容，待包含最近一次提交的索引落盘后删除。
+func (w *indexWriter) removeAfterWrite(keys []string) {
+	w.pendMu.Lock()
+	for _, key := range keys {
+		w.removals = append(w.removals, pendingRemoval{key: key, rev: w.rev})
+	}
+	w.pendMu.Unlock()
+	select {
+	case w.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Flush 把当前状态写入索引（自上次写入后没有变更时不写），再删除已可删除的内容。
+// 失败时返回错误，后台稍后重试。
+func (w *indexWriter) Flush() error {
+	return w.flush(false)
+}
+
+func (w *indexWriter) flush(force bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.s
+	s.mu.RLock()
+	rev := s.rev
+	write := force || rev != w.lastRev
+	var idx metaIndex
+	if write {
+		idx = s.indexLocked()
+	}
+	s.mu.RUnlock()
+
+	if write {
+		b, err := json.Marshal(idx)
+		if err == nil {
+			err = writeFileAtomic(w.path, b)
+		}
+		if err != nil {
+			return fmt.Errorf("write index: %w", err)
+		}
+		w.lastRev = rev
+	}
+
+	w.pendMu.Lock()
+	var ready []string
+	kept := w.removals[:0]
+	for _, r := range w.removals {
+		if r.rev <= rev {
+			ready = append(ready, r.key)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	w.removals = kept
+	w.pendMu.Unlock()
+	for _, key := range ready {
+		_ = s.blobs.remove(key)
+	}
+	return nil
+}
+
+// Close 停止后台写入并把最后的变更落盘；之后的变更不再写入索引。
+func (w *indexWriter) Close() error {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	w.wg.Wait()
+	return w.Flush()
+}
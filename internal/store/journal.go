@@ -0,0 +1,744 @@
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 操作日志（write-ahead journal）：InMemoryStore 的每次变更都在返回前追加并 fsync 到当前段文件，
+// 启动时以“最近快照 + 之后的段”重放，得到崩溃一致的状态；压缩时把当前状态写成快照并切换新段。
+//
+// 目录布局：
+//
+//	snapshot-<N>.bin  段 N 之前的完整状态（格式同 WriteSnapshot）
+//	journal-<N>.log   段 N 的变更记录
+//
+// 记录帧（大端）：headerLen u32 | dataLen u64 | crc32c(header+data) u32 | header JSON | data
+const (
+	opAdd      = "add"
+	opRename   = "rename"
+	opMove     = "move"
+	opReplace  = "replace"
+	opRestore  = "restore"
+	opPin      = "pin"
+	opAnnotate = "annotate"
+	opDelete   = "delete"
+	opEvict    = "evict"
+	opExpire   = "expire"
+	opMkdir    = "mkdir"
+	opMoveDir  = "movedir"
+	opRmdir    = "rmdir"
+	opUntrash  = "untrash"
+	opPurge    = "purge"
+
+	journalSegmentPrefix  = "journal-"
+	journalSegmentSuffix  = ".log"
+	journalSnapshotPrefix = "snapshot-"
+	journalSnapshotSuffix = ".bin"
+
+	journalFrameBytes     = 4 + 8 + 4
+	maxJournalHeaderBytes = 64 * 1024
+)
+
+var journalCRC = crc32.MakeTable(crc32.Castagnoli)
+
+type journalRecord struct {
+	Op   string   `json:"op"`
+	Meta FileMeta `json:"meta"`
+	// Versions 为 replace/restore 之后保留的历史版本号（按先后顺序）。
+	Versions []int `json:"versions,omitempty"`
+	// Folder/To 用于目录记录（mkdir/movedir/rmdir），这类记录的 Meta 为空。
+	Folder string `json:"folder,omitempty"`
+	To     string `json:"to,omitempty"`
+	// Trashed 非 nil 表示 delete/evict 的文件移入了回收站，值为删除时间。
+	Trashed *time.Time `json:"trashed,omitempty"`
+	Data    []byte     `json:"-"`
+}
+
+type JournalOptions struct {
+	Dir string
+	// CompactInterval 为周期压缩间隔（<=0 不做周期压缩）。
+	CompactInterval time.Duration
+	// CompactBytes 为当前段超过该大小时触发压缩（<=0 不按大小触发）。
+	CompactBytes int64
+	// OnError 接收后台压缩失败的错误（可为 nil）。
+	OnError func(error)
+}
+
+type Journal struct {
+	store        *InMemoryStore
+	dir          string
+	compactBytes int64
+	onError      func(error)
+
+	compactMu sync.Mutex
+
+	// 以下字段只在持有 store 写锁时读写。
+	seq  uint64
+	f    *os.File
+	size int64
+	// failed 表示上次追加失败后未能回退段文件或回滚内存状态，见 abortLocked。
+	failed bool
+
+	compactCh chan struct{}
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// OpenJournal 从 dir 恢复 s（要求 s 为空），随后把 s 的每次变更写入操作日志。
+// 恢复完成后会立即压缩一次，使下次启动只需加载一个快照。
+func OpenJournal(s *InMemoryStore, opt JournalOptions) (*Journal, error) {
+	if strings.TrimSpace(opt.Dir) == "" {
+		return nil, fmt.Errorf("%w: journal dir is required", ErrInvalidInput)
+	}
+	if err := os.MkdirAll(opt.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create journal dir: %w", err)
+	}
+	if s.Stats().Files != 0 {
+		return nil, fmt.Errorf("%w: store is not empty", ErrInvalidInput)
+	}
+
+	j := &Journal{
+		store:        s,
+		dir:          opt.Dir,
+		compactBytes: opt.CompactBytes,
+		onError:      opt.OnError,
+		compactCh:    make(chan struct{}, 1),
+		stopCh:       make(chan struct{}),
+	}
+	if err := j.recover(); err != nil {
+		return nil, err
+	}
+	if err := j.compact(true); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.journal = j.appendLocked
+	s.mu.Unlock()
+
+	if opt.CompactInterval > 0 || opt.CompactBytes > 0 {
+		j.wg.Add(1)
+		go j.loop(opt.CompactInterval)
+	}
+	return j, nil
+}
+
+func (j *Journal) loop(interval time.Duration) {
+	defer j.wg.Done()
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+		case <-j.compactCh:
+		case <-j.stopCh:
+			return
+		}
+		if err := j.Compact(); err != nil && j.onError != nil {
+			j.onError(err)
+		}
+	}
+}
+
+// Close 停止后台压缩并关闭当前段；之后 store 的变更不再写日志。
+func (j *Journal) Close() error {
+	j.stopOnce.Do(func() { close(j.stopCh) })
+	j.wg.Wait()
+
+	s := j.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = nil
+	if j.f == nil {
+		return nil
+	}
+	err := j.f.Close()
+	j.f = nil
+	return err
+}
+
+// Compact 把当前状态写成快照并切换到新段，然后删除旧快照与旧段。当前段为空时不做任何事。
+func (j *Journal) Compact() error {
+	return j.compact(false)
+}
+
+func (j *Journal) compact(force bool) error {
+	j.compactMu.Lock()
+	defer j.compactMu.Unlock()
+
+	s := j.store
+	s.mu.Lock()
+	if !force && j.size == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	// 锁内只复制元数据、切换新段；内容在锁外读取，压缩期间不阻塞写入。
+	snap := s.snapshotLocked()
+	err := j.rotateLocked()
+	seq := j.seq
+	s.mu.Unlock()
+	defer s.unhold(snap.held)
+	if err != nil {
+		return err
+	}
+
+	// 快照写完之前崩溃：旧快照 + 全部段仍可完整恢复。
+	err = writeFileAtomicFunc(j.snapshotPath(seq), func(w io.Writer) error {
+		return s.writeSnapshot(w, snap)
+	})
+	if err != nil {
+		return fmt.Errorf("write journal snapshot: %w", err)
+	}
+	return j.removeBefore(seq)
+}
+
+func (j *Journal) rotateLocked() error {
+	next := j.seq + 1
+	f, err := os.OpenFile(j.segmentPath(next), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open journal segment: %w", err)
+	}
+	if j.f != nil {
+		_ = j.f.Close()
+	}
+	j.f, j.seq, j.size = f, next, 0
+	return nil
+}
+
+func (j *Journal) appendLocked(recs []journalRecord) error {
+	if j.f == nil {
+		return errors.New("journal closed")
+	}
+	if j.failed {
+		// 内存中可能仍有未写入日志的变更（连同本次）：先重试回滚，本次变更一律失败。
+		return j.abortLocked(errJournalFailed)
+	}
+
+	var written int64
+	for _, rec := range recs {
+		n, err := writeJournalRecord(j.f, rec)
+		written += n
+		if err != nil {
+			return j.abortLocked(err)
+		}
+	}
+	if err := j.f.Sync(); err != nil {
+		return j.abortLocked(err)
+	}
+	j.size += written
+
+	if j.compactBytes > 0 && j.size >= j.compactBytes {
+		select {
+		case j.compactCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+var errJournalFailed = errors.New("journal unavailable after a failed append")
+
+// abortLocked 处理追加失败：变更在追加前已经生效，这里回退半截记录（保证段内只包含完整记录），
+// 再按日志重建内存状态，撤销没有写入日志的变更。任一步失败时进入 failed 状态，下次变更时重试。
+func (j *Journal) abortLocked(cause error) error {
+	terr := j.f.Truncate(j.size)
+	rerr := j.rollbackLocked()
+	if terr != nil || rerr != nil {
+		j.failed = true
+		return fmt.Errorf("%w (truncate: %v, roll back: %v)", cause, terr, rerr)
+	}
+	j.failed = false
+	return cause
+}
+
+// rollbackLocked 从日志目录重新装载状态并替换 store 的当前状态。并发压缩可能在装载途中
+// 删除旧快照与旧段，遇到文件不存在时重新扫描。
+func (j *Journal) rollbackLocked() error {
+	s := j.store.engine
+	for attempt := 1; ; attempt++ {
+		fresh, err := newEngine(s.params, s.blobs)
+		if err != nil {
+			return err
+		}
+		if _, err = j.load(fresh); err == nil {
+			s.replaceStateLocked(fresh)
+			return nil
+		}
+		s.removeBlobs(slices.Collect(maps.Keys(fresh.blobRefs)))
+		if !errors.Is(err, fs.ErrNotExist) || attempt == 3 {
+			return err
+		}
+	}
+}
+
+func writeJournalRecord(w io.Writer, rec journalRecord) (int64, error) {
+	hb, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	var frame [journalFrameBytes]byte
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(hb)))
+	binary.BigEndian.PutUint64(frame[4:12], uint64(len(rec.Data)))
+	crc := crc32.Update(crc32.Checksum(hb, journalCRC), journalCRC, rec.Data)
+	binary.BigEndian.PutUint32(frame[12:16], crc)
+
+	var total int64
+	for _, part := range [][]byte{frame[:], hb, rec.Data} {
+		n, err := w.Write(part)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+var errJournalTorn = errors.New("torn record")
+
+// readJournalRecord 读取一条记录；干净的段尾返回 io.EOF，半截记录返回 errJournalTorn。
+func readJournalRecord(r *bufio.Reader) (journalRecord, int64, error) {
+	var frame [journalFrameBytes]byte
+	if _, err := io.ReadFull(r, frame[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return journalRecord{}, 0, errJournalTorn
+		}
+		return journalRecord{}, 0, err
+	}
+	headerLen := binary.BigEndian.Uint32(frame[0:4])
+	dataLen := binary.BigEndian.Uint64(frame[4:12])
+	if headerLen == 0 || headerLen > maxJournalHeaderBytes {
+		return journalRecord{}, 0, fmt.Errorf("bad header length %d", headerLen)
+	}
+
+	hb, err := readExactly(r, int64(headerLen))
+	if err != nil {
+		return journalRecord{}, 0, tornOr(err)
+	}
+	data, err := readExactly(r, int64(dataLen))
+	if err != nil {
+		return journalRecord{}, 0, tornOr(err)
+	}
+	crc := crc32.Update(crc32.Checksum(hb, journalCRC), journalCRC, data)
+	if crc != binary.BigEndian.Uint32(frame[12:16]) {
+		if _, err := r.Peek(1); errors.Is(err, io.EOF) {
+			return journalRecord{}, 0, errJournalTorn
+		}
+		return journalRecord{}, 0, errors.New("checksum mismatch")
+	}
+
+	var rec journalRecord
+	if err := json.Unmarshal(hb, &rec); err != nil {
+		return journalRecord{}, 0, err
+	}
+	rec.Data = data
+	return rec, journalFrameBytes + int64(headerLen) + int64(dataLen), nil
+}
+
+func tornOr(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errJournalTorn
+	}
+	return err
+}
+
+// recover 加载最近快照并按顺序重放其后的段。只有最后一段允许出现半截记录（崩溃于追加途中），
+// 会被截断；其他任何不一致都视为损坏，拒绝启动。
+func (j *Journal) recover() error {
+	s := j.store
+	seq, err := j.load(s.engine)
+	if err != nil {
+		return err
+	}
+	j.seq = seq
+
+	s.mu.Lock()
+	dropped := s.fitLimitsLocked()
+	purged, _ := s.sweepTrashLocked(time.Now())
+	s.mu.Unlock()
+	s.removeBlobs(append(dropped, purged...))
+	return nil
+}
+
+// load 把日志目录中的状态装载到空的 target，返回最后一段的序号。
+func (j *Journal) load(target *engine) (uint64, error) {
+	snapshots, segments, err := j.scan()
+	if err != nil {
+		return 0, err
+	}
+
+	var base uint64
+	if len(snapshots) > 0 {
+		base = snapshots[len(snapshots)-1]
+		f, err := os.Open(j.snapshotPath(base))
+		if err != nil {
+			return 0, err
+		}
+		snap, err := readSnapshot(f)
+		_ = f.Close()
+		if err != nil {
+			return 0, fmt.Errorf("journal snapshot %d: %w", base, err)
+		}
+		target.mu.Lock()
+		err = target.loadSnapshotLocked(snap)
+		target.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var replay []uint64
+	for _, seq := range segments {
+		if seq >= base {
+			replay = append(replay, seq)
+		}
+	}
+	last := base
+	for i, seq := range replay {
+		if err := j.replaySegment(target, seq, i == len(replay)-1); err != nil {
+			return 0, err
+		}
+		last = seq
+	}
+	return last, nil
+}
+
+func (j *Journal) replaySegment(s *engine, seq uint64, last bool) error {
+	path := j.segmentPath(seq)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var off int64
+	for {
+		rec, n, err := readJournalRecord(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if errors.Is(err, errJournalTorn) && last {
+			_ = f.Close()
+			return os.Truncate(path, off)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s at offset %d: %v", ErrJournalCorrupt, filepath.Base(path), off, err)
+		}
+
+		s.mu.Lock()
+		err = s.applyJournalLocked(rec)
+		s.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("%w: %s at offset %d: %v", ErrJournalCorrupt, filepath.Base(path), off, err)
+		}
+		off += n
+	}
+}
+
+// applyJournalLocked 按记录原样重放，不触发淘汰（淘汰本身也有记录）。
+func (s *engine) applyJournalLocked(rec journalRecord) error {
+	switch rec.Op {
+	case opMkdir, opMoveDir, opRmdir:
+		if err := s.applyFolderJournalLocked(rec); err != nil {
+			return err
+		}
+		s.rev++
+		return nil
+	}
+
+	m := rec.Meta
+	if m.ID == "" {
+		return errors.New("record without id")
+	}
+	en, exists := s.byID[m.ID]
+	te, trashed := s.trash[m.ID]
+
+	switch rec.Op {
+	case opAdd:
+		if exists {
+			return fmt.Errorf("duplicate id %s", m.ID)
+		}
+		if _, taken := s.byName[nameKey{folder: m.Folder, name: m.Name}]; taken || m.Name == "" || !validFolder(m.Folder) {
+			return fmt.Errorf("name %q unavailable", m.Name)
+		}
+		if int64(len(rec.Data)) != m.SizeBytes {
+			return fmt.Errorf("size mismatch for %s", m.ID)
+		}
+		key, sum, err := s.storeBlobLocked(m.SHA256, rec.Data)
+		if err != nil {
+			return err
+		}
+		m.SHA256 = sum
+		s.insertLocked(&entry{meta: m, blobKey: key})
+
+	case opRename, opMove:
+		if !exists {
+			return fmt.Errorf("unknown id %s", m.ID)
+		}
+		key := nameKey{folder: m.Folder, name: m.Name}
+		if owner, taken := s.byName[key]; (taken && owner != m.ID) || m.Name == "" || !validFolder(m.Folder) {
+			return fmt.Errorf("name %q unavailable", m.Name)
+		}
+		delete(s.byName, en.nameKey())
+		en.meta.Name, en.meta.Folder = m.Name, m.Folder
+		s.byName[key] = m.ID
+		s.addFolderLocked(m.Folder)
+		s.publishLocked(en)
+
+	case opReplace, opRestore:
+		if !exists {
+			return fmt.Errorf("unknown id %s", m.ID)
+		}
+		if err := s.applyVersionsLocked(en, rec); err != nil {
+			return err
+		}
+
+	case opPin:
+		if !exists {
+			return fmt.Errorf("unknown id %s", m.ID)
+		}
+		if en.meta.Pinned != m.Pinned {
+			s.setPinnedLocked(en, m.Pinned)
+		}
+
+	case opAnnotate:
+		if !exists {
+			return fmt.Errorf("unknown id %s", m.ID)
+		}
+		en.meta.Tags, en.meta.Note = m.Tags, m.Note
+		s.publishLocked(en)
+
+	case opDelete, opEvict, opExpire:
+		if !exists {
+			return fmt.Errorf("unknown id %s", m.ID)
+		}
+		if rec.Trashed != nil && rec.Op != opExpire {
+			s.moveToTrashLocked(en, rec.Op == opEvict, *rec.Trashed)
+			break
+		}
+		s.removeBlobs(s.deleteLocked(en))
+
+	case opUntrash:
+		if !trashed {
+			return fmt.Errorf("unknown trashed id %s", m.ID)
+		}
+		if _, taken := s.byName[te.en.nameKey()]; taken {
+			return fmt.Errorf("name %q unavailable", te.en.meta.Name)
+		}
+		s.unlinkTrashLocked(te)
+		te.en.meta.Pinned = m.Pinned
+		s.untrashLocked(te.en)
+
+	case opPurge:
+		if !trashed {
+			return fmt.Errorf("unknown trashed id %s", m.ID)
+		}
+		s.removeBlobs(s.purgeLocked(te))
+
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	s.rev++
+	return nil
+}
+
+// replaceStateLocked 换用 fresh（按日志重建）的条目、目录、回收站与内容引用，并重新发布读索引。
+// 旧 blob 中不属于任何条目的引用（进行中上传、复制的临时引用）与临时保留连同该 blob 一并转入，
+// 持有者之后仍按原 key 释放；其余旧 blob 被回收。上传预留与淘汰记录不变。
+func (s *engine) replaceStateLocked(fresh *engine) {
+	owned := make(map[string]int)
+	for _, en := range s.byID {
+		for _, key := range en.blobKeys() {
+			owned[key]++
+		}
+	}
+	stale := make([]*entry, 0, len(s.byID))
+	for id, en := range s.byID {
+		if _, ok := fresh.byID[id]; !ok {
+			stale = append(stale, en)
+		}
+	}
+	old := s.blobRefs
+
+	s.byID, s.byName, s.folders = fresh.byID, fresh.byName, fresh.folders
+	s.fifo, s.policy, s.seq = fresh.fifo, fresh.policy, fresh.seq
+	s.blobRefs, s.bySum = fresh.blobRefs, fresh.bySum
+	s.totalBytes, s.rawBytes, s.logicalBytes = fresh.totalBytes, fresh.rawBytes, fresh.logicalBytes
+	s.pinnedBytes, s.pinnedFiles = fresh.pinnedBytes, fresh.pinnedFiles
+	s.trash, s.trashList, s.trashBytes = fresh.trash, fresh.trashList, fresh.trashBytes
+	s.pending = nil
+
+	var removed []string
+	for key, r := range old {
+		temp := r.refs - owned[key]
+		if temp == 0 && r.holds == 0 {
+			removed = append(removed, key)
+			continue
+		}
+		nr := s.addBlobLocked(key, r.sum, r.size, r.raw)
+		for range temp {
+			s.retainBlobLocked(nr)
+		}
+		nr.holds = r.holds
+	}
+
+	// 先发布新状态再撤下已不存在的条目，读路径不会看到中间状态。
+	for _, en := range s.byID {
+		s.publishLocked(en)
+	}
+	for _, en := range stale {
+		s.unpublishLocked(en)
+	}
+	s.removeBlobs(removed)
+}
+
+// applyVersionsLocked 按记录重建当前版本与历史版本：replace 以记录中的内容作为新的当前版本，
+// restore 把指定历史版本设为当前版本；不在 rec.Versions 中的旧版本被回收。
+func (s *engine) applyVersionsLocked(en *entry, rec journalRecord) error {
+	m := rec.Meta
+	pool := make(map[int]version, len(en.versions)+1)
+	for _, v := range append(en.versions, en.currentVersion()) {
+		pool[v.Version] = v
+	}
+
+	var cur version
+	switch rec.Op {
+	case opReplace:
+		if int64(len(rec.Data)) != m.SizeBytes {
+			return fmt.Errorf("size mismatch for %s", m.ID)
+		}
+		if m.Version <= 0 {
+			// 引入版本历史之前的记录：直接覆盖，不保留旧内容。
+			m.Version = en.nextVersion()
+		}
+		if _, dup := pool[m.Version]; dup {
+			return fmt.Errorf("duplicate version %d for %s", m.Version, m.ID)
+		}
+		key, sum, err := s.storeBlobLocked(m.SHA256, rec.Data)
+		if err != nil {
+			return err
+		}
+		s.retainLocked(key)
+		cur = version{
+			VersionMeta: VersionMeta{
+				Version:   m.Version,
+				SizeBytes: m.SizeBytes,
+				Encoding:  m.Encoding,
+				IsText:    m.IsText,
+				CreatedAt: m.UpdatedAt,
+				SHA256:    sum,
+			},
+			blobKey: key,
+		}
+	default:
+		v, ok := pool[m.Version]
+		if !ok {
+			return fmt.Errorf("unknown version %d for %s", m.Version, m.ID)
+		}
+		cur = v
+		delete(pool, m.Version)
+	}
+
+	kept := make([]version, 0, len(rec.Versions))
+	for _, n := range rec.Versions {
+		v, ok := pool[n]
+		if !ok {
+			return fmt.Errorf("unknown version %d for %s", n, m.ID)
+		}
+		kept = append(kept, v)
+		delete(pool, n)
+	}
+	for _, v := range pool {
+		s.removeBlobs(s.releaseLocked(v.blobKey))
+	}
+
+	s.unaccountLocked(en)
+	en.versions = kept
+	en.setCurrent(cur)
+	s.accountLocked(en)
+	if !en.meta.Pinned {
+		s.policy.update(en)
+	}
+	s.publishLocked(en)
+	return nil
+}
+
+func (j *Journal) scan() (snapshots, segments []uint64, err error) {
+	items, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read journal dir: %w", err)
+	}
+	for _, it := range items {
+		if it.IsDir() {
+			continue
+		}
+		if seq, ok := parseSeq(it.Name(), journalSnapshotPrefix, journalSnapshotSuffix); ok {
+			snapshots = append(snapshots, seq)
+		}
+		if seq, ok := parseSeq(it.Name(), journalSegmentPrefix, journalSegmentSuffix); ok {
+			segments = append(segments, seq)
+		}
+	}
+	sort.Slice(snapshots, func(a, b int) bool { return snapshots[a] < snapshots[b] })
+	sort.Slice(segments, func(a, b int) bool { return segments[a] < segments[b] })
+	return snapshots, segments, nil
+}
+
+func (j *Journal) removeBefore(seq uint64) error {
+	snapshots, segments, err := j.scan()
+	if err != nil {
+		return err
+	}
+	for _, n := range snapshots {
+		if n < seq {
+			_ = os.Remove(j.snapshotPath(n))
+		}
+	}
+	for _, n := range segments {
+		if n < seq {
+			_ = os.Remove(j.segmentPath(n))
+		}
+	}
+	return nil
+}
+
+func (j *Journal) segmentPath(seq uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%s%016d%s", journalSegmentPrefix, seq, journalSegmentSuffix))
+}
+
+func (j *Journal) snapshotPath(seq uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%s%016d%s", journalSnapshotPrefix, seq, journalSnapshotSuffix))
+}
+
+func parseSeq(name, prefix, suffix string) (uint64, bool) {
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
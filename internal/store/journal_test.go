@@ -0,0 +1,245 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func openJournalStore(t *testing.T, dir string, p NewParams) (*InMemoryStore, *Journal) {
+	t.Helper()
+	s, err := NewInMemoryStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := OpenJournal(s, JournalOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	return s, j
+}
+
+func TestJournalReplaysMutationsAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 2, MaxTotalBytes: 1000}
+	s, _ := openJournalStore(t, dir, params)
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("a"), Now: time.Unix(1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("b"), Now: time.Unix(2, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rename(b.ID, "B.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: b.ID, Bytes: []byte("bbb"), Encoding: "GBK", IsText: true}); err != nil {
+		t.Fatal(err)
+	}
+	// 触发 FIFO 淘汰 a。
+	c, err := s.Add(AddParams{Name: "c.txt", Bytes: []byte("c"), Now: time.Unix(3, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 不调用 Close，模拟进程崩溃。
+	restored, j := openJournalStore(t, dir, params)
+	defer j.Close()
+
+	if _, err := restored.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("expected evicted a to stay evicted, got %v", err)
+	}
+	items := restored.List()
+	if len(items) != 2 || items[0].ID != b.ID || items[1].ID != c.ID {
+		t.Fatalf("unexpected items: %#v", items)
+	}
+	got, err := restored.Get(b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Meta.Name != "B.txt" || got.Meta.Encoding != "GBK" || string(got.Bytes) != "bbb" {
+		t.Fatalf("unexpected b after replay: %#v %q", got.Meta, got.Bytes)
+	}
+	if st := restored.Stats(); st.Files != 2 || st.PhysicalBytes != 4 {
+		t.Fatalf("unexpected stats: files=%d total=%d", st.Files, st.PhysicalBytes)
+	}
+}
+
+func TestJournalTornTailIsTruncated(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1000}
+	s, j := openJournalStore(t, dir, params)
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("world")}); err != nil {
+		t.Fatal(err)
+	}
+	seg := j.segmentPath(j.seq)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 截掉最后一条记录的末尾，模拟追加途中崩溃。
+	st, err := os.Stat(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(seg, st.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	items := restored.List()
+	if len(items) != 1 || items[0].ID != a.ID {
+		t.Fatalf("expected only complete records replayed, got %#v", items)
+	}
+}
+
+func TestJournalCorruptRecordBeforeTailRejected(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1000}
+	s, j := openJournalStore(t, dir, params)
+
+	if _, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("world")}); err != nil {
+		t.Fatal(err)
+	}
+	seg := j.segmentPath(j.seq)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[journalFrameBytes+2] ^= 0xFF // 损坏第一条记录（其后仍有完整记录）
+	if err := os.WriteFile(seg, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewInMemoryStore(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenJournal(restored, JournalOptions{Dir: dir}); !errors.Is(err, ErrJournalCorrupt) {
+		t.Fatalf("expected ErrJournalCorrupt, got %v", err)
+	}
+}
+
+func TestJournalCompactRemovesOldSegments(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1000}
+	s, j := openJournalStore(t, dir, params)
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if _, err := s.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("world")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, segments, err := j.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || len(segments) != 1 || snapshots[0] != segments[0] {
+		t.Fatalf("expected one snapshot + one segment, got %v %v", snapshots, segments)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	items := restored.List()
+	if len(items) != 1 || items[0].ID != b.ID {
+		t.Fatalf("unexpected items after compact + replay: %#v", items)
+	}
+}
+
+func TestJournalAppendFailureRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 1000}
+	s, j := openJournalStore(t, dir, params)
+
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := s.Stats()
+
+	// 换成只读句柄：写入与截断都会失败。
+	s.mu.Lock()
+	good := j.f
+	ro, err := os.Open(j.segmentPath(j.seq))
+	if err != nil {
+		s.mu.Unlock()
+		t.Fatal(err)
+	}
+	j.f = ro
+	s.mu.Unlock()
+	defer ro.Close()
+
+	if _, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("world")}); err == nil {
+		t.Fatal("expected add to fail")
+	}
+	if _, err := s.Delete(a.ID); err == nil {
+		t.Fatal("expected delete to fail")
+	}
+	if s.HasName("", "b.txt") || len(s.List()) != 1 {
+		t.Fatalf("failed mutations still visible: %#v", s.List())
+	}
+	got, err := s.Get(a.ID)
+	if err != nil || string(got.Bytes) != "hello" {
+		t.Fatalf("a after rollback: %v %q", err, got.Bytes)
+	}
+	if st := s.Stats(); st != want {
+		t.Fatalf("stats after rollback: %+v, want %+v", st, want)
+	}
+	if m := s.blobs.(*memBlobs); len(m.data) != len(s.blobRefs) {
+		t.Fatalf("rollback left %d blobs for %d refs", len(m.data), len(s.blobRefs))
+	}
+
+	// 日志恢复后，第一次变更先完成重试并失败，之后正常写入。
+	s.mu.Lock()
+	j.f = good
+	s.mu.Unlock()
+	if _, err := s.Add(AddParams{Name: "c.txt", Bytes: []byte("c")}); !errors.Is(err, errJournalFailed) {
+		t.Fatalf("expected errJournalFailed, got %v", err)
+	}
+	d, err := s.Add(AddParams{Name: "d.txt", Bytes: []byte("d")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	items := restored.List()
+	if len(items) != 2 || items[0].ID != a.ID || items[1].ID != d.ID {
+		t.Fatalf("unexpected items after restart: %#v", items)
+	}
+	if st, cur := restored.Stats(), s.Stats(); st != cur {
+		t.Fatalf("restored stats %+v differ from live %+v", st, cur)
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NamePolicy 为文件名规则，在上传、改名、复制时检查（装载已有数据时不检查）。
+// 零值只做基本检查：非空、不是 . 或 ..、不含 '/' 与控制字符、首尾不是空白（下载时首尾空白会被去掉）。
+type NamePolicy struct {
+	// MaxLength 为文件名的最大长度（UTF-8 字节），0 表示不限制。
+	MaxLength int
+	// ForbiddenChars 为额外不允许出现在文件名中的字符，例如 `\:*?"<>|`。
+	ForbiddenChars string
+	// NormalizeNFC 为 true 时先把文件名转换为 Unicode NFC 形式再检查和保存，
+	// 避免同一名称因组合方式不同（如 macOS 上传的 NFD 名称）而被当作不同文件。
+	NormalizeNFC bool
+	// RejectReserved 为 true 时拒绝 Windows 保留名（CON、aux.txt、LPT1 等，不区分大小写）
+	// 以及以点或空格结尾的名称，这些名称在 Windows 上无法按原名保存。
+	RejectReserved bool
+}
+
+func (p NamePolicy) validate() error {
+	if p.MaxLength < 0 {
+		return fmt.Errorf("%w: name max length must be >= 0", ErrInvalidInput)
+	}
+	return nil
+}
+
+// CleanName 按 NamePolicy 规范化并检查新文件名，返回实际保存的名称。
+// Add、RenameWith、Copy 内部都会调用；上传在预留容量、读取内容之前先调用它，尽早拒绝不合法的名称。
+func (s *engine) CleanName(name string) (string, error) {
+	if s.names.NormalizeNFC {
+		name = norm.NFC.String(name)
+	}
+	return name, s.names.check(name)
+}
+
+func (p NamePolicy) check(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%w: %w: not valid UTF-8", ErrInvalidInput, ErrInvalidName)
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("%w: %w: %q", ErrInvalidInput, ErrInvalidName, name)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("%w: %w: leading or trailing space", ErrInvalidInput, ErrInvalidName)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: %w: control character %U", ErrInvalidInput, ErrInvalidName, r)
+		}
+		if strings.ContainsRune(p.ForbiddenChars, r) {
+			return fmt.Errorf("%w: %w: %q", ErrInvalidInput, ErrNameForbiddenChar, r)
+		}
+	}
+	if p.MaxLength > 0 && len(name) > p.MaxLength {
+		return fmt.Errorf("%w: %w: %d bytes, max %d", ErrInvalidInput, ErrNameTooLong, len(name), p.MaxLength)
+	}
+	if p.RejectReserved {
+		if strings.HasSuffix(name, ".") {
+			return fmt.Errorf("%w: %w: trailing dot", ErrInvalidInput, ErrNameReserved)
+		}
+		if isWindowsReserved(name) {
+			return fmt.Errorf("%w: %w: %q", ErrInvalidInput, ErrNameReserved, name)
+		}
+	}
+	return nil
+}
+
+// isWindowsReserved 报告 name 的主名（第一个点之前，去掉尾部空格）是否为 Windows 设备名。
+func isWindowsReserved(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	base = strings.ToUpper(strings.TrimRight(base, " "))
+	switch base {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(base) == 4 && (strings.HasPrefix(base, "COM") || strings.HasPrefix(base, "LPT")) {
+		return base[3] >= '1' && base[3] <= '9'
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNamePolicyCheck(t *testing.T) {
+	p := NamePolicy{MaxLength: 16, ForbiddenChars: `\:*?"<>|`, RejectReserved: true}
+	cases := []struct {
+		name string
+		want error
+	}{
+		{"report.csv", nil},
+		{"中文名.txt", nil},
+		{"CONSOLE.txt", nil},
+		{"", ErrInvalidName},
+		{"..", ErrInvalidName},
+		{"a/b", ErrInvalidName},
+		{"a\tb", ErrInvalidName},
+		{"a\x7fb", ErrInvalidName},
+		{" a.txt", ErrInvalidName},
+		{"a.txt ", ErrInvalidName},
+		{"a:b", ErrNameForbiddenChar},
+		{"what?.txt", ErrNameForbiddenChar},
+		{strings.Repeat("长", 6), ErrNameTooLong},
+		{"CON", ErrNameReserved},
+		{"aux.txt", ErrNameReserved},
+		{"Lpt9.tar.gz", ErrNameReserved},
+		{"notes.", ErrNameReserved},
+	}
+	for _, c := range cases {
+		err := p.check(c.name)
+		if c.want == nil {
+			if err != nil {
+				t.Errorf("check(%q) = %v, want nil", c.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.want) || !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("check(%q) = %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestNamePolicyNormalizesNFC(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, NamePolicy: NamePolicy{NormalizeNFC: true, RejectReserved: true}})
+	const nfc, nfd = "caf\u00e9.txt", "cafe\u0301.txt"
+
+	meta, err := s.Add(AddParams{Name: nfd, Bytes: []byte("a")})
+	if err != nil || meta.Name != nfc {
+		t.Fatalf("expected NFC name, got %q err=%v", meta.Name, err)
+	}
+	// 组合方式不同的同一名称视为重名。
+	if _, err := s.Add(AddParams{Name: nfc, Bytes: []byte("b")}); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	other, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("b")})
+	if _, err := s.Rename(other.ID, "nul.txt"); !errors.Is(err, ErrNameReserved) {
+		t.Fatalf("expected ErrNameReserved, got %v", err)
+	}
+	if _, err := s.Copy(other.ID, "x\n.txt"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+	if got, _ := s.GetMeta(other.ID); got.Name != "b.txt" {
+		t.Fatalf("name changed after rejected rename: %q", got.Name)
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+// SetPinned 置顶或取消置顶文件。置顶文件不参与自动淘汰（仍可手动删除）。
+// 为保证总能为新上传腾出空间，置顶文件（含历史版本）的总量不得超过 MaxPinnedBytes，
+// 且至少留出一个非置顶的文件名额；超出时返回 ErrPinLimit。
+func (s *engine) SetPinned(id string, pinned bool) (FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	en, ok := s.liveLocked(id)
+	if !ok {
+		return FileMeta{}, ErrNotFound
+	}
+	if en.meta.Pinned == pinned {
+		return en.meta, nil
+	}
+	if pinned && (s.pinnedBytes+en.bytes() > s.maxPinned || s.pinnedFiles+1 >= s.maxFiles) {
+		return FileMeta{}, ErrPinLimit
+	}
+
+	s.setPinnedLocked(en, pinned)
+	s.logLocked(journalRecord{Op: opPin, Meta: en.meta})
+	return en.meta, s.commitLocked()
+}
+
+// setPinnedLocked 切换置顶状态，同步用量统计与淘汰策略；不检查上限（重放日志时也使用）。
+func (s *engine) setPinnedLocked(en *entry, pinned bool) {
+	s.unaccountLocked(en)
+	if pinned {
+		s.policy.remove(en)
+	}
+	en.meta.Pinned = pinned
+	if !pinned {
+		s.policy.add(en)
+	}
+	s.accountLocked(en)
+	s.publishLocked(en)
+}
+
+// accountLocked/unaccountLocked 在条目加入/移除、大小或置顶状态变化前后成对调用，维护逻辑用量与置顶用量。
+func (s *engine) accountLocked(en *entry) {
+	s.logicalBytes += en.bytes()
+	if en.meta.Pinned {
+		s.pinnedBytes += en.bytes()
+		s.pinnedFiles++
+	}
+}
+
+func (s *engine) unaccountLocked(en *entry) {
+	s.logicalBytes -= en.bytes()
+	if en.meta.Pinned {
+		s.pinnedBytes -= en.bytes()
+		s.pinnedFiles--
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import "testing"
+
+func TestPinnedFilesSkipEviction(t *testing.T) {
+	for _, policy := range []string{EvictionFIFO, EvictionLRU, EvictionLFU, EvictionLargest} {
+		t.Run(policy, func(t *testing.T) {
+			s, err := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100, MaxPinnedBytes: 50, EvictionPolicy: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("aaaaaaaa")})
+			if _, err := s.SetPinned(a.ID, true); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"b", "c", "d", "e"} {
+				if _, err := s.Add(AddParams{Name: name, Bytes: []byte(name)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			meta, err := s.GetMeta(a.ID)
+			if err != nil || !meta.Pinned {
+				t.Fatalf("pinned file evicted or unpinned: %#v err=%v", meta, err)
+			}
+			if st := s.Stats(); st.Files != 3 {
+				t.Fatalf("unexpected file count: %d", st.Files)
+			}
+		})
+	}
+}
+
+func TestOnlyPinnedFilesLeftRejectsUpload(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 10, MaxPinnedBytes: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("aaaaa")})
+	if _, err := s.SetPinned(a.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := s.Add(AddParams{Name: "b", Bytes: []byte("bb")})
+
+	if _, err := s.Add(AddParams{Name: "c", Bytes: []byte("cccccc")}); err != ErrInsufficientSpace {
+		t.Fatalf("expected ErrInsufficientSpace, got %v", err)
+	}
+	if _, err := s.GetMeta(a.ID); err != nil {
+		t.Fatalf("pinned file lost: %v", err)
+	}
+	if _, err := s.GetMeta(b.ID); err != ErrNotFound {
+		t.Fatalf("expected unpinned b evicted, got %v", err)
+	}
+
+	// 放得下的上传不受影响：置顶上限保证至少留出 max_total - max_pinned 的空间。
+	if _, err := s.Add(AddParams{Name: "d", Bytes: []byte("dddd")}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPinLimits(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 20, MaxPinnedBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("aaa")})
+	b, _ := s.Add(AddParams{Name: "b", Bytes: []byte("bbb")})
+	c, _ := s.Add(AddParams{Name: "c", Bytes: []byte("c")})
+
+	if _, err := s.SetPinned(a.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetPinned(b.ID, true); err != ErrPinLimit {
+		t.Fatalf("expected ErrPinLimit for bytes, got %v", err)
+	}
+	if _, err := s.SetPinned(c.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	// 3 个名额中已置顶 2 个：剩余名额须留给新上传。
+	if _, err := s.SetPinned(b.ID, true); err != ErrPinLimit {
+		t.Fatalf("expected ErrPinLimit for count, got %v", err)
+	}
+
+	// 置顶文件替换后超出置顶上限时严格失败。
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("aaaaa")}); err != ErrReplaceWouldExceed {
+		t.Fatalf("expected ErrReplaceWouldExceed, got %v", err)
+	}
+
+	if _, err := s.SetPinned(a.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetPinned(b.ID, true); err != nil {
+		t.Fatalf("expected pin to succeed after unpinning a: %v", err)
+	}
+	if _, err := s.SetPinned("missing", true); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPinnedSurvivesPersistence(t *testing.T) {
+	params := NewParams{MaxFiles: 3, MaxTotalBytes: 100, MaxPinnedBytes: 50}
+	mutate := func(t *testing.T, s FileStore) string {
+		t.Helper()
+		a, err := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SetPinned(a.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		return a.ID
+	}
+	check := func(t *testing.T, s FileStore, id string) {
+		t.Helper()
+		meta, err := s.GetMeta(id)
+		if err != nil || !meta.Pinned {
+			t.Fatalf("expected pinned after reload: %#v err=%v", meta, err)
+		}
+		for _, name := range []string{"b", "c", "d"} {
+			if _, err := s.Add(AddParams{Name: name, Bytes: []byte(name)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.GetMeta(id); err != nil {
+			t.Fatalf("pinned file evicted after reload: %v", err)
+		}
+	}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		id := mutate(t, s)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		check(t, reopened, id)
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		dir := t.TempDir()
+		s, _ := openJournalStore(t, dir, params)
+		id := mutate(t, s)
+		restored, j := openJournalStore(t, dir, params)
+		defer j.Close()
+		check(t, restored, id)
+	})
+}
@@ -0,0 +1,308 @@
+package store
+
+import (
+	"container/heap"
+	"container/list"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 排序字段（ListQuery.Sort）。
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortName    = "name"
+	SortSize    = "size"
+)
+
+// ListQuery 描述文件列表的筛选、排序与分页。零值表示不筛选、按上传顺序、不分页。
+type ListQuery struct {
+	// Folder 非 nil 时只返回直接位于该目录下的文件。
+	Folder *string
+	// Name 为文件名匹配：含 * ? [ 时按 glob（path.Match）整体匹配，否则按子串匹配；均不区分大小写。
+	Name string
+	// Tags 须全部命中。
+	Tags []string
+	// Encoding 不区分大小写。
+	Encoding string
+	IsText   *bool
+	// MinSize/MaxSize 为大小范围（含端点），MaxSize 为 0 表示不限。
+	MinSize int64
+	MaxSize int64
+	// 时间范围为 [From, To)，零值表示不限。
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+
+	// Sort 为排序字段（空为 SortCreated，即上传顺序），相同时按 ID 排序，保证顺序稳定。
+	Sort string
+	Desc bool
+	// Limit 为每页条数（0 表示不分页）；Cursor 为上一页返回的 NextCursor。
+	Limit  int
+	Cursor string
+}
+
+// ListPage 为一页结果。Total 为符合筛选条件的总数；NextCursor 为空表示没有下一页。
+type ListPage struct {
+	Files      []FileMeta
+	Total      int
+	NextCursor string
+}
+
+// Query 在读锁内按条件筛选、排序并分页，只复制当前页的元数据。Folder 不存在时返回 ErrFolderNotFound。
+// 游标记录上一页最后一条的排序键，翻页期间文件增删不会导致重复或遗漏其余文件。
+// 默认顺序直接沿 fifo 遍历；其他排序只为当前页维护大小为 Limit 的堆，不对全部文件排序。
+func (s *engine) Query(q ListQuery) (ListPage, error) {
+	if q.Folder != nil {
+		f, err := CleanFolder(*q.Folder)
+		if err != nil {
+			return ListPage{}, err
+		}
+		q.Folder = &f
+	}
+	match, err := q.matcher()
+	if err != nil {
+		return ListPage{}, err
+	}
+	if q.Sort == "" {
+		q.Sort = SortCreated
+	}
+	keyOf, ok := sortKeys[q.Sort]
+	if !ok {
+		return ListPage{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidInput, q.Sort)
+	}
+	if q.Limit < 0 {
+		return ListPage{}, fmt.Errorf("%w: limit must be >= 0", ErrInvalidInput)
+	}
+	var after *sortKey
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor, q.Sort, q.Desc)
+		if err != nil {
+			return ListPage{}, err
+		}
+		after = &c
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if q.Folder != nil && !s.folderExistsLocked(*q.Folder) {
+		return ListPage{}, ErrFolderNotFound
+	}
+
+	now := time.Now()
+	visible := func(en *entry) bool { return !en.meta.Expired(now) && match(&en.meta) }
+	var hits []*entry
+	var total, rest int
+	if q.Sort == SortCreated {
+		hits, total, rest = s.queryUploadOrderLocked(q, after, visible)
+	} else {
+		hits, total, rest = s.querySortedLocked(q, after, keyOf, visible)
+	}
+
+	page := ListPage{Total: total, Files: make([]FileMeta, 0, len(hits))}
+	for _, en := range hits {
+		page.Files = append(page.Files, en.meta)
+	}
+	if q.Limit > 0 && rest > q.Limit {
+		page.NextCursor = encodeCursor(q.Sort, q.Desc, keyOf(hits[len(hits)-1]))
+	}
+	return page, nil
+}
+
+// queryUploadOrderLocked 沿 fifo 一次遍历，不排序：从游标所指文件之后开始收集；
+// 该文件已删除时按上传序号定位。rest 为游标之后符合条件的条数。
+func (s *engine) queryUploadOrderLocked(q ListQuery, after *sortKey, visible func(*entry) bool) (hits []*entry, total, rest int) {
+	first, next := s.fifo.Front(), (*list.Element).Next
+	if q.Desc {
+		first, next = s.fifo.Back(), (*list.Element).Prev
+	}
+	var mark *list.Element
+	if after != nil {
+		if en, ok := s.byID[after.ID]; ok && int64(en.seq) == after.Num {
+			mark = en.elem
+		}
+	}
+	started := after == nil
+	for e := first; e != nil; e = next(e) {
+		en := e.Value.(*entry)
+		if !started && mark == nil {
+			started = (!q.Desc && int64(en.seq) > after.Num) || (q.Desc && int64(en.seq) < after.Num)
+		}
+		if visible(en) {
+			total++
+			if started {
+				rest++
+				if q.Limit == 0 || len(hits) < q.Limit {
+					hits = append(hits, en)
+				}
+			}
+		}
+		if e == mark {
+			started = true
+		}
+	}
+	return hits, total, rest
+}
+
+// querySortedLocked 用大小为 Limit 的堆保留游标之后最靠前的条目，只对当前页排序；不分页时整体排序。
+func (s *engine) querySortedLocked(q ListQuery, after *sortKey, keyOf func(*entry) sortKey, visible func(*entry) bool) (hits []*entry, total, rest int) {
+	before := func(a, b sortKey) bool {
+		if q.Desc {
+			return b.less(a)
+		}
+		return a.less(b)
+	}
+	h := &pageHeap{before: before}
+	for e := s.fifo.Front(); e != nil; e = e.Next() {
+		en := e.Value.(*entry)
+		if !visible(en) {
+			continue
+		}
+		total++
+		k := keyOf(en)
+		if after != nil && !before(*after, k) {
+			continue
+		}
+		rest++
+		switch {
+		case q.Limit == 0 || h.Len() < q.Limit:
+			heap.Push(h, pageHit{en: en, key: k})
+		case before(k, h.hits[0].key):
+			h.hits[0] = pageHit{en: en, key: k}
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Slice(h.hits, func(i, j int) bool { return before(h.hits[i].key, h.hits[j].key) })
+	hits = make([]*entry, 0, len(h.hits))
+	for _, hit := range h.hits {
+		hits = append(hits, hit.en)
+	}
+	return hits, total, rest
+}
+
+type pageHit struct {
+	en  *entry
+	key sortKey
+}
+
+// pageHeap 的堆顶为已保留条目中排序最靠后的一条，便于被更靠前的条目替换。
+type pageHeap struct {
+	hits   []pageHit
+	before func(a, b sortKey) bool
+}
+
+func (h *pageHeap) Len() int           { return len(h.hits) }
+func (h *pageHeap) Less(i, j int) bool { return h.before(h.hits[j].key, h.hits[i].key) }
+func (h *pageHeap) Swap(i, j int)      { h.hits[i], h.hits[j] = h.hits[j], h.hits[i] }
+func (h *pageHeap) Push(x any)         { h.hits = append(h.hits, x.(pageHit)) }
+func (h *pageHeap) Pop() any {
+	last := h.hits[len(h.hits)-1]
+	h.hits = h.hits[:len(h.hits)-1]
+	return last
+}
+
+// matcher 要求 q.Folder 已规范化。
+func (q ListQuery) matcher() (func(m *FileMeta) bool, error) {
+	if q.MinSize < 0 || q.MaxSize < 0 || (q.MaxSize > 0 && q.MinSize > q.MaxSize) {
+		return nil, fmt.Errorf("%w: bad size range", ErrInvalidInput)
+	}
+	name := strings.ToLower(q.Name)
+	glob := strings.ContainsAny(name, "*?[")
+	if glob {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("%w: bad name pattern %q", ErrInvalidInput, q.Name)
+		}
+	}
+	inRange := func(t, from, to time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	}
+
+	return func(m *FileMeta) bool {
+		if q.Folder != nil && m.Folder != *q.Folder {
+			return false
+		}
+		if name != "" {
+			lower := strings.ToLower(m.Name)
+			if glob {
+				if ok, _ := path.Match(name, lower); !ok {
+					return false
+				}
+			} else if !strings.Contains(lower, name) {
+				return false
+			}
+		}
+		for _, t := range q.Tags {
+			if !m.HasTag(t) {
+				return false
+			}
+		}
+		if q.Encoding != "" && !strings.EqualFold(m.Encoding, q.Encoding) {
+			return false
+		}
+		if q.IsText != nil && m.IsText != *q.IsText {
+			return false
+		}
+		if m.SizeBytes < q.MinSize || (q.MaxSize > 0 && m.SizeBytes > q.MaxSize) {
+			return false
+		}
+		return inRange(m.CreatedAt, q.CreatedFrom, q.CreatedTo) && inRange(m.UpdatedAt, q.UpdatedFrom, q.UpdatedTo)
+	}, nil
+}
+
+// sortKey 为排序键：只有与排序字段对应的成员有值，ID 用于打破平局。
+type sortKey struct {
+	Str string `json:"s,omitempty"`
+	Num int64  `json:"n,omitempty"`
+	ID  string `json:"id"`
+}
+
+func (a sortKey) less(b sortKey) bool {
+	if a.Str != b.Str {
+		return a.Str < b.Str
+	}
+	if a.Num != b.Num {
+		return a.Num < b.Num
+	}
+	return a.ID < b.ID
+}
+
+// SortCreated 即上传顺序（fifo），排序键为上传序号。
+var sortKeys = map[string]func(en *entry) sortKey{
+	SortCreated: func(en *entry) sortKey { return sortKey{Num: int64(en.seq), ID: en.meta.ID} },
+	SortUpdated: func(en *entry) sortKey { return sortKey{Num: en.meta.UpdatedAt.UnixNano(), ID: en.meta.ID} },
+	SortName:    func(en *entry) sortKey { return sortKey{Str: strings.ToLower(en.meta.Name), ID: en.meta.ID} },
+	SortSize:    func(en *entry) sortKey { return sortKey{Num: en.meta.SizeBytes, ID: en.meta.ID} },
+}
+
+// cursor 为不透明游标的内容；记录排序方式，换了排序条件的游标会被拒绝。
+type cursor struct {
+	Sort string  `json:"sort"`
+	Desc bool    `json:"desc,omitempty"`
+	Key  sortKey `json:"key"`
+}
+
+func encodeCursor(sortBy string, desc bool, k sortKey) string {
+	b, _ := json.Marshal(cursor{Sort: sortBy, Desc: desc, Key: k})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s, sortBy string, desc bool) (sortKey, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return sortKey{}, fmt.Errorf("%w: bad cursor", ErrInvalidInput)
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Key.ID == "" {
+		return sortKey{}, fmt.Errorf("%w: bad cursor", ErrInvalidInput)
+	}
+	if c.Sort != sortBy || c.Desc != desc {
+		return sortKey{}, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidInput)
+	}
+	return c.Key, nil
+}
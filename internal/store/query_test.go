@@ -0,0 +1,182 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func queryNames(t *testing.T, s FileStore, q ListQuery) []string {
+	t.Helper()
+	page, err := s.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(page.Files))
+	for _, f := range page.Files {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestQueryFiltersAndSorts(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateFolder("docs"); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Now().Add(-time.Hour)
+	add := func(name, folder string, size int, enc string, at time.Time) FileMeta {
+		t.Helper()
+		m, err := s.Add(AddParams{Name: name, Folder: folder, Bytes: []byte(strings.Repeat("x", size)), Encoding: enc, IsText: enc != "", Now: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	add("Report.txt", "", 30, "UTF-8", base)
+	b := add("notes.md", "docs", 10, "GB18030", base.Add(time.Minute))
+	add("image.png", "", 50, "", base.Add(2*time.Minute))
+	add("report-2.TXT", "docs", 20, "utf-8", base.Add(3*time.Minute))
+	if _, err := s.SetTags(b.ID, []string{"x", "y"}); err != nil {
+		t.Fatal(err)
+	}
+
+	yes, no, docs := true, false, "/docs/"
+	cases := []struct {
+		name string
+		q    ListQuery
+		want string
+	}{
+		{"all", ListQuery{}, "Report.txt notes.md image.png report-2.TXT"},
+		{"folder", ListQuery{Folder: &docs}, "notes.md report-2.TXT"},
+		{"substring", ListQuery{Name: "REPORT"}, "Report.txt report-2.TXT"},
+		{"glob", ListQuery{Name: "*.txt"}, "Report.txt report-2.TXT"},
+		{"tags", ListQuery{Tags: []string{"y", "x"}}, "notes.md"},
+		{"encoding", ListQuery{Encoding: "utf-8"}, "Report.txt report-2.TXT"},
+		{"text", ListQuery{IsText: &yes}, "Report.txt notes.md report-2.TXT"},
+		{"binary", ListQuery{IsText: &no}, "image.png"},
+		{"size", ListQuery{MinSize: 20, MaxSize: 30}, "Report.txt report-2.TXT"},
+		{"created", ListQuery{CreatedFrom: base.Add(time.Minute), CreatedTo: base.Add(3 * time.Minute)}, "notes.md image.png"},
+		{"name", ListQuery{Sort: SortName}, "image.png notes.md report-2.TXT Report.txt"},
+		{"size desc", ListQuery{Sort: SortSize, Desc: true}, "image.png Report.txt report-2.TXT notes.md"},
+		{"created desc", ListQuery{Desc: true, Limit: 2}, "report-2.TXT image.png"},
+	}
+	for _, c := range cases {
+		if got := strings.Join(queryNames(t, s, c.q), " "); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	missing := "nope"
+	if _, err := s.Query(ListQuery{Folder: &missing}); err != ErrFolderNotFound {
+		t.Fatalf("expected ErrFolderNotFound, got %v", err)
+	}
+	for _, q := range []ListQuery{{Sort: "color"}, {Name: "[a"}, {MinSize: 5, MaxSize: 1}, {Limit: -1}, {Cursor: "!!"}} {
+		if _, err := s.Query(q); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%+v: expected ErrInvalidInput, got %v", q, err)
+		}
+	}
+}
+
+func TestQueryCursorPagination(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 20, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, name := range []string{"e", "b", "g", "a", "f", "c", "d"} {
+		m, err := s.Add(AddParams{Name: name, Bytes: []byte(name)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, m.ID)
+	}
+
+	q := ListQuery{Sort: SortName, Limit: 3}
+	page, err := s.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 7 || page.NextCursor == "" || len(page.Files) != 3 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	// 翻页期间删除已返回与未返回的文件、在游标之前新增文件，其余文件既不重复也不遗漏。
+	if _, err := s.Delete(ids[3]); err != nil { // a
+		t.Fatal(err)
+	}
+	if _, err := s.Delete(ids[6]); err != nil { // d
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "bb", Bytes: []byte("bb")}); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{page.Files[0].Name, page.Files[1].Name, page.Files[2].Name}
+	for page.NextCursor != "" {
+		q.Cursor = page.NextCursor
+		if page, err = s.Query(q); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range page.Files {
+			got = append(got, f.Name)
+		}
+	}
+	if strings.Join(got, " ") != "a b c e f g" {
+		t.Fatalf("unexpected pages: %v", got)
+	}
+
+	q.Desc = true
+	if _, err := s.Query(q); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for cursor of another order, got %v", err)
+	}
+}
+
+func TestQueryUploadOrderPagination(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		s, err := NewInMemoryStore(NewParams{MaxFiles: 20, MaxTotalBytes: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make(map[string]string)
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			m, err := s.Add(AddParams{Name: name, Bytes: []byte(name)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[name] = m.ID
+		}
+
+		q := ListQuery{Desc: desc, Limit: 2}
+		page, err := s.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range page.Files {
+			got = append(got, f.Name)
+		}
+		// 删除游标所指的文件与下一页中的一个文件，翻页按上传序号继续。
+		skip := map[bool]string{false: "d", true: "c"}[desc]
+		for _, name := range []string{page.Files[1].Name, skip} {
+			if _, err := s.Delete(ids[name]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for page.NextCursor != "" {
+			q.Cursor = page.NextCursor
+			if page, err = s.Query(q); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range page.Files {
+				got = append(got, f.Name)
+			}
+		}
+		want := map[bool]string{false: "a b c e f", true: "f e d b a"}[desc]
+		if strings.Join(got, " ") != want {
+			t.Fatalf("desc=%v: got %v, want %s", desc, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// 容量预留：上传在读取请求体之前按大小上界预留一个文件名额与相应字节。预留本身不淘汰文件，只要求连同其他预留、
+// 在淘汰所有可淘汰的文件后放得下；淘汰与上限检查把未结束的预留当作已占用，淘汰推迟到 Add 按实际存放的大小
+// （去重、压缩后）进行。因此获准的上传在 Add 时放得下（除非期间有文件被置顶），正在读取的请求体总量也不会超过
+// MaxTotalBytes。Add 写入内容时按实际新增的物理用量逐步扣减预留，提交时结束预留。
+
+// Reservation 为一次上传预留的容量，由 Reserve 返回；使用完毕（无论成败）须调用 Release。
+type Reservation struct {
+	s *engine
+	// bytes 为尚未被实际内容占用的预留字节，与 done 一起受 engine.mu 保护。
+	bytes int64
+	done  bool
+}
+
+// Reserve 为即将写入的 size 字节内容预留容量，不淘汰文件；淘汰所有可淘汰的文件后仍放不下时返回 ErrInsufficientSpace。
+// size 为 0 时只预留文件名额（大小未知的上传）。size 超过总量上限时返回 ErrTooLarge；
+// 启用压缩时存放大小事先未知，最多预留 MaxTotalBytes。
+// 把预留传给 Add（AddParams.Reservation）后，成功提交即结束预留；此后的 Release 不再有作用。
+func (s *engine) Reserve(size int64) (*Reservation, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("%w: size must be >= 0", ErrInvalidInput)
+	}
+	if size > s.maxTotalBytes {
+		if !s.compresses() {
+			return nil, ErrTooLarge
+		}
+		size = s.maxTotalBytes
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, _, fits := s.runEvictionLocked(size, "", time.Now(), true); !fits {
+		return nil, ErrInsufficientSpace
+	}
+	s.reservedBytes += size
+	s.reservedFiles++
+	return &Reservation{s: s, bytes: size}, nil
+}
+
+// Release 结束预留，归还尚未使用的容量；可重复调用，nil 时不做任何事。
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	r.s.mu.Lock()
+	r.endLocked()
+	r.s.mu.Unlock()
+}
+
+// takeLocked 把 n 字节新增的物理用量从预留中扣除（最多扣到 0）。
+func (r *Reservation) takeLocked(n int64) {
+	if r == nil || r.done {
+		return
+	}
+	n = min(n, r.bytes)
+	if n <= 0 {
+		return
+	}
+	r.bytes -= n
+	r.s.reservedBytes -= n
+}
+
+// endLocked 结束预留并归还剩余的字节与文件名额。
+func (r *Reservation) endLocked() {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	r.s.reservedBytes -= r.bytes
+	r.s.reservedFiles--
+	r.bytes = 0
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReserveHoldsCapacity(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100})
+	res, err := s.Reserve(60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 预留的空间不会被其他上传或替换占用。
+	if _, err := s.Add(AddParams{Name: "big", Bytes: make([]byte, 50)}); err != ErrInsufficientSpace {
+		t.Fatalf("expected ErrInsufficientSpace, got %v", err)
+	}
+	small, err := s.Add(AddParams{Name: "small", Bytes: []byte("0123456789")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: small.ID, Bytes: make([]byte, 45)}); err != ErrReplaceWouldExceed {
+		t.Fatalf("expected ErrReplaceWouldExceed, got %v", err)
+	}
+	// 淘汰其他文件也腾不出被预留的空间；被拒绝的预留不淘汰文件。
+	if _, err := s.Reserve(41); err != ErrInsufficientSpace {
+		t.Fatalf("expected ErrInsufficientSpace, got %v", err)
+	}
+
+	// 获准的上传一定放得下。
+	if _, err := s.Add(AddParams{Name: "reserved", Bytes: bytes.Repeat([]byte("r"), 60), Reservation: res}); err != nil {
+		t.Fatal(err)
+	}
+	if st := s.Stats(); st.Files != 2 || st.PhysicalBytes != 70 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+	res.Release()
+	if s.reservedBytes != 0 || s.reservedFiles != 0 {
+		t.Fatalf("reservation not ended: %d bytes, %d files", s.reservedBytes, s.reservedFiles)
+	}
+}
+
+func TestReserveCountsFilesWithoutEvicting(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100})
+	a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+	b, _ := s.Add(AddParams{Name: "b", Bytes: []byte("b")})
+
+	r1, err := s.Reserve(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个预留占一个文件名额，但预留本身不淘汰文件。
+	r2, err := s.Reserve(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != nil {
+		t.Fatalf("reserve should not evict: %v", err)
+	}
+
+	// 提交时才淘汰：连同 r2 的名额，b2 需要淘汰最早的文件。
+	// 内容与已有文件相同时不新增用量，预留在提交时全部归还。
+	if _, err := s.Add(AddParams{Name: "b2", Bytes: []byte("b"), Reservation: r1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("oldest file should be evicted, got %v", err)
+	}
+	if _, err := s.GetMeta(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	r1.Release()
+	r2.Release()
+	r2.Release()
+	if s.reservedBytes != 0 || s.reservedFiles != 0 {
+		t.Fatalf("reservations not released: %d bytes, %d files", s.reservedBytes, s.reservedFiles)
+	}
+
+	if _, err := s.Reserve(101); err != ErrTooLarge {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	other, _ := NewInMemoryStore(NewParams{MaxFiles: 3, MaxTotalBytes: 100})
+	r3, _ := other.Reserve(1)
+	defer r3.Release()
+	if _, err := s.Add(AddParams{Name: "c", Bytes: []byte("c"), Reservation: r3}); err == nil {
+		t.Fatal("reservation from another store should be rejected")
+	}
+}
+
+func TestReserveRejectsWhatEvictionCannotFree(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxPinnedBytes: 50})
+	pinned, _ := s.Add(AddParams{Name: "pinned", Bytes: make([]byte, 40)})
+	if _, err := s.SetPinned(pinned.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := s.Add(AddParams{Name: "other", Bytes: bytes.Repeat([]byte("o"), 30)})
+
+	// 淘汰 other 之后放得下：预留成功，但不淘汰。
+	res, err := s.Reserve(60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(other.ID); err != nil {
+		t.Fatalf("reserve should not evict: %v", err)
+	}
+	// 置顶文件不可淘汰，连同已有预留放不下。
+	if _, err := s.Reserve(1); err != ErrInsufficientSpace {
+		t.Fatalf("expected ErrInsufficientSpace, got %v", err)
+	}
+	res.Release()
+	if st := s.Stats(); st.Files != 2 || len(s.RecentEvictions()) != 0 {
+		t.Fatalf("unexpected stats after rejected reservation: %#v", st)
+	}
+}
+
+func TestUnknownSizeReservationHoldsNoBytes(t *testing.T) {
+	s, _ := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	a, _ := s.Add(AddParams{Name: "a", Bytes: make([]byte, 90)})
+
+	// 大小未知的上传只预留文件名额，其他文件不会为它被淘汰。
+	res, err := s.Reserve(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "b", Bytes: make([]byte, 5)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != nil {
+		t.Fatalf("unexpected eviction: %v", err)
+	}
+	// 提交时按实际大小淘汰。
+	if _, err := s.Add(AddParams{Name: "c", Bytes: bytes.Repeat([]byte("c"), 10), Reservation: res}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("expected a evicted on commit, got %v", err)
+	}
+}
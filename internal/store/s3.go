@@ -0,0 +1,187 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultS3Prefix = "files/"
+
+// S3Store 把文件内容保存在 S3 兼容的对象存储中，元数据索引保存在本地文件。
+// 下载通过流式 GET（按需 Range）读取；转码为“整体读取 → 写入新对象 → 删除旧对象”。
+// 淘汰、重名与 ReplaceBytes 口径与 InMemoryStore 一致（共用 engine）。
+// 本地索引的写入方式同 DiskStore（见 indexWriter），退出前应调用 Close。
+type S3Store struct {
+	*engine
+	client *s3Client
+	prefix string
+}
+
+type S3Params struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// Prefix 为对象 key 前缀（默认 "files/"）。
+	Prefix string
+	// CleanOrphans 为 true 时，启动时删除该前缀下未被本地索引引用的内容对象（上次崩溃留下的孤儿）。
+	// 只在确实读到本地索引时进行，且只删除 key 符合内容 key 格式的对象；
+	// 本地索引丢失（换机器、改了 storage.dir）时不会据此清空桶。
+	CleanOrphans bool
+	// PathStyle 为 true 时使用 <endpoint>/<bucket>/<key>，否则使用 <bucket>.<host>/<key>。
+	PathStyle bool
+	// IndexPath 为本地元数据索引文件路径。
+	IndexPath  string
+	HTTPClient *http.Client
+}
+
+func NewS3Store(sp S3Params, p NewParams) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimSpace(sp.Endpoint))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: s3 endpoint must be an http(s) URL", ErrInvalidInput)
+	}
+	if strings.TrimSpace(sp.Bucket) == "" {
+		return nil, fmt.Errorf("%w: s3 bucket is required", ErrInvalidInput)
+	}
+	if strings.TrimSpace(sp.IndexPath) == "" {
+		return nil, fmt.Errorf("%w: s3 index path is required", ErrInvalidInput)
+	}
+	if sp.Region == "" {
+		sp.Region = "us-east-1"
+	}
+	if sp.Prefix == "" {
+		sp.Prefix = defaultS3Prefix
+	}
+	httpClient := sp.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: 30 * time.Second,
+			MaxIdleConnsPerHost:   32,
+		}}
+	}
+	if err := os.MkdirAll(filepath.Dir(sp.IndexPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create index dir: %w", err)
+	}
+
+	client := &s3Client{
+		endpoint:  endpoint,
+		bucket:    sp.Bucket,
+		region:    sp.Region,
+		accessKey: sp.AccessKey,
+		secretKey: sp.SecretKey,
+		pathStyle: sp.PathStyle,
+		http:      httpClient,
+	}
+	e, err := newEngine(p, &s3Blobs{client: client, prefix: sp.Prefix})
+	if err != nil {
+		return nil, err
+	}
+	s := &S3Store{engine: e, client: client, prefix: sp.Prefix}
+	if err := s.load(sp.IndexPath, sp.CleanOrphans); err != nil {
+		return nil, err
+	}
+	w, err := newIndexWriter(e, sp.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	e.index = w
+	return s, nil
+}
+
+// Flush 等待此前的变更写入本地索引。
+func (s *S3Store) Flush() error {
+	return s.index.Flush()
+}
+
+// Close 停止后台写索引并把最后的变更落盘（用于优雅退出）。
+func (s *S3Store) Close() error {
+	return s.index.Close()
+}
+
+// load 读取本地索引并与桶内对象对账（一次 List）：丢弃对象缺失/大小不符的条目；
+// cleanOrphans 为 true 且读到了索引时，删除未被引用的内容对象。
+func (s *S3Store) load(indexPath string, cleanOrphans bool) error {
+	idx, found, err := readIndexFile(indexPath)
+	if err != nil {
+		return err
+	}
+	objects, err := s.client.listObjects(s.prefix)
+	if err != nil {
+		return fmt.Errorf("list bucket: %w", err)
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, o := range objects {
+		sizes[strings.TrimPrefix(o.Key, s.prefix)] = o.Size
+	}
+
+	blobOK := func(key string, size int64) bool {
+		got, ok := sizes[key]
+		return ok && got == size
+	}
+	s.mu.Lock()
+	s.loadFoldersLocked(idx.Folders)
+	for _, f := range idx.Files {
+		s.insertIndexedLocked(f, blobOK)
+	}
+	for _, t := range idx.Trash {
+		s.trashIndexedLocked(t, blobOK)
+	}
+	s.fitLimitsLocked()
+	s.sweepTrashLocked(time.Now())
+	referenced := s.referencedLocked()
+	s.mu.Unlock()
+
+	// 没有索引时桶内对象都“未被引用”，不能据此删除。
+	if !cleanOrphans || !found {
+		return nil
+	}
+	for key := range sizes {
+		if _, ok := referenced[key]; !ok && isBlobKey(key) {
+			_ = s.blobs.remove(key)
+		}
+	}
+	return nil
+}
+
+type s3Blobs struct {
+	client *s3Client
+	prefix string
+}
+
+func (b *s3Blobs) put(key string, data []byte) error {
+	return b.client.putObject(b.prefix+key, data)
+}
+
+func (b *s3Blobs) get(key string) ([]byte, error) {
+	body, err := b.client.getObject(b.prefix+key, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// open 先用 HEAD 确认对象存在且大小相符（缺失时返回 errBlobNotFound 以便 engine 重试），
+// 内容在第一次 Read 时才按当前偏移 GET，http.ServeContent 先 Seek 到末尾探测长度也不会多发请求。
+func (b *s3Blobs) open(key string, size int64) (io.ReadSeekCloser, error) {
+	got, err := b.client.headObject(b.prefix + key)
+	if err != nil {
+		return nil, err
+	}
+	if got >= 0 && got != size {
+		return nil, fmt.Errorf("s3 object %q: size %d, expected %d", key, got, size)
+	}
+	return &s3ObjectReader{client: b.client, key: b.prefix + key, size: size}, nil
+}
+
+func (b *s3Blobs) remove(key string) error {
+	return b.client.deleteObject(b.prefix + key)
+}
@@ -0,0 +1,532 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 快照格式（大端）：
+//
+//	magic[8] "FECSNAP1" | version u32 | count u64
+//	count × ( metaLen u32 | meta JSON | dataLen u64 | data | 每个历史版本 ( dataLen u64 | data ) )
+//	foldersLen u32 | folders JSON（目录列表，version 3 起）
+//	trashCount u64 | trashCount × 条目（回收站，编码同上，version 4 起）
+//	sha256[32]（覆盖此前全部字节）
+//
+// 历史版本的元数据在 meta JSON 的 Versions 中，内容按相同顺序紧跟在当前内容之后（version 2 起）。
+// 目录列表单独保存，以便保留空目录。回收站条目按删除顺序写入，meta JSON 中带有 DeletedAt/Evicted。
+// 条目按 FIFO 顺序写入；读取时校验完整性，任何截断/损坏都会整体拒绝，不会部分加载。
+const (
+	snapshotMagic  = "FECSNAP1"
+	snapshotFormat = 4
+
+	maxSnapshotMetaBytes   = 64 * 1024
+	maxSnapshotFolderBytes = 16 * 1024 * 1024
+)
+
+// snapshotState 为快照的全部内容：文件（FIFO 顺序）、目录与回收站条目。
+// 读取快照得到的条目带有内容（data）；snapshotLocked 得到的条目只带 blob，内容在写出时才读取。
+type snapshotState struct {
+	items   []snapshotItem
+	folders []string
+	trash   []snapshotItem
+	// held 为 snapshotLocked 临时保留的内容 key，写完后须 unhold。
+	held []string
+}
+
+type snapshotItem struct {
+	meta     FileMeta
+	data     []byte
+	blob     blobRef
+	versions []snapshotVersion
+	// deletedAt/evicted 只用于回收站条目。
+	deletedAt time.Time
+	evicted   bool
+}
+
+type snapshotVersion struct {
+	meta VersionMeta
+	data []byte
+	blob blobRef
+}
+
+type snapshotMeta struct {
+	FileMeta
+	Versions  []VersionMeta `json:",omitempty"`
+	DeletedAt time.Time     `json:",omitzero"`
+	Evicted   bool          `json:",omitempty"`
+}
+
+// WriteSnapshot 把当前全部文件（元数据 + 内容）按 FIFO 顺序写入 w，随后写入目录列表与回收站。
+// 锁内只复制元数据并保留所引用的内容；内容在锁外逐个读出（压缩的边读边解压）写入 w，
+// 写出过程不阻塞其他请求，内存占用也与文件总量无关。
+func (s *InMemoryStore) WriteSnapshot(w io.Writer) error {
+	s.mu.Lock()
+	snap := s.snapshotLocked()
+	s.mu.Unlock()
+	defer s.unhold(snap.held)
+	return s.writeSnapshot(w, snap)
+}
+
+// snapshotLocked 复制当前状态的元数据，并临时保留所引用的内容（调用方写完后 unhold(snap.held)），
+// 使写出期间被删除、淘汰或替换的内容仍可读取。
+func (s *engine) snapshotLocked() snapshotState {
+	snap := snapshotState{
+		items:   make([]snapshotItem, 0, len(s.byID)),
+		folders: s.folderListLocked(),
+	}
+	for e := s.fifo.Front(); e != nil; e = e.Next() {
+		snap.items = append(snap.items, s.snapshotItemLocked(e.Value.(*entry), &snap.held))
+	}
+	for e := s.trashList.Front(); e != nil; e = e.Next() {
+		te := e.Value.(*trashEntry)
+		it := s.snapshotItemLocked(te.en, &snap.held)
+		it.deletedAt, it.evicted = te.deletedAt, te.evicted
+		snap.trash = append(snap.trash, it)
+	}
+	return snap
+}
+
+func (s *engine) snapshotItemLocked(en *entry, held *[]string) snapshotItem {
+	hold := func(key string) blobRef {
+		s.holdLocked(key)
+		*held = append(*held, key)
+		return *s.blobRefs[key]
+	}
+	it := snapshotItem{meta: en.meta, blob: hold(en.blobKey)}
+	for _, v := range en.versions {
+		it.versions = append(it.versions, snapshotVersion{meta: v.VersionMeta, blob: hold(v.blobKey)})
+	}
+	return it
+}
+
+// writeSnapshot 写出 snapshotLocked 得到的快照，内容按 blob 逐个流式读取。
+func (s *engine) writeSnapshot(w io.Writer, snap snapshotState) error {
+	h := sha256.New()
+	bw := bufio.NewWriter(w)
+	mw := io.MultiWriter(bw, h)
+
+	var hdr [8 + 4 + 8]byte
+	copy(hdr[:8], snapshotMagic)
+	binary.BigEndian.PutUint32(hdr[8:12], snapshotFormat)
+	binary.BigEndian.PutUint64(hdr[12:20], uint64(len(snap.items)))
+	if _, err := mw.Write(hdr[:]); err != nil {
+		return err
+	}
+	for _, it := range snap.items {
+		if err := s.writeSnapshotItem(mw, it); err != nil {
+			return err
+		}
+	}
+
+	fb, err := json.Marshal(snap.folders)
+	if err != nil {
+		return err
+	}
+	var n4 [4]byte
+	binary.BigEndian.PutUint32(n4[:], uint32(len(fb)))
+	if _, err := mw.Write(n4[:]); err != nil {
+		return err
+	}
+	if _, err := mw.Write(fb); err != nil {
+		return err
+	}
+
+	var n8 [8]byte
+	binary.BigEndian.PutUint64(n8[:], uint64(len(snap.trash)))
+	if _, err := mw.Write(n8[:]); err != nil {
+		return err
+	}
+	for _, it := range snap.trash {
+		if err := s.writeSnapshotItem(mw, it); err != nil {
+			return err
+		}
+	}
+
+	if _, err := bw.Write(h.Sum(nil)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (s *engine) writeSnapshotItem(w io.Writer, it snapshotItem) error {
+	sm := snapshotMeta{FileMeta: it.meta, DeletedAt: it.deletedAt, Evicted: it.evicted}
+	for _, v := range it.versions {
+		sm.Versions = append(sm.Versions, v.meta)
+	}
+	mb, err := json.Marshal(sm)
+	if err != nil {
+		return err
+	}
+	var n4 [4]byte
+	binary.BigEndian.PutUint32(n4[:], uint32(len(mb)))
+	if _, err := w.Write(n4[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(mb); err != nil {
+		return err
+	}
+	if err := s.writeSnapshotData(w, it.blob, it.meta.SizeBytes); err != nil {
+		return fmt.Errorf("read content %s: %w", it.meta.ID, err)
+	}
+	for _, v := range it.versions {
+		if err := s.writeSnapshotData(w, v.blob, v.meta.SizeBytes); err != nil {
+			return fmt.Errorf("read content %s@%d: %w", it.meta.ID, v.meta.Version, err)
+		}
+	}
+	return nil
+}
+
+// writeSnapshotData 写出内容长度，再把 blob 解压后的 size 字节写入 w。
+func (s *engine) writeSnapshotData(w io.Writer, b blobRef, size int64) error {
+	var n8 [8]byte
+	binary.BigEndian.PutUint64(n8[:], uint64(size))
+	if _, err := w.Write(n8[:]); err != nil {
+		return err
+	}
+	rc, err := s.openBlob(b, size)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.CopyN(w, rc, size)
+	return err
+}
+
+// LoadSnapshot 从 r 恢复文件。要求 store 为空；快照校验通过后才一次性装载。
+// 若快照超出当前上限（例如配置调小），按淘汰策略丢弃文件。
+func (s *InMemoryStore) LoadSnapshot(r io.Reader) error {
+	snap, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.byID) != 0 || len(s.folders) != 0 || len(s.trash) != 0 {
+		return fmt.Errorf("%w: store is not empty", ErrInvalidInput)
+	}
+	if err := s.loadSnapshotLocked(snap); err != nil {
+		return err
+	}
+	s.removeBlobs(s.fitLimitsLocked())
+	purged, _ := s.sweepTrashLocked(time.Now())
+	s.removeBlobs(purged)
+	return s.commitLocked()
+}
+
+func (s *engine) loadSnapshotLocked(snap snapshotState) error {
+	for _, f := range snap.folders {
+		s.addFolderLocked(f)
+	}
+	for _, it := range snap.items {
+		en, err := s.snapshotEntryLocked(it)
+		if err != nil {
+			return err
+		}
+		s.insertLocked(en)
+	}
+	for _, it := range snap.trash {
+		en, err := s.snapshotEntryLocked(it)
+		if err != nil {
+			return err
+		}
+		s.loadTrashLocked(en, it.deletedAt, it.evicted)
+	}
+	return nil
+}
+
+func (s *engine) snapshotEntryLocked(it snapshotItem) (*entry, error) {
+	key, sum, err := s.storeBlobLocked(it.meta.SHA256, it.data)
+	if err != nil {
+		return nil, err
+	}
+	en := &entry{meta: it.meta, blobKey: key}
+	en.meta.SHA256 = sum
+	for _, v := range it.versions {
+		vkey, vsum, err := s.storeBlobLocked(v.meta.SHA256, v.data)
+		if err != nil {
+			return nil, err
+		}
+		vm := v.meta
+		vm.SHA256 = vsum
+		en.versions = append(en.versions, version{VersionMeta: vm, blobKey: vkey})
+	}
+	return en, nil
+}
+
+func readSnapshot(r io.Reader) (snapshotState, error) {
+	h := sha256.New()
+	tr := io.TeeReader(bufio.NewReader(r), h)
+
+	var hdr [8 + 4 + 8]byte
+	if _, err := io.ReadFull(tr, hdr[:]); err != nil {
+		return snapshotState{}, snapshotCorrupt("header", err)
+	}
+	if string(hdr[:8]) != snapshotMagic {
+		return snapshotState{}, fmt.Errorf("%w: bad magic", ErrSnapshotCorrupt)
+	}
+	format := binary.BigEndian.Uint32(hdr[8:12])
+	if format < 1 || format > snapshotFormat {
+		return snapshotState{}, fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupt, format)
+	}
+	count := binary.BigEndian.Uint64(hdr[12:20])
+
+	var (
+		snap  snapshotState
+		ids   = make(map[string]struct{})
+		names = make(map[nameKey]struct{})
+	)
+	for i := uint64(0); i < count; i++ {
+		it, err := readSnapshotItem(tr, format, ids)
+		if err != nil {
+			return snapshotState{}, err
+		}
+		key := nameKey{folder: it.meta.Folder, name: it.meta.Name}
+		if _, dup := names[key]; dup {
+			return snapshotState{}, fmt.Errorf("%w: duplicate name %q", ErrSnapshotCorrupt, it.meta.Name)
+		}
+		names[key] = struct{}{}
+		snap.items = append(snap.items, it)
+	}
+
+	if format >= 3 {
+		var n4 [4]byte
+		if _, err := io.ReadFull(tr, n4[:]); err != nil {
+			return snapshotState{}, snapshotCorrupt("folders header", err)
+		}
+		n := binary.BigEndian.Uint32(n4[:])
+		if n > maxSnapshotFolderBytes {
+			return snapshotState{}, fmt.Errorf("%w: bad folders length %d", ErrSnapshotCorrupt, n)
+		}
+		fb, err := readExactly(tr, int64(n))
+		if err != nil {
+			return snapshotState{}, snapshotCorrupt("folders", err)
+		}
+		if err := json.Unmarshal(fb, &snap.folders); err != nil {
+			return snapshotState{}, snapshotCorrupt("folders", err)
+		}
+		for _, f := range snap.folders {
+			if f == "" || !validFolder(f) {
+				return snapshotState{}, fmt.Errorf("%w: bad folder %q", ErrSnapshotCorrupt, f)
+			}
+		}
+	}
+
+	if format >= 4 {
+		var n8 [8]byte
+		if _, err := io.ReadFull(tr, n8[:]); err != nil {
+			return snapshotState{}, snapshotCorrupt("trash header", err)
+		}
+		// 回收站中的文件不占文件名，只要求 ID 与文件及其他条目都不重复。
+		for i, n := uint64(0), binary.BigEndian.Uint64(n8[:]); i < n; i++ {
+			it, err := readSnapshotItem(tr, format, ids)
+			if err != nil {
+				return snapshotState{}, err
+			}
+			snap.trash = append(snap.trash, it)
+		}
+	}
+
+	want := h.Sum(nil)
+	var got [sha256.Size]byte
+	if _, err := io.ReadFull(tr, got[:]); err != nil {
+		return snapshotState{}, snapshotCorrupt("checksum", err)
+	}
+	if !bytes.Equal(got[:], want) {
+		return snapshotState{}, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+	return snap, nil
+}
+
+// readSnapshotItem 读取并校验一个条目，ID 登记到 ids 中用于查重。
+func readSnapshotItem(r io.Reader, format uint32, ids map[string]struct{}) (snapshotItem, error) {
+	var n4 [4]byte
+	if _, err := io.ReadFull(r, n4[:]); err != nil {
+		return snapshotItem{}, snapshotCorrupt("entry header", err)
+	}
+	metaLen := binary.BigEndian.Uint32(n4[:])
+	if metaLen == 0 || metaLen > maxSnapshotMetaBytes {
+		return snapshotItem{}, fmt.Errorf("%w: bad meta length %d", ErrSnapshotCorrupt, metaLen)
+	}
+	mb, err := readExactly(r, int64(metaLen))
+	if err != nil {
+		return snapshotItem{}, snapshotCorrupt("meta", err)
+	}
+	var sm snapshotMeta
+	if err := json.Unmarshal(mb, &sm); err != nil {
+		return snapshotItem{}, snapshotCorrupt("meta", err)
+	}
+	if format < 2 {
+		sm.Versions = nil
+	}
+
+	data, err := readSnapshotData(r, sm.SizeBytes)
+	if err != nil {
+		return snapshotItem{}, fmt.Errorf("%s: %w", sm.ID, err)
+	}
+	it := snapshotItem{meta: sm.FileMeta, data: data, deletedAt: sm.DeletedAt, evicted: sm.Evicted}
+	seen := map[int]struct{}{sm.Version: {}}
+	for _, vm := range sm.Versions {
+		if _, dup := seen[vm.Version]; dup || vm.Version <= 0 {
+			return snapshotItem{}, fmt.Errorf("%w: bad version %d for %s", ErrSnapshotCorrupt, vm.Version, sm.ID)
+		}
+		seen[vm.Version] = struct{}{}
+		vd, err := readSnapshotData(r, vm.SizeBytes)
+		if err != nil {
+			return snapshotItem{}, fmt.Errorf("%s@%d: %w", sm.ID, vm.Version, err)
+		}
+		it.versions = append(it.versions, snapshotVersion{meta: vm, data: vd})
+	}
+
+	if sm.ID == "" || sm.Name == "" {
+		return snapshotItem{}, fmt.Errorf("%w: empty id/name", ErrSnapshotCorrupt)
+	}
+	if !validFolder(sm.Folder) {
+		return snapshotItem{}, fmt.Errorf("%w: bad folder %q", ErrSnapshotCorrupt, sm.Folder)
+	}
+	if _, dup := ids[sm.ID]; dup {
+		return snapshotItem{}, fmt.Errorf("%w: duplicate id %s", ErrSnapshotCorrupt, sm.ID)
+	}
+	ids[sm.ID] = struct{}{}
+	return it, nil
+}
+
+func readSnapshotData(r io.Reader, size int64) ([]byte, error) {
+	var n8 [8]byte
+	if _, err := io.ReadFull(r, n8[:]); err != nil {
+		return nil, snapshotCorrupt("data length", err)
+	}
+	if n := binary.BigEndian.Uint64(n8[:]); n != uint64(size) {
+		return nil, fmt.Errorf("%w: size mismatch", ErrSnapshotCorrupt)
+	}
+	data, err := readExactly(r, size)
+	if err != nil {
+		return nil, snapshotCorrupt("data", err)
+	}
+	return data, nil
+}
+
+// readExactly 按实际到达的数据增长缓冲区，避免损坏的长度字段触发超大内存分配。
+func readExactly(r io.Reader, n int64) ([]byte, error) {
+	const initialCap = 1 << 20
+	buf := bytes.NewBuffer(make([]byte, 0, min(n, initialCap)))
+	if _, err := io.CopyN(buf, r, n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func snapshotCorrupt(what string, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated %s", ErrSnapshotCorrupt, what)
+	}
+	return fmt.Errorf("%w: %s: %v", ErrSnapshotCorrupt, what, err)
+}
+
+// SaveSnapshotFile 以“临时文件 + rename”的方式写快照，写到一半崩溃不会破坏旧快照。
+func (s *InMemoryStore) SaveSnapshotFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+	return writeFileAtomicFunc(path, s.WriteSnapshot)
+}
+
+// LoadSnapshotFile 读取快照文件；文件不存在时视为空快照（首次启动）。
+func (s *InMemoryStore) LoadSnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return s.LoadSnapshot(f)
+}
+
+type SnapshotterOptions struct {
+	Path     string
+	Interval time.Duration
+	// OnError 接收周期保存失败的错误（可为 nil）。
+	OnError func(error)
+}
+
+// Snapshotter 周期性地把 InMemoryStore 写入快照文件（仅在有变更时写），Close 时再写一次。
+type Snapshotter struct {
+	store   *InMemoryStore
+	path    string
+	onError func(error)
+
+	mu      sync.Mutex
+	lastRev uint64
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewSnapshotter(s *InMemoryStore, opt SnapshotterOptions) *Snapshotter {
+	p := &Snapshotter{
+		store:   s,
+		path:    opt.Path,
+		onError: opt.OnError,
+		lastRev: s.Revision(),
+		stopCh:  make(chan struct{}),
+	}
+
+	if opt.Interval > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			ticker := time.NewTicker(opt.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := p.Save(); err != nil && p.onError != nil {
+						p.onError(err)
+					}
+				case <-p.stopCh:
+					return
+				}
+			}
+		}()
+	}
+
+	return p
+}
+
+// Save 在自上次保存以来有变更时写快照。
+func (p *Snapshotter) Save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rev := p.store.Revision()
+	if rev == p.lastRev {
+		return nil
+	}
+	if err := p.store.SaveSnapshotFile(p.path); err != nil {
+		return err
+	}
+	p.lastRev = rev
+	return nil
+}
+
+// Close 停止周期任务并写最后一次快照（用于优雅退出）。
+func (p *Snapshotter) Close() error {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.wg.Wait()
+	return p.Save()
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTripKeepsFIFOOrder(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello"), Encoding: "UTF-8", IsText: true, Now: time.Unix(1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(AddParams{Name: "b.bin", Bytes: []byte{0, 1, 2}, Now: time.Unix(2, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+
+	restored, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadSnapshot(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+
+	items := restored.List()
+	if len(items) != 2 || items[0].ID != a.ID || items[1].ID != b.ID {
+		t.Fatalf("expected FIFO order kept, got %#v", items)
+	}
+	if !reflect.DeepEqual(items[0], a) {
+		t.Fatalf("expected meta kept, got %#v want %#v", items[0], a)
+	}
+	got, err := restored.Get(b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes, []byte{0, 1, 2}) {
+		t.Fatalf("unexpected bytes: %v", got.Bytes)
+	}
+	if st := restored.Stats(); st.Files != 2 || st.PhysicalBytes != 8 {
+		t.Fatalf("unexpected stats: files=%d total=%d", st.Files, st.PhysicalBytes)
+	}
+}
+
+func TestSnapshotTruncatedRejectedWithoutPartialLoad(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if _, err := s.Add(AddParams{Name: name, Bytes: []byte("content of " + name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := s.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+
+	for _, cut := range []int{0, 10, len(full) / 2, len(full) - 1} {
+		restored, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = restored.LoadSnapshot(bytes.NewReader(full[:cut]))
+		if !errors.Is(err, ErrSnapshotCorrupt) {
+			t.Fatalf("cut=%d: expected ErrSnapshotCorrupt, got %v", cut, err)
+		}
+		if files := restored.Stats().Files; files != 0 {
+			t.Fatalf("cut=%d: expected nothing loaded, got %d files", cut, files)
+		}
+	}
+}
+
+func TestSnapshotChecksumMismatchRejected(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]byte(nil), buf.Bytes()...)
+	corrupted[len(corrupted)-40] ^= 0xFF // 翻转内容区的一个字节
+
+	restored, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadSnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("expected ErrSnapshotCorrupt, got %v", err)
+	}
+}
+
+func TestSnapshotterSavesOnlyWhenChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap", "snapshot.bin")
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := NewSnapshotter(s, SnapshotterOptions{Path: path})
+
+	if err := snap.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadSnapshotFile(path); err != nil {
+		t.Fatalf("expected missing snapshot tolerated, got %v", err)
+	}
+
+	if _, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	restored, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadSnapshotFile(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if items := restored.List(); len(items) != 1 || items[0].Name != "a.txt" {
+		t.Fatalf("unexpected restored items: %#v", items)
+	}
+}
+
+func TestSnapshotHoldsContentRemovedWhileWriting(t *testing.T) {
+	content := bytes.Repeat([]byte("snapshot streams compressed content outside the lock\n"), 100)
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := s.byID[a.ID].blobKey
+
+	// 复制元数据之后、写出内容之前文件被删除：内容须保留到快照写完。
+	s.mu.Lock()
+	snap := s.snapshotLocked()
+	s.mu.Unlock()
+	if _, err := s.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.blobs.get(key); err != nil {
+		t.Fatalf("expected held content kept: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := s.writeSnapshot(&buf, snap); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	s.unhold(snap.held)
+	if _, err := s.blobs.get(key); err == nil {
+		t.Fatal("expected content removed after unhold")
+	}
+	if st := s.Stats(); st.Files != 0 || st.PhysicalBytes != 0 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+
+	restored, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	got, err := restored.Get(a.ID)
+	if err != nil || !bytes.Equal(got.Bytes, content) {
+		t.Fatalf("unexpected restored content: %v", err)
+	}
+}
@@ -1,22 +1,109 @@
 package store
 
 import (
-	"bytes"
 	"container/list"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 )
 
+// FileStore 是 httpapi 依赖的文件仓库抽象。
+// 实现需保证并发安全，并遵守同一套口径：文件名区分大小写且在所在目录内唯一、超限按淘汰策略（默认 FIFO）淘汰、
+// ReplaceBytes 超出总量时严格失败且不修改原内容；相同内容按 SHA-256 共享存储，总量按去重后的字节计算。
+type FileStore interface {
+	Add(p AddParams) (FileMeta, error)
+	Get(id string) (File, error)
+	Open(id string) (FileMeta, io.ReadSeekCloser, error)
+	GetMeta(id string) (FileMeta, error)
+	List() []FileMeta
+	Query(q ListQuery) (ListPage, error)
+	HasName(folder, name string) bool
+	CleanName(name string) (string, error)
+	Rename(id string, newName string) (FileMeta, error)
+	RenameIf(id string, newName string, cond IfMatch) (FileMeta, error)
+	RenameWith(p RenameParams) (FileMeta, error)
+	Copy(id, newName string) (FileMeta, error)
+	Delete(id string) (FileMeta, error)
+	DeleteIf(id string, cond IfMatch) (FileMeta, error)
+	ReplaceBytes(p ReplaceParams) (FileMeta, error)
+	Reserve(size int64) (*Reservation, error)
+	CheckAdmission(folder, name string, size int64) (Admission, error)
+	Stats() Stats
+	RecentEvictions() []EvictionRecord
+
+	ListVersions(id string) ([]VersionMeta, error)
+	OpenVersion(id string, version int) (FileMeta, io.ReadSeekCloser, error)
+	RestoreVersion(id string, version int) (FileMeta, error)
+
+	SetPinned(id string, pinned bool) (FileMeta, error)
+	SetTags(id string, tags []string) (FileMeta, error)
+	SetNote(id string, note string) (FileMeta, error)
+	SweepExpired(now time.Time) (int, error)
+
+	Folders() []string
+	HasFolder(path string) bool
+	ListFolder(folder string) ([]FileMeta, error)
+	CreateFolder(path string) error
+	RenameFolder(from, to string) error
+	DeleteFolder(path string, recursive bool) (int, error)
+	Move(id, folder string) (FileMeta, error)
+
+	ListTrash() []TrashItem
+	RestoreTrash(id string) (FileMeta, error)
+	PurgeTrash(id string) error
+	EmptyTrash() (int, error)
+}
+
+var (
+	_ FileStore = (*InMemoryStore)(nil)
+	_ FileStore = (*DiskStore)(nil)
+	_ FileStore = (*S3Store)(nil)
+)
+
 type FileMeta struct {
-	ID        string
-	Name      string
+	ID   string
+	Name string
+	// Folder 为所在目录（"a/b" 形式，根目录为 ""），见 folder.go。
+	Folder    string
 	CreatedAt time.Time
 	SizeBytes int64
 	Encoding  string
 	IsText    bool
+	// Version 为当前内容的版本号（从 1 开始），UpdatedAt 为当前内容的写入时间。
+	Version   int
+	UpdatedAt time.Time
+	// PrevVersions 为保留的历史版本数。
+	PrevVersions int
+	// SHA256 为当前内容的十六进制摘要，用于去重与条件请求（见 IfMatch）；旧数据可能为空。
+	SHA256 string
+	// Pinned 为 true 时不参与自动淘汰。
+	Pinned bool
+	// ExpiresAt 为过期时间（零值表示永不过期），过期后不可读取并由 SweepExpired 回收。
+	ExpiresAt time.Time
+	// Tags（已规范化、有序）与 Note 为用户附加的标签与备注，见 annotate.go。
+	Tags []string
+	Note string
+}
+
+// Stats 为用量统计。LogicalBytes 为各文件（含历史版本）大小之和；
+// PhysicalBytes 为去重、压缩后实际占用的字节数，MaxTotalBytes 按它计算；
+// CompressionRatio 为去重后的内容大小与 PhysicalBytes 之比（未压缩时为 1）。
+// TrashFiles/TrashBytes 为回收站的条目数与只被回收站引用的物理字节数，不计入以上各项。
+// ReservedBytes 为进行中的上传预留的字节数（见 reserve.go）；MaxFiles/MaxTotalBytes 为配置的上限。
+type Stats struct {
+	Files            int
+	LogicalBytes     int64
+	PhysicalBytes    int64
+	CompressionRatio float64
+	TrashFiles       int
+	TrashBytes       int64
+	ReservedBytes    int64
+	MaxFiles         int
+	MaxTotalBytes    int64
 }
 
 type File struct {
@@ -24,208 +111,523 @@ type File struct {
 	Bytes []byte
 }
 
+// InMemoryStore 把文件内容保存在进程内存中，重启即丢。
 type InMemoryStore struct {
-	maxFiles      int
-	maxTotalBytes int64
-
-	mu         sync.RWMutex
-	byID       map[string]*entry
-	byName     map[string]string
-	fifo       *list.List
-	totalBytes int64
-}
-
-type entry struct {
-	meta FileMeta
-	data []byte
-	elem *list.Element
+	*engine
 }
 
 type NewParams struct {
 	MaxFiles      int
 	MaxTotalBytes int64
+	// MaxVersions 为每个文件最多保留的历史版本数（0 表示不保留）。
+	MaxVersions int
+	// EvictionPolicy 为超限时的淘汰策略（EvictionFIFO 等，空表示 FIFO）。
+	EvictionPolicy string
+	// MaxPinnedBytes 为置顶文件（含历史版本）的总量上限，须小于 MaxTotalBytes；0 表示不允许置顶。
+	MaxPinnedBytes int64
+	// Compression 为静态压缩方式（CompressionGzip，空或 CompressionNone 表示不压缩）。
+	Compression string
+	// TrashTTL 为删除的文件在回收站中保留的时长，0 表示不启用回收站（删除即回收）。
+	// MaxTrashBytes 为回收站的总量上限（独立于 MaxTotalBytes），启用回收站时须 > 0。
+	// TrashEvicted 为 true 时被自动淘汰的文件也放入回收站。见 trash.go。
+	TrashTTL      time.Duration
+	MaxTrashBytes int64
+	TrashEvicted  bool
+	// NamePolicy 为新文件名的规则，见 names.go。
+	NamePolicy NamePolicy
 }
 
 func NewInMemoryStore(p NewParams) (*InMemoryStore, error) {
+	e, err := newEngine(p, newMemBlobs())
+	if err != nil {
+		return nil, err
+	}
+	return &InMemoryStore{engine: e}, nil
+}
+
+// engine 维护元数据、索引、上传顺序、淘汰策略与总量统计；文件内容交给 blobBackend 存取。
+// 各存储实现共用同一份淘汰/重名/替换逻辑，只在内容落地方式上不同。
+type engine struct {
+	// params 为创建参数，日志追加失败时据此重建状态（见 journal.go）。
+	params        NewParams
+	maxFiles      int
+	maxTotalBytes int64
+	maxVersions   int
+	maxPinned     int64
+	compression   string
+	trashTTL      time.Duration
+	maxTrash      int64
+	trashEvicted  bool
+	names         NamePolicy
+	blobs         blobBackend
+
+	// index 非 nil 时，变更提交后由它在锁外把索引落盘，不再被引用的内容也由它延后删除（见 indexwriter.go）。
+	index *indexWriter
+	// journal 非 nil 时，每次变更产生的记录在 commitLocked 中一次性追加到操作日志。
+	journal func(recs []journalRecord) error
+	pending []journalRecord
+
+	mu     sync.RWMutex
+	byID   map[string]*entry
+	byName map[nameKey]string
+	// folders 为除根目录外的全部目录。
+	folders map[string]struct{}
+	// fifo 为上传顺序（List 按此顺序返回），policy 决定淘汰顺序。
+	fifo   *list.List
+	policy evictionPolicy
+	// seq 为最近一次加入 fifo 的上传序号（见 entry.seq）。
+	seq uint64
+	// views 为供读路径使用的分片只读索引，touches 为尚未交给淘汰策略的读取记录（见 views.go）。
+	views        *viewIndex
+	trackReads   bool
+	touchMu      sync.Mutex
+	touches      []string
+	spareTouches []string
+	// blobRefs 按 key 记录 blob 的引用计数，bySum 按内容摘要索引可复用的 blob（见 dedup.go）。
+	blobRefs map[string]*blobRef
+	bySum    map[string]*blobRef
+	// totalBytes 为去重、压缩后的物理占用（含历史版本），rawBytes 为去重后、压缩前的合计，
+	// logicalBytes 为去重前的合计。
+	totalBytes   int64
+	rawBytes     int64
+	logicalBytes int64
+	// pinnedBytes/pinnedFiles 为置顶文件的逻辑用量与个数（见 pin.go）。
+	pinnedBytes int64
+	pinnedFiles int
+	// trash 为回收站条目（按 ID），trashList 为删除顺序（最早在前）；
+	// trashBytes 为只被回收站引用的内容的物理占用，不计入 totalBytes。
+	trash      map[string]*trashEntry
+	trashList  *list.List
+	trashBytes int64
+	// reservedBytes/reservedFiles 为尚未结束的上传预留（见 reserve.go），淘汰与上限检查视同已占用。
+	reservedBytes int64
+	reservedFiles int
+	// evictions 为最近的淘汰记录（最早在前，见 evictlog.go）。
+	evictions []EvictionRecord
+	// rev 每次变更递增，供快照等判断“自上次保存后是否有变化”。
+	rev uint64
+}
+
+type entry struct {
+	meta    FileMeta
+	blobKey string
+	// versions 为历史版本，按成为历史的先后排列（最老在前）。
+	versions []version
+	elem     *list.Element
+	// seq 为加入 fifo 时的序号，沿 fifo 递增，供按上传顺序翻页的游标定位。
+	seq uint64
+
+	// 以下字段由淘汰策略维护。
+	policyElem *list.Element
+	heapIndex  int
+	hits       uint64
+	lastUse    uint64
+}
+
+func newEngine(p NewParams, blobs blobBackend) (*engine, error) {
 	if p.MaxFiles <= 0 || p.MaxTotalBytes <= 0 {
 		return nil, fmt.Errorf("%w: max_files/max_total_bytes must be > 0", ErrInvalidInput)
 	}
-	return &InMemoryStore{
+	if p.MaxVersions < 0 {
+		return nil, fmt.Errorf("%w: max_versions must be >= 0", ErrInvalidInput)
+	}
+	if p.MaxPinnedBytes < 0 || p.MaxPinnedBytes >= p.MaxTotalBytes {
+		return nil, fmt.Errorf("%w: max_pinned_bytes must be >= 0 and < max_total_bytes", ErrInvalidInput)
+	}
+	if !validCompression(p.Compression) {
+		return nil, fmt.Errorf("%w: unknown compression %q", ErrInvalidInput, p.Compression)
+	}
+	if p.TrashTTL < 0 || p.MaxTrashBytes < 0 || (p.TrashTTL > 0 && p.MaxTrashBytes == 0) {
+		return nil, fmt.Errorf("%w: trash_ttl must be >= 0 and max_trash_bytes must be > 0 when trash is enabled", ErrInvalidInput)
+	}
+	if err := p.NamePolicy.validate(); err != nil {
+		return nil, err
+	}
+	fifo := list.New()
+	policy, err := newEvictionPolicy(p.EvictionPolicy, fifo)
+	if err != nil {
+		return nil, err
+	}
+	return &engine{
+		params:        p,
 		maxFiles:      p.MaxFiles,
 		maxTotalBytes: p.MaxTotalBytes,
+		maxVersions:   p.MaxVersions,
+		maxPinned:     p.MaxPinnedBytes,
+		compression:   p.Compression,
+		trashTTL:      p.TrashTTL,
+		maxTrash:      p.MaxTrashBytes,
+		trashEvicted:  p.TrashEvicted,
+		names:         p.NamePolicy,
+		blobs:         blobs,
 		byID:          make(map[string]*entry),
-		byName:        make(map[string]string),
-		fifo:          list.New(),
+		byName:        make(map[nameKey]string),
+		folders:       make(map[string]struct{}),
+		fifo:          fifo,
+		policy:        policy,
+		views:         newViewIndex(),
+		trackReads:    p.EvictionPolicy == EvictionLRU || p.EvictionPolicy == EvictionLFU,
+		blobRefs:      make(map[string]*blobRef),
+		bySum:         make(map[string]*blobRef),
+		trash:         make(map[string]*trashEntry),
+		trashList:     list.New(),
 	}, nil
 }
 
-func (s *InMemoryStore) Limits() (maxFiles int, maxTotalBytes int64) {
+func (s *engine) Limits() (maxFiles int, maxTotalBytes int64) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.maxFiles, s.maxTotalBytes
 }
 
-func (s *InMemoryStore) Stats() (files int, totalBytes int64) {
+func (s *engine) Stats() Stats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.byID), s.totalBytes
+	st := Stats{
+		Files:            len(s.byID),
+		LogicalBytes:     s.logicalBytes,
+		PhysicalBytes:    s.totalBytes,
+		CompressionRatio: 1,
+		TrashFiles:       len(s.trash),
+		TrashBytes:       s.trashBytes,
+		ReservedBytes:    s.reservedBytes,
+		MaxFiles:         s.maxFiles,
+		MaxTotalBytes:    s.maxTotalBytes,
+	}
+	if s.totalBytes > 0 {
+		st.CompressionRatio = float64(s.rawBytes) / float64(s.totalBytes)
+	}
+	return st
 }
 
-func (s *InMemoryStore) HasName(name string) bool {
+// Revision 返回当前修订号；内容或元数据每变更一次递增一次。
+func (s *engine) Revision() uint64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	_, ok := s.byName[name]
-	return ok
+	return s.rev
 }
 
-// EvictToFit 会尽最大努力在当前 store 内进行 FIFO 淘汰，使“incomingSize”有机会被加入。
-// 注意：该函数不做“预留”，只用于上传前的最佳努力预处理；最终是否能加入仍以 Add 为准。
-func (s *InMemoryStore) EvictToFit(incomingSize int64) error {
-	if incomingSize < 0 {
-		return fmt.Errorf("%w: incomingSize must be >= 0", ErrInvalidInput)
-	}
-	if incomingSize > s.maxTotalBytes {
-		return ErrTooLarge
+func (s *engine) HasName(folder, name string) bool {
+	k := nameKey{folder: folder, name: name}
+	id, ok := s.views.lookupName(k)
+	if !ok {
+		return false
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.evictLocked(incomingSize)
+	v := s.views.get(id)
+	return v != nil && v.nameKey() == k && !v.meta.Expired(time.Now())
 }
 
-func (s *InMemoryStore) List() []FileMeta {
+func (s *engine) List() []FileMeta {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	now := time.Now()
 	out := make([]FileMeta, 0, len(s.byID))
 	for e := s.fifo.Front(); e != nil; e = e.Next() {
 		en := e.Value.(*entry)
+		if en.meta.Expired(now) {
+			continue
+		}
 		out = append(out, en.meta)
 	}
 	return out
 }
 
-func (s *InMemoryStore) GetMeta(id string) (FileMeta, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	en, ok := s.byID[id]
-	if !ok {
+func (s *engine) GetMeta(id string) (FileMeta, error) {
+	v := s.views.get(id)
+	if v == nil || v.meta.Expired(time.Now()) {
 		return FileMeta{}, ErrNotFound
 	}
-	return en.meta, nil
+	return v.meta, nil
 }
 
-// Get returns the stored bytes slice by reference (read-only contract).
-// It is safe to keep the returned slice for reading even if ReplaceBytes happens later,
-// because ReplaceBytes swaps to a new slice and does not mutate the old one.
-func (s *InMemoryStore) Get(id string) (File, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// Get returns the stored bytes. For the in-memory backend the slice is returned by
+// reference (read-only contract); it stays valid even if ReplaceBytes happens later,
+// because ReplaceBytes swaps to a new blob and does not mutate the old one.
+func (s *engine) Get(id string) (File, error) {
+	var f File
+	err := s.withBlob(id, 0, func(meta FileMeta, blob blobRef) error {
+		b, err := s.readBlob(blob.key, meta.SizeBytes)
+		if err != nil {
+			return err
+		}
+		f = File{Meta: meta, Bytes: b}
+		return nil
+	})
+	return f, err
+}
 
-	en, ok := s.byID[id]
-	if !ok {
-		return File{}, ErrNotFound
-	}
-	return File{Meta: en.meta, Bytes: en.data}, nil
+// Open 返回可 Seek 的内容流，调用方负责 Close。磁盘等后端不会把整个文件读入内存。
+func (s *engine) Open(id string) (FileMeta, io.ReadSeekCloser, error) {
+	return s.openVersion(id, 0)
 }
 
-func (s *InMemoryStore) Open(id string) (FileMeta, *bytes.Reader, error) {
-	f, err := s.Get(id)
-	if err != nil {
-		return FileMeta{}, nil, err
+func (s *engine) openVersion(id string, version int) (FileMeta, io.ReadSeekCloser, error) {
+	var (
+		meta FileMeta
+		rc   io.ReadSeekCloser
+	)
+	err := s.withBlob(id, version, func(m FileMeta, blob blobRef) error {
+		r, err := s.openBlob(blob, m.SizeBytes)
+		if err != nil {
+			return err
+		}
+		meta, rc = m, r
+		return nil
+	})
+	return meta, rc, err
+}
+
+// withBlob 按已发布的快照读取内容，不持 engine.mu；若读取期间内容被并发替换/删除，则重新查快照后重试。
+// version 为 0 表示当前版本，否则读取对应版本（meta 中的大小/编码为该版本的值）。
+func (s *engine) withBlob(id string, version int, fn func(meta FileMeta, blob blobRef) error) error {
+	const maxAttempts = 3
+	for attempt := 1; ; attempt++ {
+		v := s.views.get(id)
+		if v == nil || v.meta.Expired(time.Now()) {
+			return ErrNotFound
+		}
+		meta, blob, err := v.version(version)
+		if err != nil {
+			return err
+		}
+		if attempt == 1 && !v.meta.Pinned {
+			s.recordTouch(id)
+		}
+
+		err = fn(meta, blob)
+		if err == nil || !errors.Is(err, errBlobNotFound) || attempt >= maxAttempts {
+			return err
+		}
 	}
-	return f.Meta, bytes.NewReader(f.Bytes), nil
 }
 
 type AddParams struct {
-	Name     string
+	Name string
+	// Folder 为目标目录（须已存在，空表示根目录）。
+	Folder   string
 	Bytes    []byte
 	Encoding string
 	IsText   bool
 	Now      time.Time
+	// ExpiresAt 可选，须晚于 Now。
+	ExpiresAt time.Time
+	// Reservation 为 Reserve 返回的预留（可为 nil）：内容按实际新增的用量从中扣除，提交成功后预留结束。
+	Reservation *Reservation
+	// OnConflict 为目录中已有同名文件时的处理策略（见 conflict.go），空串表示拒绝。
+	// ConflictOverwrite 时返回的是写入了新版本的同名文件，其有效期等属性保持不变。
+	OnConflict string
 }
 
-func (s *InMemoryStore) Add(p AddParams) (FileMeta, error) {
+func (s *engine) Add(p AddParams) (FileMeta, error) {
 	if p.Now.IsZero() {
 		p.Now = time.Now()
 	}
-	if p.Name == "" {
-		return FileMeta{}, fmt.Errorf("%w: name is required", ErrInvalidInput)
+	name, err := s.CleanName(p.Name)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	p.Name = name
+	if err := CheckConflictPolicy(p.OnConflict); err != nil {
+		return FileMeta{}, err
+	}
+	folder, err := CleanFolder(p.Folder)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	p.Folder = folder
+	if !p.ExpiresAt.IsZero() && !p.ExpiresAt.After(p.Now) {
+		return FileMeta{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInput)
+	}
+	if p.Reservation != nil && p.Reservation.s != s {
+		return FileMeta{}, fmt.Errorf("%w: reservation belongs to another store", ErrInvalidInput)
 	}
 	size := int64(len(p.Bytes))
 	if size < 0 {
 		return FileMeta{}, fmt.Errorf("%w: invalid bytes", ErrInvalidInput)
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if size > s.maxTotalBytes && !s.compresses() {
+		return FileMeta{}, ErrTooLarge
+	}
 
-	if _, exists := s.byName[p.Name]; exists {
-		return FileMeta{}, ErrNameConflict
+	// 内容先写入后端（不持锁），提交失败时再回收，避免大文件 I/O 阻塞其他请求。
+	// 相同内容已存在时不再写入，直接共享。
+	sum := contentSum(p.Bytes)
+	key, stored, err := s.prepareBlob(sum, p.Bytes, p.Reservation)
+	if err != nil {
+		return FileMeta{}, fmt.Errorf("store content: %w", err)
 	}
-	if size > s.maxTotalBytes {
+	if stored > s.maxTotalBytes {
+		s.discardBlob(key)
 		return FileMeta{}, ErrTooLarge
 	}
 
-	if err := s.evictLocked(size); err != nil {
-		return FileMeta{}, err
+	s.mu.Lock()
+	meta, evicted, err := s.addLocked(p, size, key, sum)
+	evicted = append(evicted, s.releaseLocked(key)...)
+	s.mu.Unlock()
+
+	s.removeBlobs(evicted)
+	return meta, err
+}
+
+func (s *engine) addLocked(p AddParams, size int64, key, sum string) (FileMeta, []string, error) {
+	if !s.folderExistsLocked(p.Folder) {
+		return FileMeta{}, nil, ErrFolderNotFound
+	}
+	// 内容已由 prepareBlob 计入物理用量（并相应扣减了预留），这里结束预留，只需保证总量不超限。
+	if holder := s.liveNameLocked(p.Folder, p.Name); holder != nil {
+		switch p.OnConflict {
+		case ConflictOverwrite:
+			p.Reservation.endLocked()
+			return s.replaceLocked(ReplaceParams{ID: holder.meta.ID, Bytes: p.Bytes, Encoding: p.Encoding, IsText: p.IsText, Now: p.Now}, size, key, sum)
+		case ConflictSuffix, ConflictKeepBoth:
+		default:
+			return FileMeta{}, nil, ErrNameConflict
+		}
+	}
+	p.Reservation.endLocked()
+	evicted, err := s.evictLocked(0, filePath(p.Folder, p.Name))
+	if err != nil {
+		if len(evicted) > 0 {
+			_ = s.commitLocked()
+		}
+		return FileMeta{}, evicted, err
+	}
+	// 淘汰之后再占用名称：放不下时不会改动同名文件。
+	name, _, expired, err := s.claimNameLocked(p.Folder, p.Name, p.OnConflict, nil)
+	evicted = append(evicted, expired...)
+	if err != nil {
+		if len(evicted) > 0 {
+			_ = s.commitLocked()
+		}
+		return FileMeta{}, evicted, err
 	}
 
 	id := newID()
 	meta := FileMeta{
 		ID:        id,
-		Name:      p.Name,
+		Name:      name,
+		Folder:    p.Folder,
 		CreatedAt: p.Now.UTC(),
 		SizeBytes: size,
 		Encoding:  p.Encoding,
 		IsText:    p.IsText,
+		Version:   1,
+		UpdatedAt: p.Now.UTC(),
+		SHA256:    sum,
 	}
-	en := &entry{meta: meta, data: p.Bytes}
-	en.elem = s.fifo.PushBack(en)
+	if !p.ExpiresAt.IsZero() {
+		meta.ExpiresAt = p.ExpiresAt.UTC()
+	}
+	s.insertLocked(&entry{meta: meta, blobKey: key})
+	s.logLocked(journalRecord{Op: opAdd, Meta: meta, Data: p.Bytes})
 
-	s.byID[id] = en
-	s.byName[p.Name] = id
-	s.totalBytes += size
+	return meta, evicted, s.commitLocked()
+}
 
-	return meta, nil
+func (s *engine) insertLocked(en *entry) {
+	// 兼容引入版本历史之前落盘的元数据。
+	if en.meta.Version <= 0 {
+		en.meta.Version = 1
+	}
+	if en.meta.UpdatedAt.IsZero() {
+		en.meta.UpdatedAt = en.meta.CreatedAt
+	}
+	en.meta.PrevVersions = len(en.versions)
+	s.seq++
+	en.seq = s.seq
+	en.elem = s.fifo.PushBack(en)
+	if !en.meta.Pinned {
+		s.policy.add(en)
+	}
+	s.byID[en.meta.ID] = en
+	s.byName[en.nameKey()] = en.meta.ID
+	s.addFolderLocked(en.meta.Folder)
+	for _, key := range en.blobKeys() {
+		s.retainLocked(key)
+	}
+	s.accountLocked(en)
+	s.publishLocked(en)
 }
 
-func (s *InMemoryStore) Delete(id string) (FileMeta, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *engine) Delete(id string) (FileMeta, error) {
+	return s.DeleteIf(id, nil)
+}
 
+// DeleteIf 在当前内容满足 cond 时删除文件（启用回收站时移入回收站），否则返回 ErrPreconditionFailed。
+func (s *engine) DeleteIf(id string, cond IfMatch) (FileMeta, error) {
+	s.mu.Lock()
 	en, ok := s.byID[id]
 	if !ok {
+		s.mu.Unlock()
 		return FileMeta{}, ErrNotFound
 	}
-	s.deleteLocked(en)
-	return en.meta, nil
+	if err := cond.check(en.meta); err != nil {
+		s.mu.Unlock()
+		return FileMeta{}, err
+	}
+	removed := s.discardLocked(en, opDelete, time.Now())
+	err := s.commitLocked()
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return en.meta, err
+}
+
+// Rename 在文件所在目录内改名（移动到其他目录见 Move）。
+func (s *engine) Rename(id string, newName string) (FileMeta, error) {
+	return s.RenameIf(id, newName, nil)
 }
 
-func (s *InMemoryStore) Rename(id string, newName string) (FileMeta, error) {
-	if newName == "" {
-		return FileMeta{}, fmt.Errorf("%w: new name is required", ErrInvalidInput)
+// RenameIf 在当前内容满足 cond 时改名，否则返回 ErrPreconditionFailed。
+func (s *engine) RenameIf(id string, newName string, cond IfMatch) (FileMeta, error) {
+	return s.RenameWith(RenameParams{ID: id, Name: newName, IfMatch: cond})
+}
+
+// RenameWith 按 p.OnConflict 处理重名后改名。
+func (s *engine) RenameWith(p RenameParams) (FileMeta, error) {
+	name, err := s.CleanName(p.Name)
+	if err != nil {
+		return FileMeta{}, err
+	}
+	p.Name = name
+	if err := CheckConflictPolicy(p.OnConflict); err != nil {
+		return FileMeta{}, err
 	}
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	meta, removed, err := s.renameLocked(p)
+	s.mu.Unlock()
 
-	en, ok := s.byID[id]
+	s.removeBlobs(removed)
+	return meta, err
+}
+
+func (s *engine) renameLocked(p RenameParams) (FileMeta, []string, error) {
+	en, ok := s.liveLocked(p.ID)
 	if !ok {
-		return FileMeta{}, ErrNotFound
+		return FileMeta{}, nil, ErrNotFound
+	}
+	if err := p.IfMatch.check(en.meta); err != nil {
+		return FileMeta{}, nil, err
 	}
-	if en.meta.Name == newName {
-		return en.meta, nil
+	if en.meta.Name == p.Name {
+		return en.meta, nil, nil
 	}
-	if _, exists := s.byName[newName]; exists {
-		return FileMeta{}, ErrNameConflict
+	name, holder, removed, err := s.claimNameLocked(en.meta.Folder, p.Name, p.OnConflict, en)
+	if err != nil {
+		return FileMeta{}, removed, err
 	}
-
-	delete(s.byName, en.meta.Name)
-	en.meta.Name = newName
-	s.byName[newName] = id
-	return en.meta, nil
+	if holder != nil {
+		meta, dropped, err := s.overwriteLocked(en, holder, time.Now())
+		return meta, append(removed, dropped...), err
+	}
+	if name != en.meta.Name {
+		s.setNameLocked(en, name)
+	}
+	return en.meta, removed, s.commitLocked()
 }
 
 type ReplaceParams struct {
@@ -233,9 +635,18 @@ type ReplaceParams struct {
 	Bytes    []byte
 	Encoding string
 	IsText   bool
+	Now      time.Time
+	// IfMatch 非空时，当前内容须满足该条件才写入（否则返回 ErrPreconditionFailed）。
+	IfMatch IfMatch
 }
 
-func (s *InMemoryStore) ReplaceBytes(p ReplaceParams) (FileMeta, error) {
+// ReplaceBytes 写入新内容作为新版本，原内容转为历史版本保留。
+// 历史版本超过数量上限或总量不足时，先丢弃该文件最老的历史版本；
+// 只有新内容本身放不下（或超出置顶总量上限）时才返回 ErrReplaceWouldExceed（不修改原内容，也不淘汰其他文件）。
+func (s *engine) ReplaceBytes(p ReplaceParams) (FileMeta, error) {
+	if p.Now.IsZero() {
+		p.Now = time.Now()
+	}
 	if p.ID == "" {
 		return FileMeta{}, fmt.Errorf("%w: id is required", ErrInvalidInput)
 	}
@@ -243,51 +654,245 @@ func (s *InMemoryStore) ReplaceBytes(p ReplaceParams) (FileMeta, error) {
 	if newSize < 0 {
 		return FileMeta{}, fmt.Errorf("%w: invalid bytes", ErrInvalidInput)
 	}
-	if newSize > s.maxTotalBytes {
+	if newSize > s.maxTotalBytes && !s.compresses() {
+		return FileMeta{}, ErrTooLarge
+	}
+
+	sum := contentSum(p.Bytes)
+	key, stored, err := s.prepareBlob(sum, p.Bytes, nil)
+	if err != nil {
+		return FileMeta{}, fmt.Errorf("store content: %w", err)
+	}
+	if stored > s.maxTotalBytes {
+		s.discardBlob(key)
 		return FileMeta{}, ErrTooLarge
 	}
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	meta, removed, err := s.replaceLocked(p, newSize, key, sum)
+	removed = append(removed, s.releaseLocked(key)...)
+	s.mu.Unlock()
 
-	en, ok := s.byID[p.ID]
+	s.removeBlobs(removed)
+	return meta, err
+}
+
+func (s *engine) replaceLocked(p ReplaceParams, newSize int64, key, sum string) (FileMeta, []string, error) {
+	en, ok := s.liveLocked(p.ID)
 	if !ok {
-		return FileMeta{}, ErrNotFound
+		return FileMeta{}, nil, ErrNotFound
+	}
+	if err := p.IfMatch.check(en.meta); err != nil {
+		return FileMeta{}, nil, err
 	}
 
-	newTotal := s.totalBytes - en.meta.SizeBytes + newSize
-	if newTotal > s.maxTotalBytes {
-		return FileMeta{}, ErrReplaceWouldExceed
+	// 新内容已由 prepareBlob 计入物理用量；被丢弃的历史版本只有在没有其他引用时才释放空间。
+	// 置顶文件的新内容与保留的历史版本还须满足置顶总量上限。
+	kept := append(append([]version(nil), en.versions...), en.currentVersion())
+	// 尚未结束的上传预留视同已占用。
+	maxTotal := s.maxTotalBytes - s.reservedBytes
+	newTotal := s.totalBytes
+	pinnedTotal := s.pinnedBytes - en.bytes() + newSize
+	for _, v := range kept {
+		pinnedTotal += v.SizeBytes
+	}
+	overPinned := func() bool { return en.meta.Pinned && pinnedTotal > s.maxPinned }
+	var dropped []string
+	for len(kept) > 0 && (len(kept) > s.maxVersions || newTotal > maxTotal || overPinned()) {
+		dropped = append(dropped, kept[0].blobKey)
+		pinnedTotal -= kept[0].SizeBytes
+		kept = kept[1:]
+		newTotal = s.totalBytes - s.freedIfReleasedLocked(dropped)
+	}
+	if newTotal > maxTotal || overPinned() {
+		return FileMeta{}, nil, ErrReplaceWouldExceed
 	}
 
-	s.totalBytes = newTotal
-	en.data = p.Bytes
+	next := en.nextVersion()
+	s.unaccountLocked(en)
+	s.retainLocked(key)
+	en.versions = kept
+	en.blobKey = key
 	en.meta.SizeBytes = newSize
 	en.meta.Encoding = p.Encoding
 	en.meta.IsText = p.IsText
-	return en.meta, nil
+	en.meta.Version = next
+	en.meta.UpdatedAt = p.Now.UTC()
+	en.meta.PrevVersions = len(kept)
+	en.meta.SHA256 = sum
+	s.accountLocked(en)
+	if !en.meta.Pinned {
+		s.policy.update(en)
+	}
+	s.publishLocked(en)
+
+	var removed []string
+	for _, k := range dropped {
+		removed = append(removed, s.releaseLocked(k)...)
+	}
+	s.logLocked(journalRecord{Op: opReplace, Meta: en.meta, Versions: en.versionNumbers(), Data: p.Bytes})
+	return en.meta, removed, s.commitLocked()
 }
 
-func (s *InMemoryStore) evictLocked(incomingSize int64) error {
-	for (len(s.byID) >= s.maxFiles) || (s.totalBytes+incomingSize > s.maxTotalBytes) {
-		front := s.fifo.Front()
-		if front == nil {
-			break
+// evictLocked 按淘汰策略淘汰文件，为一个新文件（incomingSize 字节，trigger 为其路径，记入淘汰记录）腾出位置，
+// 返回因淘汰而不再被引用的内容 key，调用方需在释放锁后回收。
+// 与其他文件共享内容的条目被淘汰时不释放空间，会继续淘汰下一个；置顶文件不会被淘汰。
+// TrashEvicted 时被淘汰的文件移入回收站。
+func (s *engine) evictLocked(incomingSize int64, trigger string) ([]string, error) {
+	_, evicted, fits := s.runEvictionLocked(incomingSize, trigger, time.Now(), false)
+	if !fits {
+		return evicted, ErrInsufficientSpace
+	}
+	return evicted, nil
+}
+
+// runEvictionLocked 为 incomingSize 字节的新文件腾位置：放不下时先清理已过期的文件，再按淘汰策略依次选出条目，
+// 直到放得下或没有可淘汰的文件，返回选出的条目（不含过期文件）与最终能否放下。
+// dryRun 时只预演（CheckAdmission）：不改变存储，按选出条目释放的文件数与空间估算用量；
+// 否则实际清理、淘汰（evictLocked），removed 为不再被引用的内容 key。
+func (s *engine) runEvictionLocked(incomingSize int64, trigger string, now time.Time, dryRun bool) (victims []*entry, removed []string, fits bool) {
+	var (
+		files    int
+		released []string
+		gone     = make(map[*entry]bool)
+	)
+	full := func() bool {
+		return s.fullAfterLocked(files, s.freedIfReleasedLocked(released), incomingSize)
+	}
+	drop := func(en *entry, expired bool) {
+		switch {
+		case dryRun:
+			gone[en] = true
+			files++
+			released = append(released, en.blobKeys()...)
+		case expired:
+			removed = append(removed, s.expireLocked(en)...)
+		default:
+			reason := s.evictReasonLocked()
+			removed = append(removed, s.discardLocked(en, opEvict, now)...)
+			s.recordEvictionLocked(en, reason, trigger, now)
+		}
+	}
+
+	if full() {
+		// 先回收已过期但尚未清理的文件。
+		for _, en := range s.byID {
+			if en.meta.Expired(now) {
+				drop(en, true)
+			}
+		}
+	}
+	s.applyTouchesLocked()
+	pick := func(en *entry) bool {
+		if !full() {
+			return false
 		}
-		oldest := front.Value.(*entry)
-		s.deleteLocked(oldest)
+		if !gone[en] {
+			drop(en, false)
+			victims = append(victims, en)
+		}
+		return true
 	}
-	if (len(s.byID) >= s.maxFiles) || (s.totalBytes+incomingSize > s.maxTotalBytes) {
-		return ErrInsufficientSpace
+	if dryRun {
+		// walk 不改变策略状态，顺序与依次淘汰 victim 相同。
+		s.policy.walk(pick)
+	} else {
+		for {
+			en := s.policy.victim()
+			if en == nil || !pick(en) {
+				break
+			}
+		}
 	}
-	return nil
+	return victims, removed, !full()
 }
 
-func (s *InMemoryStore) deleteLocked(en *entry) {
+// fullLocked 报告连同尚未结束的预留，是否已放不下一个 incomingSize 字节的新文件。
+func (s *engine) fullLocked(incomingSize int64) bool {
+	return s.fullAfterLocked(0, 0, incomingSize)
+}
+
+// fullAfterLocked 与 fullLocked 相同，但先扣除预演中淘汰的 files 个文件与释放的 freed 字节。
+func (s *engine) fullAfterLocked(files int, freed, incomingSize int64) bool {
+	return len(s.byID)-files+s.reservedFiles >= s.maxFiles || s.totalBytes-freed+s.reservedBytes+incomingSize > s.maxTotalBytes
+}
+
+// fitLimitsLocked 用于装载已有数据（重启恢复、配置调小）时：按淘汰策略丢弃文件直到满足上限。
+// 与 evictLocked 不同，它不为新文件预留位置。
+func (s *engine) fitLimitsLocked() []string {
+	var dropped []string
+	s.applyTouchesLocked()
+	for len(s.byID) > s.maxFiles || s.totalBytes > s.maxTotalBytes {
+		victim := s.policy.victim()
+		if victim == nil {
+			// 只剩置顶文件仍超限（配置调小）：上限优先，按上传顺序丢弃。
+			victim = s.fifo.Front().Value.(*entry)
+		}
+		reason := EvictReasonMaxBytes
+		if len(s.byID) > s.maxFiles {
+			reason = EvictReasonMaxFiles
+		}
+		dropped = append(dropped, s.deleteLocked(victim)...)
+		s.recordEvictionLocked(victim, reason, "", time.Now())
+	}
+	return dropped
+}
+
+// deleteLocked 移除条目并释放其内容引用，返回引用归零、需要回收的 key。
+func (s *engine) deleteLocked(en *entry) []string {
+	s.unlinkLocked(en)
+	var removed []string
+	for _, key := range en.blobKeys() {
+		removed = append(removed, s.releaseLocked(key)...)
+	}
+	return removed
+}
+
+// unlinkLocked 把条目从索引、上传顺序与淘汰策略中摘除，不释放内容引用。
+func (s *engine) unlinkLocked(en *entry) {
 	delete(s.byID, en.meta.ID)
-	delete(s.byName, en.meta.Name)
-	s.totalBytes -= en.meta.SizeBytes
+	delete(s.byName, en.nameKey())
+	s.unpublishLocked(en)
 	s.fifo.Remove(en.elem)
+	if !en.meta.Pinned {
+		s.policy.remove(en)
+	}
+	s.unaccountLocked(en)
+}
+
+// logLocked 暂存一条变更记录，由 commitLocked 统一写入操作日志。
+func (s *engine) logLocked(rec journalRecord) {
+	if s.journal != nil {
+		s.pending = append(s.pending, rec)
+	}
+}
+
+// commitLocked 在每次变更生效后调用：推进修订号，追加操作日志，并按需通知后台写索引。
+// 日志追加失败时，日志按已落盘的记录重建状态，本次变更随之撤销（见 Journal.abortLocked）。
+func (s *engine) commitLocked() error {
+	s.rev++
+	if s.journal != nil && len(s.pending) > 0 {
+		recs := s.pending
+		s.pending = nil
+		if err := s.journal(recs); err != nil {
+			return fmt.Errorf("append journal: %w", err)
+		}
+	}
+	if s.index != nil {
+		s.index.markLocked(s.rev)
+	}
+	return nil
+}
+
+// removeBlobs 回收不再被引用的内容；失败只会留下孤儿内容，由后端在下次启动时清理。
+func (s *engine) removeBlobs(keys []string) {
+	if s.index != nil && len(keys) > 0 {
+		s.index.removeAfterWrite(keys)
+		return
+	}
+	for _, key := range keys {
+		_ = s.blobs.remove(key)
+	}
 }
 
 func newID() string {
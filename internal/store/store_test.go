@@ -75,7 +75,7 @@ func TestFIFOEvictionByMaxFiles(t *testing.T) {
 }
 
 func TestFIFOEvictionByMaxTotalBytes(t *testing.T) {
-	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 3})
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 2})
 	if err != nil {
 		t.Fatal(err)
 	}
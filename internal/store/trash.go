@@ -0,0 +1,273 @@
+package store
+
+import (
+	"container/list"
+	"time"
+)
+
+// 回收站：启用后（TrashTTL > 0）手动删除的文件（以及 TrashEvicted 时被自动淘汰的文件）先移入回收站，
+// 保留 TrashTTL 后由 SweepExpired 清除；文件本身的有效期仍然生效，到期同样清除。
+// 回收站中的文件不占文件名额与文件名，内容按 trashBytes 单独计量（不计入 MaxTotalBytes），
+// 超出 MaxTrashBytes 时按删除顺序清除最早的条目。已过期的文件删除时不进入回收站。
+
+// TrashItem 为回收站中的一个文件。
+type TrashItem struct {
+	Meta      FileMeta
+	DeletedAt time.Time
+	// PurgeAt 为自动清除的时间（保留期满与文件有效期中较早者）。
+	PurgeAt time.Time
+	// Evicted 为 true 表示因自动淘汰进入回收站，否则为手动删除。
+	Evicted bool
+}
+
+type trashEntry struct {
+	en        *entry
+	deletedAt time.Time
+	evicted   bool
+	elem      *list.Element
+}
+
+func (t *trashEntry) purgeAt(ttl time.Duration) time.Time {
+	at := t.deletedAt.Add(ttl)
+	if exp := t.en.meta.ExpiresAt; !exp.IsZero() && exp.Before(at) {
+		return exp
+	}
+	return at
+}
+
+func (t *trashEntry) item(ttl time.Duration) TrashItem {
+	return TrashItem{Meta: t.en.meta, DeletedAt: t.deletedAt, PurgeAt: t.purgeAt(ttl), Evicted: t.evicted}
+}
+
+// ListTrash 返回回收站中尚未到期的文件，最近删除的在前。
+func (s *engine) ListTrash() []TrashItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	out := make([]TrashItem, 0, len(s.trash))
+	for e := s.trashList.Back(); e != nil; e = e.Prev() {
+		te := e.Value.(*trashEntry)
+		if s.trashLiveLocked(te, now) {
+			out = append(out, te.item(s.trashTTL))
+		}
+	}
+	return out
+}
+
+// RestoreTrash 把文件从回收站恢复到原目录（目录已删除时重新创建），保留历史版本、标签与备注。
+// 原目录中已有同名文件时返回 ErrNameConflict；空间不足时按淘汰策略淘汰其他文件，仍不足返回 ErrInsufficientSpace。
+// 置顶文件恢复后超出置顶上限时取消置顶。
+func (s *engine) RestoreTrash(id string) (FileMeta, error) {
+	s.mu.Lock()
+	meta, removed, err := s.restoreTrashLocked(id, time.Now())
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return meta, err
+}
+
+func (s *engine) restoreTrashLocked(id string, now time.Time) (FileMeta, []string, error) {
+	te, ok := s.trash[id]
+	if !ok || !s.trashLiveLocked(te, now) {
+		return FileMeta{}, nil, ErrNotFound
+	}
+	en := te.en
+	removed, err := s.takeNameLocked(en.meta.Folder, en.meta.Name)
+	if err != nil {
+		return FileMeta{}, nil, err
+	}
+
+	// 先摘出回收站并持有临时引用（内容用量转入总量），再按需淘汰；
+	// 这样为它腾空间时被淘汰进回收站的文件不会把它挤掉。
+	s.unlinkTrashLocked(te)
+	keys := en.blobKeys()
+	for _, key := range keys {
+		s.retainLocked(key)
+	}
+	evicted, err := s.evictLocked(0, filePath(en.meta.Folder, en.meta.Name))
+	removed = append(removed, evicted...)
+	if err != nil {
+		for _, key := range keys {
+			s.releaseLocked(key)
+		}
+		s.linkTrashLocked(te)
+		removed = append(removed, s.fitTrashLocked()...)
+		_ = s.commitLocked()
+		return FileMeta{}, removed, err
+	}
+
+	if en.meta.Pinned && (s.pinnedBytes+en.bytes() > s.maxPinned || s.pinnedFiles+1 >= s.maxFiles) {
+		en.meta.Pinned = false
+	}
+	s.untrashLocked(en)
+	for _, key := range keys {
+		s.releaseLocked(key)
+	}
+	s.logLocked(journalRecord{Op: opUntrash, Meta: en.meta})
+	removed = append(removed, s.fitTrashLocked()...)
+	return en.meta, removed, s.commitLocked()
+}
+
+// PurgeTrash 从回收站彻底删除文件。
+func (s *engine) PurgeTrash(id string) error {
+	s.mu.Lock()
+	te, ok := s.trash[id]
+	if !ok || !s.trashLiveLocked(te, time.Now()) {
+		s.mu.Unlock()
+		return ErrNotFound
+	}
+	removed := s.purgeLocked(te)
+	err := s.commitLocked()
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return err
+}
+
+// EmptyTrash 清空回收站，返回清除的文件数。
+func (s *engine) EmptyTrash() (int, error) {
+	s.mu.Lock()
+	var removed []string
+	n := len(s.trash)
+	for s.trashList.Len() > 0 {
+		removed = append(removed, s.purgeLocked(s.trashList.Front().Value.(*trashEntry))...)
+	}
+	var err error
+	if n > 0 {
+		err = s.commitLocked()
+	}
+	s.mu.Unlock()
+
+	s.removeBlobs(removed)
+	return n, err
+}
+
+// trashLiveLocked 报告回收站条目在 now 时是否尚未到期（到期未清除的条目对外不可见）。
+func (s *engine) trashLiveLocked(te *trashEntry, now time.Time) bool {
+	return now.Before(te.purgeAt(s.trashTTL))
+}
+
+// discardLocked 删除或淘汰文件（op 为 opDelete 或 opEvict）并记录日志：启用回收站时移入回收站
+// （淘汰只在 TrashEvicted 时），否则直接删除。返回不再被引用、需要回收的内容 key。
+func (s *engine) discardLocked(en *entry, op string, now time.Time) []string {
+	rec := journalRecord{Op: op, Meta: en.meta}
+	if s.trashTTL <= 0 || en.meta.Expired(now) || (op == opEvict && !s.trashEvicted) {
+		removed := s.deleteLocked(en)
+		s.logLocked(rec)
+		return removed
+	}
+	at := now.UTC()
+	s.moveToTrashLocked(en, op == opEvict, at)
+	rec.Trashed = &at
+	s.logLocked(rec)
+	return s.fitTrashLocked()
+}
+
+// moveToTrashLocked 把文件移入回收站（不检查回收站上限，重放日志时也使用）。
+func (s *engine) moveToTrashLocked(en *entry, evicted bool, at time.Time) {
+	s.unlinkLocked(en)
+	for _, key := range en.blobKeys() {
+		// 先持有回收站引用再释放文件引用，内容的用量直接转入回收站，不会被回收。
+		s.trashRetainLocked(key)
+		s.releaseLocked(key)
+	}
+	s.linkTrashLocked(&trashEntry{en: en, deletedAt: at, evicted: evicted})
+}
+
+// loadTrashLocked 装载回收站条目（快照/索引），内容须已登记。
+func (s *engine) loadTrashLocked(en *entry, deletedAt time.Time, evicted bool) {
+	en.meta.PrevVersions = len(en.versions)
+	for _, key := range en.blobKeys() {
+		s.trashRetainLocked(key)
+	}
+	s.linkTrashLocked(&trashEntry{en: en, deletedAt: deletedAt, evicted: evicted})
+}
+
+// untrashLocked 把已摘出回收站的条目放回文件列表；调用方负责检查重名与上限。
+func (s *engine) untrashLocked(en *entry) {
+	s.insertLocked(en)
+	for _, key := range en.blobKeys() {
+		// 文件已持有引用，这里不会回收内容。
+		s.trashReleaseLocked(key)
+	}
+}
+
+// purgeLocked 彻底删除回收站条目并记录日志，返回需要回收的内容 key。
+func (s *engine) purgeLocked(te *trashEntry) []string {
+	s.unlinkTrashLocked(te)
+	var removed []string
+	for _, key := range te.en.blobKeys() {
+		removed = append(removed, s.trashReleaseLocked(key)...)
+	}
+	s.logLocked(journalRecord{Op: opPurge, Meta: te.en.meta})
+	return removed
+}
+
+// fitTrashLocked 按删除顺序清除最早的条目，直到回收站不超过 MaxTrashBytes。
+func (s *engine) fitTrashLocked() []string {
+	var removed []string
+	for s.trashBytes > s.maxTrash && s.trashList.Len() > 0 {
+		removed = append(removed, s.purgeLocked(s.trashList.Front().Value.(*trashEntry))...)
+	}
+	return removed
+}
+
+// sweepTrashLocked 清除在 now 时已到期的条目（未启用回收站时清除全部），再按上限清除，返回清除的个数。
+func (s *engine) sweepTrashLocked(now time.Time) ([]string, int) {
+	var (
+		removed []string
+		n       = s.trashList.Len()
+	)
+	for e := s.trashList.Front(); e != nil; {
+		te := e.Value.(*trashEntry)
+		e = e.Next()
+		if s.trashTTL <= 0 || !s.trashLiveLocked(te, now) {
+			removed = append(removed, s.purgeLocked(te)...)
+		}
+	}
+	removed = append(removed, s.fitTrashLocked()...)
+	return removed, n - s.trashList.Len()
+}
+
+// linkTrashLocked 按删除时间把条目放入回收站（通常追加在末尾）。
+func (s *engine) linkTrashLocked(te *trashEntry) {
+	e := s.trashList.Back()
+	for e != nil && e.Value.(*trashEntry).deletedAt.After(te.deletedAt) {
+		e = e.Prev()
+	}
+	if e == nil {
+		te.elem = s.trashList.PushFront(te)
+	} else {
+		te.elem = s.trashList.InsertAfter(te, e)
+	}
+	s.trash[te.en.meta.ID] = te
+}
+
+func (s *engine) unlinkTrashLocked(te *trashEntry) {
+	s.trashList.Remove(te.elem)
+	delete(s.trash, te.en.meta.ID)
+}
+
+// trashRetainLocked 增加一个回收站引用；内容不再被文件引用时计入 trashBytes。
+func (s *engine) trashRetainLocked(key string) {
+	r := s.blobRefs[key]
+	r.trashRefs++
+	if r.trashRefs == 1 && r.refs == 0 {
+		s.trashBytes += r.size
+	}
+}
+
+// trashReleaseLocked 释放一个回收站引用；内容不再被任何条目引用时返回需回收的 key。
+func (s *engine) trashReleaseLocked(key string) []string {
+	r, ok := s.blobRefs[key]
+	if !ok {
+		return nil
+	}
+	r.trashRefs--
+	if r.trashRefs > 0 || r.refs > 0 {
+		return nil
+	}
+	s.trashBytes -= r.size
+	return s.dropBlobLocked(r)
+}
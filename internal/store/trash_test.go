@@ -0,0 +1,278 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func newTrashStore(t *testing.T, p NewParams) *InMemoryStore {
+	t.Helper()
+	if p.TrashTTL == 0 {
+		p.TrashTTL = time.Hour
+	}
+	if p.MaxTrashBytes == 0 {
+		p.MaxTrashBytes = 100
+	}
+	s, err := NewInMemoryStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTrashDeleteAndRestore(t *testing.T) {
+	s := newTrashStore(t, NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+	if err := s.CreateFolder("docs"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Folder: "docs", Bytes: []byte("hello")})
+	if _, err := s.SetTags(a.ID, []string{"keep"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("deleted file should not be visible, got %v", err)
+	}
+	if st := s.Stats(); st.Files != 0 || st.PhysicalBytes != 0 || st.TrashFiles != 1 || st.TrashBytes != 5 {
+		t.Fatalf("trash should be accounted separately: %#v", st)
+	}
+	items := s.ListTrash()
+	if len(items) != 1 || items[0].Meta.ID != a.ID || items[0].Evicted || !items[0].PurgeAt.Equal(items[0].DeletedAt.Add(time.Hour)) {
+		t.Fatalf("unexpected trash: %#v", items)
+	}
+
+	// 回收站中的文件不占文件名；恢复时原名已被占用则返回 ErrNameConflict。
+	b, err := s.Add(AddParams{Name: "a.txt", Folder: "docs", Bytes: []byte("other")})
+	if err != nil {
+		t.Fatalf("trashed file should not hold its name: %v", err)
+	}
+	if _, err := s.RestoreTrash(a.ID); err != ErrNameConflict {
+		t.Fatalf("expected ErrNameConflict, got %v", err)
+	}
+	if _, err := s.Rename(b.ID, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeleteFolder("docs", true); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := s.RestoreTrash(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Folder != "docs" || restored.Name != "a.txt" || !restored.HasTag("keep") {
+		t.Fatalf("unexpected restored meta: %#v", restored)
+	}
+	if f, err := s.Get(a.ID); err != nil || string(f.Bytes) != "hello" {
+		t.Fatalf("restored content: %q err=%v", f.Bytes, err)
+	}
+	if _, err := s.RestoreTrash(a.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound restoring twice, got %v", err)
+	}
+	if st := s.Stats(); st.Files != 1 || st.PhysicalBytes != 5 || st.TrashFiles != 1 || st.TrashBytes != 5 {
+		t.Fatalf("unexpected stats after restore: %#v", st)
+	}
+
+	if err := s.PurgeTrash(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PurgeTrash(b.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if st := s.Stats(); st.TrashFiles != 0 || st.TrashBytes != 0 {
+		t.Fatalf("unexpected stats after purge: %#v", st)
+	}
+}
+
+func TestTrashLimits(t *testing.T) {
+	t.Run("ttl", func(t *testing.T) {
+		s := newTrashStore(t, NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+		a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+		if _, err := s.Delete(a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.SweepExpired(time.Now()); err != nil || len(s.ListTrash()) != 1 {
+			t.Fatalf("trash purged too early: err=%v", err)
+		}
+		if _, err := s.SweepExpired(time.Now().Add(2 * time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if st := s.Stats(); st.TrashFiles != 0 || st.TrashBytes != 0 {
+			t.Fatalf("expired trash not purged: %#v", st)
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		s := newTrashStore(t, NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxTrashBytes: 6})
+		var ids []string
+		for _, name := range []string{"1", "2", "3"} {
+			m, _ := s.Add(AddParams{Name: name, Bytes: bytes.Repeat([]byte(name), 4)})
+			ids = append(ids, m.ID)
+		}
+		// 与仍在使用的文件共享内容时不占回收站空间。
+		shared, _ := s.Add(AddParams{Name: "shared", Bytes: []byte("3333")})
+		for _, id := range ids {
+			if _, err := s.Delete(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		items := s.ListTrash()
+		if len(items) != 2 || items[0].Meta.ID != ids[2] || items[1].Meta.ID != ids[1] {
+			t.Fatalf("oldest entry should be purged first: %#v", items)
+		}
+		if st := s.Stats(); st.TrashBytes != 4 || st.PhysicalBytes != 4 {
+			t.Fatalf("unexpected stats: %#v", st)
+		}
+		if _, err := s.Delete(shared.ID); err != nil {
+			t.Fatal(err)
+		}
+		// 共享内容转入回收站后超出上限，继续清除最早的条目。
+		if items := s.ListTrash(); len(items) != 2 || items[0].Meta.ID != shared.ID || items[1].Meta.ID != ids[2] {
+			t.Fatalf("unexpected trash after deleting shared content: %#v", items)
+		}
+		if st := s.Stats(); st.TrashBytes != 4 || st.PhysicalBytes != 0 {
+			t.Fatalf("unexpected stats after deleting shared content: %#v", st)
+		}
+		if n, err := s.EmptyTrash(); err != nil || n != 2 {
+			t.Fatalf("empty trash: n=%d err=%v", n, err)
+		}
+		if st := s.Stats(); st.TrashFiles != 0 || st.TrashBytes != 0 {
+			t.Fatalf("unexpected stats after emptying: %#v", st)
+		}
+	})
+
+	t.Run("evicted", func(t *testing.T) {
+		s := newTrashStore(t, NewParams{MaxFiles: 2, MaxTotalBytes: 100, TrashEvicted: true})
+		a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+		s.Add(AddParams{Name: "b", Bytes: []byte("b")})
+		s.Add(AddParams{Name: "c", Bytes: []byte("c")})
+		items := s.ListTrash()
+		if len(items) != 1 || items[0].Meta.ID != a.ID || !items[0].Evicted {
+			t.Fatalf("evicted file should go to trash: %#v", items)
+		}
+		// 恢复同样受文件数上限约束，会按淘汰策略淘汰下一个文件。
+		if _, err := s.RestoreTrash(a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if names := s.List(); len(names) != 2 || names[1].ID != a.ID {
+			t.Fatalf("unexpected files after restore: %#v", names)
+		}
+		if items := s.ListTrash(); len(items) != 1 || items[0].Meta.Name != "b" {
+			t.Fatalf("unexpected trash after restore: %#v", items)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s, _ := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+		a, _ := s.Add(AddParams{Name: "a", Bytes: []byte("a")})
+		if _, err := s.Delete(a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.ListTrash()) != 0 {
+			t.Fatal("trash should be disabled by default")
+		}
+		if _, err := s.RestoreTrash(a.ID); err != ErrNotFound {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestTrashSurvivesPersistence(t *testing.T) {
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100, TrashTTL: time.Hour, MaxTrashBytes: 100}
+	mutate := func(t *testing.T, s FileStore) (trashed, restored string) {
+		t.Helper()
+		a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("aaa")})
+		b, _ := s.Add(AddParams{Name: "b.txt", Bytes: []byte("bb")})
+		c, _ := s.Add(AddParams{Name: "c.txt", Bytes: []byte("c")})
+		for _, id := range []string{a.ID, b.ID, c.ID} {
+			if _, err := s.Delete(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.RestoreTrash(b.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PurgeTrash(c.ID); err != nil {
+			t.Fatal(err)
+		}
+		return a.ID, b.ID
+	}
+	check := func(t *testing.T, s FileStore, trashed, restored string) {
+		t.Helper()
+		items := s.ListTrash()
+		if len(items) != 1 || items[0].Meta.ID != trashed || items[0].DeletedAt.IsZero() {
+			t.Fatalf("unexpected trash after reload: %#v", items)
+		}
+		if _, err := s.GetMeta(restored); err != nil {
+			t.Fatalf("restored file lost: %v", err)
+		}
+		if st := s.Stats(); st.Files != 1 || st.PhysicalBytes != 2 || st.TrashFiles != 1 || st.TrashBytes != 3 {
+			t.Fatalf("unexpected stats after reload: %#v", st)
+		}
+		if _, err := s.RestoreTrash(trashed); err != nil {
+			t.Fatal(err)
+		}
+		if f, err := s.Get(trashed); err != nil || string(f.Bytes) != "aaa" {
+			t.Fatalf("content after restore: %q err=%v", f.Bytes, err)
+		}
+	}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		trashed, restored := mutate(t, s)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		check(t, reopened, trashed, restored)
+
+		// 关闭回收站后重启，回收站中的文件被清除。
+		reopened.Delete(trashed)
+		if err := reopened.Close(); err != nil {
+			t.Fatal(err)
+		}
+		off, err := NewDiskStore(dir, NewParams{MaxFiles: 10, MaxTotalBytes: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = off.Close() })
+		if st := off.Stats(); st.TrashFiles != 0 || st.TrashBytes != 0 {
+			t.Fatalf("trash should be purged when disabled: %#v", st)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s := newTrashStore(t, params)
+		trashed, restored := mutate(t, s)
+		var buf bytes.Buffer
+		if err := s.WriteSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded := newTrashStore(t, params)
+		if err := loaded.LoadSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		check(t, loaded, trashed, restored)
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		dir := t.TempDir()
+		s, _ := openJournalStore(t, dir, params)
+		trashed, restored := mutate(t, s)
+		recovered, j := openJournalStore(t, dir, params)
+		defer j.Close()
+		check(t, recovered, trashed, restored)
+	})
+}
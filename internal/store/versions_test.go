@@ -0,0 +1,270 @@
+package store
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func readVersion(t *testing.T, s FileStore, id string, version int) string {
+	t.Helper()
+	_, r, err := s.OpenVersion(id, version)
+	if err != nil {
+		t.Fatalf("open version %d: %v", version, err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestReplaceKeepsPreviousVersion(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("abc"), Encoding: "GBK", IsText: true, Now: time.Unix(1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 1 || a.PrevVersions != 0 {
+		t.Fatalf("unexpected initial meta: %#v", a)
+	}
+
+	updated, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("abcdef"), Encoding: "UTF-8", IsText: true, Now: time.Unix(2, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 || updated.PrevVersions != 1 || !updated.UpdatedAt.Equal(time.Unix(2, 0)) {
+		t.Fatalf("unexpected updated meta: %#v", updated)
+	}
+	if total := s.Stats().PhysicalBytes; total != 9 {
+		t.Fatalf("history should count against total bytes, got %d", total)
+	}
+
+	versions, err := s.ListVersions(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+		t.Fatalf("unexpected versions: %#v", versions)
+	}
+	if versions[1].Encoding != "GBK" || versions[1].SizeBytes != 3 || !versions[1].CreatedAt.Equal(time.Unix(1, 0)) {
+		t.Fatalf("unexpected previous version: %#v", versions[1])
+	}
+	if got := readVersion(t, s, a.ID, 1); got != "abc" {
+		t.Fatalf("expected original bytes, got %q", got)
+	}
+	if _, _, err := s.OpenVersion(a.ID, 7); err != ErrVersionNotFound {
+		t.Fatalf("expected ErrVersionNotFound, got %v", err)
+	}
+}
+
+func TestReplaceDropsOldestVersionsBeyondCount(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxVersions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []string{"22", "333", "4444"} {
+		if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte(b)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := s.ListVersions(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != 4 || versions[1].Version != 3 || versions[2].Version != 2 {
+		t.Fatalf("unexpected versions: %#v", versions)
+	}
+	if total := s.Stats().PhysicalBytes; total != 4+3+2 {
+		t.Fatalf("unexpected total bytes: %d", total)
+	}
+}
+
+func TestReplaceDropsHistoryToFitTotalBytes(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 10, MaxVersions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("aaaa")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(AddParams{Name: "b.txt", Bytes: []byte("bb")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 4(a) + 2(b) + 5 > 10：只能丢弃 a 的旧内容，且不淘汰 b。
+	updated, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("AAAAA")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.PrevVersions != 0 {
+		t.Fatalf("expected history to be dropped, got %#v", updated)
+	}
+	if st := s.Stats(); st.Files != 2 || st.PhysicalBytes != 7 {
+		t.Fatalf("unexpected stats: files=%d total=%d", st.Files, st.PhysicalBytes)
+	}
+
+	// 新内容本身放不下时严格失败，原内容与历史均不变。
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("123456789")}); err != ErrReplaceWouldExceed {
+		t.Fatalf("expected ErrReplaceWouldExceed, got %v", err)
+	}
+	if got := readVersion(t, s, a.ID, updated.Version); got != "AAAAA" {
+		t.Fatalf("unexpected bytes after failed replace: %q", got)
+	}
+}
+
+func TestRestoreVersionIsUndoable(t *testing.T) {
+	s, err := NewInMemoryStore(NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxVersions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("old"), Encoding: "GBK", IsText: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("new!"), Encoding: "UTF-8", IsText: true}); err != nil {
+		t.Fatal(err)
+	}
+	before := s.Stats().PhysicalBytes
+
+	restored, err := s.RestoreVersion(a.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 1 || restored.Encoding != "GBK" || restored.SizeBytes != 3 || restored.PrevVersions != 1 {
+		t.Fatalf("unexpected restored meta: %#v", restored)
+	}
+	f, err := s.Get(a.ID)
+	if err != nil || !bytes.Equal(f.Bytes, []byte("old")) {
+		t.Fatalf("unexpected current bytes: %q err=%v", f.Bytes, err)
+	}
+	if after := s.Stats().PhysicalBytes; after != before {
+		t.Fatalf("restore should not change total bytes: %d -> %d", before, after)
+	}
+
+	// 撤销恢复：版本 2 仍可恢复。
+	redo, err := s.RestoreVersion(a.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redo.Version != 2 || redo.Encoding != "UTF-8" {
+		t.Fatalf("unexpected meta after redo: %#v", redo)
+	}
+	if _, err := s.RestoreVersion(a.ID, 9); err != ErrVersionNotFound {
+		t.Fatalf("expected ErrVersionNotFound, got %v", err)
+	}
+
+	// 再次替换时版本号继续递增，不复用。
+	next, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Version != 3 {
+		t.Fatalf("expected version 3, got %d", next.Version)
+	}
+}
+
+func TestVersionsSurvivePersistence(t *testing.T) {
+	params := NewParams{MaxFiles: 10, MaxTotalBytes: 100, MaxVersions: 3}
+	mutate := func(t *testing.T, s FileStore) string {
+		t.Helper()
+		a, err := s.Add(AddParams{Name: "a.txt", Bytes: []byte("v1"), Encoding: "GBK", IsText: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("v2!"), Encoding: "UTF-8", IsText: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ReplaceBytes(ReplaceParams{ID: a.ID, Bytes: []byte("v3!!"), Encoding: "UTF-8", IsText: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.RestoreVersion(a.ID, 1); err != nil {
+			t.Fatal(err)
+		}
+		return a.ID
+	}
+	check := func(t *testing.T, s FileStore, id string) {
+		t.Helper()
+		meta, err := s.GetMeta(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Version != 1 || meta.Encoding != "GBK" || meta.PrevVersions != 2 {
+			t.Fatalf("unexpected meta: %#v", meta)
+		}
+		versions, err := s.ListVersions(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 3 || versions[1].Version != 3 || versions[2].Version != 2 {
+			t.Fatalf("unexpected versions: %#v", versions)
+		}
+		for v, want := range map[int]string{1: "v1", 2: "v2!", 3: "v3!!"} {
+			if got := readVersion(t, s, id, v); got != want {
+				t.Fatalf("version %d: got %q want %q", v, got, want)
+			}
+		}
+		if total := s.Stats().PhysicalBytes; total != 2+3+4 {
+			t.Fatalf("unexpected total bytes: %d", total)
+		}
+	}
+
+	t.Run("disk", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		id := mutate(t, s)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		reopened, err := NewDiskStore(dir, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = reopened.Close() })
+		check(t, reopened, id)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := mutate(t, s)
+		var buf bytes.Buffer
+		if err := s.WriteSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		restored, err := NewInMemoryStore(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := restored.LoadSnapshot(&buf); err != nil {
+			t.Fatal(err)
+		}
+		check(t, restored, id)
+	})
+
+	t.Run("journal", func(t *testing.T) {
+		dir := t.TempDir()
+		s, _ := openJournalStore(t, dir, params)
+		id := mutate(t, s)
+		restored, j := openJournalStore(t, dir, params)
+		defer j.Close()
+		check(t, restored, id)
+	})
+}
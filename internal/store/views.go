@@ -0,0 +1,185 @@
+package store
+
+import (
+	"hash/maphash"
+	"slices"
+	"sync"
+)
+
+// 读路径（GetMeta、Get/Open/OpenVersion、HasName）不持 engine.mu：每次变更在持有写锁时把条目的只读快照
+// 发布到按 ID（与按文件名）分片的索引中，读取只锁对应分片，因此上传提交、淘汰等写操作不会阻塞下载。
+// 用量统计、上限检查与淘汰顺序仍只在 engine.mu 下维护，口径与之前一致；
+// 列表、查询等需要整体视图的操作仍持读锁遍历。
+//
+// 读取记录（LRU/LFU 的 touch）先放入缓冲，由写操作在选择淘汰对象前统一应用；
+// 缓冲满时由读取方持写锁应用一次，不会丢失记录。
+
+const (
+	viewShards     = 64
+	maxTouchBuffer = 4096
+)
+
+// fileView 为条目在某次变更后的只读快照，发布后不再修改。
+type fileView struct {
+	meta     FileMeta
+	blobKey  string
+	versions []version
+	// blobs 为当前内容与各历史版本的 blob（按 key）。
+	blobs map[string]blobRef
+}
+
+func (v *fileView) nameKey() nameKey {
+	return nameKey{folder: v.meta.Folder, name: v.meta.Name}
+}
+
+// version 与 entry.versionLocked 相同，另返回对应的 blob。
+func (v *fileView) version(n int) (FileMeta, blobRef, error) {
+	en := entry{meta: v.meta, blobKey: v.blobKey, versions: v.versions}
+	meta, key, err := en.versionLocked(n)
+	if err != nil {
+		return FileMeta{}, blobRef{}, err
+	}
+	return meta, v.blobs[key], nil
+}
+
+type viewShard struct {
+	mu    sync.RWMutex
+	byID  map[string]*fileView
+	names map[nameKey]string
+	// 填充到 64 字节，避免相邻分片的锁落在同一缓存行。
+	_ [24]byte
+}
+
+type viewIndex struct {
+	seed   maphash.Seed
+	shards [viewShards]viewShard
+}
+
+func newViewIndex() *viewIndex {
+	x := &viewIndex{seed: maphash.MakeSeed()}
+	for i := range x.shards {
+		x.shards[i].byID = make(map[string]*fileView)
+		x.shards[i].names = make(map[nameKey]string)
+	}
+	return x
+}
+
+func (x *viewIndex) idShard(id string) *viewShard {
+	return &x.shards[maphash.String(x.seed, id)%viewShards]
+}
+
+func (x *viewIndex) nameShard(k nameKey) *viewShard {
+	var h maphash.Hash
+	h.SetSeed(x.seed)
+	h.WriteString(k.folder)
+	h.WriteByte(0)
+	h.WriteString(k.name)
+	return &x.shards[h.Sum64()%viewShards]
+}
+
+func (x *viewIndex) get(id string) *fileView {
+	sh := x.idShard(id)
+	sh.mu.RLock()
+	v := sh.byID[id]
+	sh.mu.RUnlock()
+	return v
+}
+
+// swap 把 id 的快照换成 v（nil 表示删除），返回原快照。
+func (x *viewIndex) swap(id string, v *fileView) *fileView {
+	sh := x.idShard(id)
+	sh.mu.Lock()
+	old := sh.byID[id]
+	if v == nil {
+		delete(sh.byID, id)
+	} else {
+		sh.byID[id] = v
+	}
+	sh.mu.Unlock()
+	return old
+}
+
+func (x *viewIndex) lookupName(k nameKey) (string, bool) {
+	sh := x.nameShard(k)
+	sh.mu.RLock()
+	id, ok := sh.names[k]
+	sh.mu.RUnlock()
+	return id, ok
+}
+
+func (x *viewIndex) setName(k nameKey, id string) {
+	sh := x.nameShard(k)
+	sh.mu.Lock()
+	sh.names[k] = id
+	sh.mu.Unlock()
+}
+
+// deleteName 只在 k 仍属于 id 时删除。
+func (x *viewIndex) deleteName(k nameKey, id string) {
+	sh := x.nameShard(k)
+	sh.mu.Lock()
+	if sh.names[k] == id {
+		delete(sh.names, k)
+	}
+	sh.mu.Unlock()
+}
+
+// publishLocked 在条目的元数据、文件名或内容变化后发布新的快照；调用方持有写锁。
+// 改名时先登记新名再移除旧名，HasName 可能短暂同时看到两者（它本来就只是提示）。
+func (s *engine) publishLocked(en *entry) {
+	keys := en.blobKeys()
+	v := &fileView{
+		meta:     en.meta,
+		blobKey:  en.blobKey,
+		versions: slices.Clone(en.versions),
+		blobs:    make(map[string]blobRef, len(keys)),
+	}
+	for _, key := range keys {
+		v.blobs[key] = *s.blobRefs[key]
+	}
+	old := s.views.swap(en.meta.ID, v)
+	if k := v.nameKey(); old == nil || old.nameKey() != k {
+		s.views.setName(k, en.meta.ID)
+		if old != nil {
+			s.views.deleteName(old.nameKey(), en.meta.ID)
+		}
+	}
+}
+
+// unpublishLocked 撤下条目的快照；调用方持有写锁。
+func (s *engine) unpublishLocked(en *entry) {
+	if old := s.views.swap(en.meta.ID, nil); old != nil {
+		s.views.deleteName(old.nameKey(), en.meta.ID)
+	}
+}
+
+// recordTouch 记录一次内容读取，不持 engine.mu。缓冲满时持写锁应用一次。
+func (s *engine) recordTouch(id string) {
+	if !s.trackReads {
+		return
+	}
+	s.touchMu.Lock()
+	s.touches = append(s.touches, id)
+	full := len(s.touches) == maxTouchBuffer
+	s.touchMu.Unlock()
+	if full {
+		s.mu.Lock()
+		s.applyTouchesLocked()
+		s.mu.Unlock()
+	}
+}
+
+// applyTouchesLocked 把缓冲中的读取记录按先后交给淘汰策略（跳过已删除或置顶的条目）；调用方持有写锁。
+func (s *engine) applyTouchesLocked() {
+	s.touchMu.Lock()
+	ids := s.touches
+	s.touches = s.spareTouches[:0]
+	s.touchMu.Unlock()
+
+	for _, id := range ids {
+		if en, ok := s.byID[id]; ok && !en.meta.Pinned {
+			s.policy.touch(en)
+		}
+	}
+	s.spareTouches = ids
+}
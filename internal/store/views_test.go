@@ -0,0 +1,98 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// checkViews 核对读路径的分片索引与 engine 的索引完全一致。
+func checkViews(t *testing.T, s *engine) {
+	t.Helper()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views, names := 0, 0
+	for i := range s.views.shards {
+		views += len(s.views.shards[i].byID)
+		names += len(s.views.shards[i].names)
+	}
+	if views != len(s.byID) || names != len(s.byName) {
+		t.Fatalf("views=%d names=%d, want %d/%d", views, names, len(s.byID), len(s.byName))
+	}
+	for id, en := range s.byID {
+		v := s.views.get(id)
+		if v == nil || !reflect.DeepEqual(v.meta, en.meta) || v.blobKey != en.blobKey || !reflect.DeepEqual(v.versions, en.versions) {
+			t.Fatalf("stale view for %s: %#v", en.meta.Name, v)
+		}
+		for _, key := range en.blobKeys() {
+			if v.blobs[key].key != key {
+				t.Fatalf("view of %s misses blob %s", en.meta.Name, key)
+			}
+		}
+	}
+	for k, id := range s.byName {
+		if got, ok := s.views.lookupName(k); !ok || got != id {
+			t.Fatalf("name %q maps to %q, want %q", k.name, got, id)
+		}
+	}
+}
+
+func TestViewsFollowMutations(t *testing.T) {
+	dir := t.TempDir()
+	params := NewParams{MaxFiles: 4, MaxTotalBytes: 1000, MaxVersions: 2, MaxPinnedBytes: 100,
+		EvictionPolicy: EvictionLRU, TrashTTL: time.Hour, MaxTrashBytes: 1000, TrashEvicted: true}
+	s, j := openJournalStore(t, dir, params)
+
+	must := func(_ any, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreateFolder("docs"); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Add(AddParams{Name: "a.txt", Bytes: []byte("aaa")})
+	b, _ := s.Add(AddParams{Name: "b.txt", Folder: "docs", Bytes: []byte("bbb")})
+	c, _ := s.Add(AddParams{Name: "c.txt", Bytes: []byte("aaa"), ExpiresAt: time.Now().Add(time.Hour)})
+	must(s.Rename(a.ID, "a2.txt"))
+	must(s.Move(a.ID, "docs"))
+	must(s.ReplaceBytes(ReplaceParams{ID: b.ID, Bytes: []byte("v2")}))
+	must(s.ReplaceBytes(ReplaceParams{ID: b.ID, Bytes: []byte("v3")}))
+	must(s.RestoreVersion(b.ID, 1))
+	must(s.SetTags(b.ID, []string{"x"}))
+	must(s.SetPinned(c.ID, true))
+	if err := s.RenameFolder("docs", "papers"); err != nil {
+		t.Fatal(err)
+	}
+	checkViews(t, s.engine)
+
+	// 读取缓冲写满时由读取方应用，之后 b 比 a 更近被读取，淘汰 a（进入回收站）。
+	for range maxTouchBuffer {
+		must(s.Get(b.ID))
+	}
+	must(s.Add(AddParams{Name: "d.txt", Bytes: []byte("d")}))
+	must(s.Add(AddParams{Name: "e.txt", Bytes: []byte("e")}))
+	if _, err := s.GetMeta(a.ID); err != ErrNotFound {
+		t.Fatalf("least recently read file should be evicted, got %v", err)
+	}
+	if s.HasName("papers", "a2.txt") {
+		t.Fatal("evicted file should release its name")
+	}
+	must(s.Delete(b.ID))
+	must(s.RestoreTrash(a.ID))
+	if !s.HasName("papers", "a2.txt") || s.HasName("docs", "a2.txt") {
+		t.Fatal("restored file should be visible under its folder")
+	}
+	checkViews(t, s.engine)
+
+	// 重放日志得到的索引同样一致。
+	j.Close()
+	recovered, j2 := openJournalStore(t, dir, params)
+	defer j2.Close()
+	checkViews(t, recovered.engine)
+	if m, err := recovered.GetMeta(a.ID); err != nil || m.Folder != "papers" || m.Name != "a2.txt" {
+		t.Fatalf("recovered meta: %#v err=%v", m, err)
+	}
+}
@@ -34,6 +34,10 @@ func DetectTextAndEncoding(b []byte) (isText bool, enc string) {
 		sample = sample[:maxDetectSampleBytes]
 	}
 
+	// UTF-16/UTF-32 含大量 NUL，须在二进制判定之前识别。
+	if enc, ok := detectWide(sample, len(b) > len(sample)); ok {
+		return true, enc
+	}
 	if looksBinary(sample) {
 		return false, EncodingUnknown
 	}
@@ -153,6 +157,9 @@ func decodeStrictBytes(encName string, src []byte) ([]byte, error) {
 		return out, nil
 	}
 
+	if w, ok := lookupWide(encName); ok {
+		return w.decode(src)
+	}
 	enc, err := lookupEncoding(encName)
 	if err != nil {
 		return nil, ErrUnsupportedEncoding
@@ -13,6 +13,10 @@ import (
 const (
 	EncodingUnknown     = "Unknown"
 	EncodingUTF8        = "UTF-8"
+	EncodingUTF16LE     = "UTF-16LE"
+	EncodingUTF16BE     = "UTF-16BE"
+	EncodingUTF32LE     = "UTF-32LE"
+	EncodingUTF32BE     = "UTF-32BE"
 	EncodingGB18030     = "GB18030"
 	EncodingGBK         = "GBK"
 	EncodingBig5        = "Big5"
@@ -26,6 +30,10 @@ const (
 func TargetEncodings() []string {
 	return []string{
 		EncodingUTF8,
+		EncodingUTF16LE,
+		EncodingUTF16BE,
+		EncodingUTF32LE,
+		EncodingUTF32BE,
 		EncodingGB18030,
 		EncodingGBK,
 		EncodingBig5,
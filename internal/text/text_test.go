@@ -1,6 +1,8 @@
 package text
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -65,3 +67,81 @@ func TestStrictTranscodeGBKToUTF8(t *testing.T) {
 	}
 }
 
+func TestDetectUTF16AndUTF32(t *testing.T) {
+	src := "hello, 世界\r\nsecond line🙂\r\n"
+	cases := []struct {
+		name string
+		enc  string
+		bom  bool
+	}{
+		{"utf16le bom", EncodingUTF16LE, true},
+		{"utf16be bom", EncodingUTF16BE, true},
+		{"utf32le bom", EncodingUTF32LE, true},
+		{"utf32be bom", EncodingUTF32BE, true},
+		{"utf16le", EncodingUTF16LE, false},
+		{"utf16be", EncodingUTF16BE, false},
+		{"utf32le", EncodingUTF32LE, false},
+		{"utf32be", EncodingUTF32BE, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := encodeStrictBytes(tc.enc, []byte(src))
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !tc.bom {
+				w, _ := lookupWide(tc.enc)
+				b = b[len(w.bom):]
+			}
+			isText, enc := DetectTextAndEncoding(b)
+			if !isText || enc != tc.enc {
+				t.Fatalf("expected %s text, got isText=%v enc=%s", tc.enc, isText, enc)
+			}
+		})
+	}
+}
+
+func TestDetectUTF16TruncatedSample(t *testing.T) {
+	// 截取的样本末尾恰好切开一个代理对。
+	src := strings.Repeat("a", maxDetectSampleBytes/2-2) + "🙂🙂"
+	b, err := encodeStrictBytes(EncodingUTF16LE, []byte(src))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	isText, enc := DetectTextAndEncoding(b)
+	if !isText || enc != EncodingUTF16LE {
+		t.Fatalf("expected UTF-16LE text, got isText=%v enc=%s", isText, enc)
+	}
+}
+
+func TestStrictTranscodeUTF16BOM(t *testing.T) {
+	src := "中文,abc🙂\r\n"
+	out, _, err := StrictTranscode([]byte(src), TranscodeParams{SourceEncoding: EncodingUTF8, TargetEncoding: EncodingUTF16LE})
+	if err != nil {
+		t.Fatalf("transcode: %v", err)
+	}
+	if !bytes.HasPrefix(out, []byte{0xFF, 0xFE}) {
+		t.Fatalf("expected UTF-16LE BOM, got % x", out[:2])
+	}
+
+	back, _, err := StrictTranscode(out, TranscodeParams{SourceEncoding: SourceEncodingAuto, TargetEncoding: EncodingUTF8})
+	if err != nil {
+		t.Fatalf("transcode back: %v", err)
+	}
+	if string(back) != src {
+		t.Fatalf("expected %q (BOM stripped), got %q", src, string(back))
+	}
+}
+
+func TestStrictDecodeUTF16Invalid(t *testing.T) {
+	cases := map[string][]byte{
+		"odd length":     {0xFF, 0xFE, 'a', 0x00, 'b'},
+		"lone surrogate": {'a', 0x00, 0x3D, 0xD8, 'b', 0x00},
+		"wrong bom":      {0xFE, 0xFF, 0x00, 'a'},
+	}
+	for name, b := range cases {
+		if _, _, err := StrictTranscode(b, TranscodeParams{SourceEncoding: EncodingUTF16LE, TargetEncoding: EncodingUTF8}); err != ErrDecodeFailed {
+			t.Fatalf("%s: expected decode failed, got %v", name, err)
+		}
+	}
+}
@@ -64,6 +64,10 @@ func encodeStrictBytes(encName string, utf8Bytes []byte) ([]byte, error) {
 		return out, nil
 	}
 
+	if w, ok := lookupWide(encName); ok {
+		// UTF-16/UTF-32 能表示所有字符，无需回读校验。
+		return w.encode(utf8Bytes)
+	}
 	enc, err := lookupEncoding(encName)
 	if err != nil {
 		return nil, ErrUnsupportedEncoding
@@ -0,0 +1,159 @@
+package text
+
+import (
+	"bytes"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
+)
+
+// 无 BOM 时按 NUL 分布识别 UTF-16/UTF-32，至少需要这么多字符，避免把很短的二进制片段当作文本。
+const minTextRunesNoBOM = 8
+
+// wideEncoding 为 UTF-16/UTF-32 的一种字节序。这类文本（如 Windows 工具导出的“Unicode”文件）
+// 含大量 NUL 字节，不能按 looksBinary 判定，单独按 BOM 与 NUL 分布识别。
+type wideEncoding struct {
+	name string
+	// unit 为码元字节数。
+	unit int
+	bom  []byte
+	// enc 不处理 BOM：解码时由 decode 去掉，编码时由 encode 写入。
+	enc encoding.Encoding
+}
+
+// UTF-32LE 的 BOM 以 UTF-16LE 的 BOM 开头，须先匹配。
+var wideEncodings = []wideEncoding{
+	{EncodingUTF32LE, 4, []byte{0xFF, 0xFE, 0x00, 0x00}, utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)},
+	{EncodingUTF32BE, 4, []byte{0x00, 0x00, 0xFE, 0xFF}, utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM)},
+	{EncodingUTF16LE, 2, []byte{0xFF, 0xFE}, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+	{EncodingUTF16BE, 2, []byte{0xFE, 0xFF}, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+}
+
+func lookupWide(name string) (wideEncoding, bool) {
+	for _, w := range wideEncodings {
+		if w.name == name {
+			return w, true
+		}
+	}
+	return wideEncoding{}, false
+}
+
+// bomEncoding 返回以 b 开头的 BOM 对应的 UTF-16/UTF-32 编码。
+func bomEncoding(b []byte) (wideEncoding, bool) {
+	for _, w := range wideEncodings {
+		if bytes.HasPrefix(b, w.bom) {
+			return w, true
+		}
+	}
+	return wideEncoding{}, false
+}
+
+// detectWide 识别 UTF-16/UTF-32 文本：有 BOM 时按 BOM，否则按 NUL 分布猜测；两种情况都须严格解码通过。
+// truncated 表示 sample 是截取的前缀，末尾可能截断了代理对。
+func detectWide(sample []byte, truncated bool) (string, bool) {
+	w, hasBOM := bomEncoding(sample)
+	if !hasBOM {
+		var ok bool
+		if w, ok = guessWide(sample); !ok {
+			return EncodingUnknown, false
+		}
+	}
+	if truncated {
+		sample = w.trimPartial(sample)
+	}
+	minRunes := 0
+	if !hasBOM {
+		minRunes = minTextRunesNoBOM
+	}
+	if !tryEncoding(sample, w.name, minPrintableRatio, minRunes) {
+		return EncodingUnknown, false
+	}
+	return w.name, true
+}
+
+// guessWide 按 NUL 字节的分布猜测无 BOM 的 UTF-16/UTF-32：以 ASCII 为主的文本中，每个码元的高位字节几乎都是 0。
+// 以非拉丁字符为主又没有 BOM 的 UTF-16 无法可靠区分，不做识别。
+func guessWide(sample []byte) (wideEncoding, bool) {
+	if len(sample) < 4 || len(sample)%2 != 0 {
+		return wideEncoding{}, false
+	}
+	var zeros [4]int
+	for i, c := range sample {
+		if c == 0x00 {
+			zeros[i%4]++
+		}
+	}
+	mostly := func(n, total int) bool { return n*10 >= total*9 }
+
+	if len(sample)%4 == 0 {
+		// UTF-32 最高字节总是 0，BMP 字符的次高字节也是 0。
+		units := len(sample) / 4
+		switch {
+		case zeros[3] == units && mostly(zeros[2], units) && !mostly(zeros[0], units):
+			return wideEncodings[0], true
+		case zeros[0] == units && mostly(zeros[1], units) && !mostly(zeros[3], units):
+			return wideEncodings[1], true
+		}
+	}
+
+	// UTF-16：至少一半字符的高位字节为 0，且低位字节很少为 0。
+	units := len(sample) / 2
+	even, odd := zeros[0]+zeros[2], zeros[1]+zeros[3]
+	switch {
+	case odd*2 >= units && even*10 < odd:
+		return wideEncodings[2], true
+	case even*2 >= units && odd*10 < even:
+		return wideEncodings[3], true
+	}
+	return wideEncoding{}, false
+}
+
+// trimPartial 去掉被截断的末尾码元（以及 UTF-16 末尾不成对的高代理项），用于识别截取的前缀。
+func (w wideEncoding) trimPartial(b []byte) []byte {
+	b = b[:len(b)-len(b)%w.unit]
+	if w.unit != 2 || len(b) < 2 {
+		return b
+	}
+	hi := b[len(b)-1]
+	if w.name == EncodingUTF16BE {
+		hi = b[len(b)-2]
+	}
+	if hi >= 0xD8 && hi <= 0xDB {
+		b = b[:len(b)-2]
+	}
+	return b
+}
+
+// decode 严格解码：开头与本编码一致的 BOM 被去掉；开头是其他字节序的 BOM、长度不是码元整数倍、
+// 孤立代理项或超出范围的码点均视为解码失败。
+func (w wideEncoding) decode(src []byte) ([]byte, error) {
+	if bytes.HasPrefix(src, w.bom) {
+		src = src[len(w.bom):]
+	} else if _, ok := bomEncoding(src); ok {
+		return nil, ErrDecodeFailed
+	}
+	if len(src)%w.unit != 0 {
+		return nil, ErrDecodeFailed
+	}
+	out, _, err := transform.Bytes(w.enc.NewDecoder(), src)
+	if err != nil {
+		return nil, ErrDecodeFailed
+	}
+	// decoder 会把非法码元静默替换为 U+FFFD，编码回去与原文不同即为非法输入。
+	back, _, err := transform.Bytes(w.enc.NewEncoder(), out)
+	if err != nil || !bytes.Equal(back, src) {
+		return nil, ErrDecodeFailed
+	}
+	return out, nil
+}
+
+// encode 把 UTF-8 编码为本编码，并在开头写入 BOM（记事本等 Windows 工具靠 BOM 识别 UTF-16/UTF-32）。
+func (w wideEncoding) encode(utf8Bytes []byte) ([]byte, error) {
+	body, _, err := transform.Bytes(w.enc.NewEncoder(), utf8Bytes)
+	if err != nil {
+		return nil, ErrEncodeFailed
+	}
+	return append(bytes.Clone(w.bom), body...), nil
+}
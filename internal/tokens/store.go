@@ -15,6 +15,8 @@ type Item struct {
 	FileID    string
 	CreatedAt time.Time
 	ExpiresAt time.Time
+	// Version 非 0 时 token 只用于下载该历史版本。
+	Version int
 }
 
 type Options struct {
@@ -75,6 +77,21 @@ func (s *Store) Create(kind string, fileID string, ttl time.Duration) (Item, err
 }
 
 func (s *Store) CreateAt(now time.Time, kind string, fileID string, ttl time.Duration) (Item, error) {
+	return s.create(now, kind, fileID, 0, ttl)
+}
+
+func (s *Store) CreateVersion(kind string, fileID string, version int, ttl time.Duration) (Item, error) {
+	return s.CreateVersionAt(time.Now(), kind, fileID, version, ttl)
+}
+
+func (s *Store) CreateVersionAt(now time.Time, kind string, fileID string, version int, ttl time.Duration) (Item, error) {
+	if version <= 0 {
+		return Item{}, fmt.Errorf("%w: version must be > 0", ErrInvalidInput)
+	}
+	return s.create(now, kind, fileID, version, ttl)
+}
+
+func (s *Store) create(now time.Time, kind string, fileID string, version int, ttl time.Duration) (Item, error) {
 	if kind == "" {
 		return Item{}, fmt.Errorf("%w: kind is required", ErrInvalidInput)
 	}
@@ -94,6 +111,7 @@ func (s *Store) CreateAt(now time.Time, kind string, fileID string, ttl time.Dur
 		Token:     token,
 		Kind:      kind,
 		FileID:    fileID,
+		Version:   version,
 		CreatedAt: now.UTC(),
 		ExpiresAt: now.Add(ttl).UTC(),
 	}
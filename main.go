@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"go-learn/internal/config"
@@ -13,6 +18,8 @@ import (
 	"go-learn/internal/tokens"
 )
 
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	configPath := flag.String("config", "./config.yaml", "config file path (YAML)")
 	flag.Parse()
@@ -30,14 +37,15 @@ func main() {
 	}
 
 	log.Printf("config loaded: listen=%s base_url=%s external_origin=%s", cfg.Server.Listen, cfg.Server.BaseURL, origin)
-	log.Printf("limits: max_file_size_mb=%d max_files=%d max_total_size_mb=%d upload_concurrency=%d transcode_concurrency=%d",
-		cfg.Limits.MaxFileSizeMB, cfg.Limits.MaxFiles, cfg.Limits.MaxTotalSizeMB, cfg.Limits.UploadConcurrency, cfg.Limits.TranscodeConcurrency)
+	log.Printf("limits: max_file_size_mb=%d max_files=%d max_total_size_mb=%d upload_concurrency=%d transcode_concurrency=%d max_versions=%d eviction_policy=%s max_pinned_size_mb=%d",
+		cfg.Limits.MaxFileSizeMB, cfg.Limits.MaxFiles, cfg.Limits.MaxTotalSizeMB, cfg.Limits.UploadConcurrency, cfg.Limits.TranscodeConcurrency, cfg.Limits.HistoryVersions(), cfg.Limits.EvictionPolicy, cfg.Limits.MaxPinnedBytes()/1024/1024)
 	log.Printf("tokens: download_ttl_seconds=%d bridge_ttl_seconds=%d", cfg.Tokens.DownloadTTLSeconds, cfg.Tokens.BridgeTTLSeconds)
+	log.Printf("retention: default_seconds=%d sweep_interval_seconds=%d", cfg.Retention.DefaultSeconds, cfg.Retention.SweepIntervalSeconds)
+	log.Printf("files: on_name_conflict=%s names.max_length=%d names.forbidden_chars=%q names.normalize_nfc=%v names.reject_reserved=%v",
+		cfg.Files.OnNameConflict, cfg.Files.Names.MaxLength, cfg.Files.Names.ForbiddenChars, cfg.Files.Names.NormalizeNFC, cfg.Files.Names.RejectReserved)
+	log.Printf("storage: backend=%s dir=%s compression=%s snapshot=%v journal=%v", cfg.Storage.Backend, cfg.Storage.Dir, cfg.Storage.Compression, cfg.Storage.Snapshot.Enabled, cfg.Storage.Journal.Enabled)
 
-	memStore, err := store.NewInMemoryStore(store.NewParams{
-		MaxFiles:      cfg.Limits.MaxFiles,
-		MaxTotalBytes: int64(cfg.Limits.MaxTotalSizeMB) * 1024 * 1024,
-	})
+	fileStore, closeStore, err := newFileStore(cfg)
 	if err != nil {
 		log.Printf("store init error: %v", err)
 		os.Exit(2)
@@ -46,18 +54,23 @@ func main() {
 	tokenStore := tokens.NewStore(tokens.Options{
 		CleanupInterval: 30 * time.Second,
 	})
-	defer tokenStore.Close()
+	sweeper := store.NewSweeper(fileStore, store.SweeperOptions{
+		Interval: cfg.Retention.SweepInterval(),
+		OnError:  func(err error) { log.Printf("expiry sweep error: %v", err) },
+	})
 
 	handler := httpapi.NewRouter(httpapi.RouterDeps{
 		ExternalOrigin:  origin,
-		Store:           memStore,
+		Store:           fileStore,
 		Tokens:          tokenStore,
 		DownloadTTL:     time.Duration(cfg.Tokens.DownloadTTLSeconds) * time.Second,
 		BridgeTTL:       time.Duration(cfg.Tokens.BridgeTTLSeconds) * time.Second,
+		DefaultTTL:      cfg.Retention.Default(),
 		UploadSem:       httpapi.NewSemaphore(cfg.Limits.UploadConcurrency),
 		TranscodeSem:    httpapi.NewSemaphore(cfg.Limits.TranscodeConcurrency),
 		MaxFileBytes:    int64(cfg.Limits.MaxFileSizeMB) * 1024 * 1024,
 		MaxRequestBytes: int64(cfg.Limits.MaxFileSizeMB)*1024*1024 + 2*1024*1024,
+		OnNameConflict:  cfg.Files.OnNameConflict,
 	})
 
 	srv := &http.Server{
@@ -69,9 +82,118 @@ func main() {
 		IdleTimeout:       cfg.Server.Timeouts.Idle(),
 	}
 
-	log.Printf("listening on %s", cfg.Server.Listen)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Printf("server error: %v", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", cfg.Server.Listen)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("server error: %v", err)
+			exitCode = 1
+		}
+	case <-ctx.Done():
+		log.Printf("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutdown error: %v", err)
+		}
+		cancel()
+	}
+
+	tokenStore.Close()
+	sweeper.Close()
+	if err := closeStore(); err != nil {
+		log.Printf("store close error: %v", err)
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// newFileStore 按配置创建存储后端；返回的 close 函数在退出时调用（例如写最后一次快照）。
+func newFileStore(cfg config.Config) (store.FileStore, func() error, error) {
+	params := store.NewParams{
+		MaxFiles:       cfg.Limits.MaxFiles,
+		MaxTotalBytes:  int64(cfg.Limits.MaxTotalSizeMB) * 1024 * 1024,
+		MaxVersions:    cfg.Limits.HistoryVersions(),
+		EvictionPolicy: cfg.Limits.EvictionPolicy,
+		MaxPinnedBytes: cfg.Limits.MaxPinnedBytes(),
+		Compression:    cfg.Storage.Compression,
+		TrashTTL:       cfg.Retention.TrashTTL(),
+		MaxTrashBytes:  cfg.Retention.MaxTrashBytes(),
+		TrashEvicted:   cfg.Retention.TrashEvicted,
+		NamePolicy: store.NamePolicy{
+			MaxLength:      cfg.Files.Names.MaxNameLength(),
+			ForbiddenChars: cfg.Files.Names.ForbiddenChars,
+			NormalizeNFC:   cfg.Files.Names.NormalizeNFC,
+			RejectReserved: cfg.Files.Names.RejectReserved,
+		},
+	}
+	noop := func() error { return nil }
+
+	switch cfg.Storage.Backend {
+	case config.StorageBackendDisk:
+		s, err := store.NewDiskStore(cfg.Storage.Dir, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case config.StorageBackendS3:
+		s, err := store.NewS3Store(store.S3Params{
+			Endpoint:     cfg.Storage.S3.Endpoint,
+			Bucket:       cfg.Storage.S3.Bucket,
+			Region:       cfg.Storage.S3.Region,
+			AccessKey:    cfg.Storage.S3.AccessKey,
+			SecretKey:    cfg.Storage.S3.SecretKey,
+			Prefix:       cfg.Storage.S3.Prefix,
+			PathStyle:    cfg.Storage.S3.PathStyle,
+			IndexPath:    filepath.Join(cfg.Storage.Dir, "s3-index.json"),
+			CleanOrphans: cfg.Storage.S3.CleanOrphans,
+		}, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	default:
+		s, err := store.NewInMemoryStore(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Storage.Journal.Enabled {
+			j, err := store.OpenJournal(s, store.JournalOptions{
+				Dir:             cfg.Storage.Journal.Dir,
+				CompactInterval: cfg.Storage.Journal.CompactInterval(),
+				CompactBytes:    int64(cfg.Storage.Journal.CompactSizeMB) * 1024 * 1024,
+				OnError:         func(err error) { log.Printf("journal compact error: %v", err) },
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			st := s.Stats()
+			log.Printf("journal recovered: dir=%s files=%d logical_bytes=%d physical_bytes=%d compression_ratio=%.2f", cfg.Storage.Journal.Dir, st.Files, st.LogicalBytes, st.PhysicalBytes, st.CompressionRatio)
+			return s, j.Close, nil
+		}
+		if !cfg.Storage.Snapshot.Enabled {
+			return s, noop, nil
+		}
+
+		if err := s.LoadSnapshotFile(cfg.Storage.Snapshot.Path); err != nil {
+			return nil, nil, err
+		}
+		st := s.Stats()
+		log.Printf("snapshot loaded: path=%s files=%d logical_bytes=%d physical_bytes=%d compression_ratio=%.2f", cfg.Storage.Snapshot.Path, st.Files, st.LogicalBytes, st.PhysicalBytes, st.CompressionRatio)
+
+		snap := store.NewSnapshotter(s, store.SnapshotterOptions{
+			Path:     cfg.Storage.Snapshot.Path,
+			Interval: cfg.Storage.Snapshot.Interval(),
+			OnError:  func(err error) { log.Printf("snapshot error: %v", err) },
+		})
+		return s, snap.Close, nil
 	}
 }